@@ -3,40 +3,128 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/dsha256/packer/internal/grpcserver"
 	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/middleware"
 	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/admission"
 	"github.com/dsha256/packer/pkg/cache"
 	"github.com/dsha256/packer/pkg/config"
 	"github.com/dsha256/packer/pkg/profiler"
+	"github.com/dsha256/packer/pkg/tlsconfig"
 )
 
+// configWatchInterval is how often the config file is checked for changes.
+const configWatchInterval = 5 * time.Second
+
 func main() {
+	defaultConfigPath := config.DefaultPath
+	if path, ok := os.LookupEnv(config.PathEnv); ok {
+		defaultConfigPath = path
+	}
+	configPath := flag.String("config", defaultConfigPath, "path to the YAML config file, also set by $"+config.PathEnv)
+	flag.Parse()
+
+	var logLevel slog.LevelVar
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: &logLevel,
 	}))
 	slog.SetDefault(logger)
 
-	cfg, err := config.GetConfigFromFile("./config.yaml")
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		logger.Error("Failed to load config file", "error", err)
+		logger.Error("Failed to load config file", "path", *configPath, "error", err)
 		os.Exit(1)
 	}
 
-	logger.Info("Starting packer service")
+	level, _ := config.ParseLogLevel(cfg.Log.Level)
+	logLevel.Set(level)
+
+	logger.Info("Starting packer service", "config", *configPath)
+
+	newPacker, err := packer.NewWithConfig(packerConfig(&cfg.Packer, cfg.Packer.DefaultSizes))
+	if err != nil {
+		logger.Error("Failed to create packer", "error", err)
+		os.Exit(1)
+	}
+
+	catalogPackers := make(map[string]packer.Packer, len(cfg.Packer.Catalogs))
+	for _, catalog := range cfg.Packer.Catalogs {
+		if catalogPackers[catalog.Name], err = packer.NewWithConfig(packerConfig(&cfg.Packer, catalog.Sizes)); err != nil {
+			logger.Error("Failed to create catalog packer", "catalog", catalog.Name, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	newCache := cache.NewInMemoryCacheWithConfig(cache.Config{
+		CleanupInterval: cfg.Cache.CleanupInterval,
+		Capacity:        cfg.Cache.Capacity,
+	})
+
+	maxItems, maxSizes := packerLimits(&cfg.Packer)
+	newHandler := handler.New(logger, newPacker, newCache).
+		WithLimits(maxItems, maxSizes).
+		WithCacheTTL(cacheTTL(&cfg.Cache)).
+		WithCatalogs(catalogPackers)
+
+	var rateLimiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled {
+		rateLimiter = middleware.NewRateLimiter(rateLimitConfig(&cfg.RateLimit))
+		newHandler.WithRateLimiter(rateLimiter, cfg.RateLimit.ItemsPerCostUnit)
+	}
+
+	newGRPCService := grpcserver.New(logger, newPacker, maxItems, maxSizes)
+
+	if cfg.Auth.Enabled {
+		authenticator := middleware.NewAuthenticator(authConfig(&cfg.Auth))
+		newHandler.WithAuthenticator(authenticator)
+		newGRPCService.WithAuthenticator(authenticator)
+	}
 
-	newPacker := packer.New()
+	if cfg.Admission.Enabled {
+		admissionController := admission.New(admission.Config{
+			Capacity: cfg.Admission.MaxInFlightWork,
+			MaxQueue: cfg.Admission.MaxQueue,
+			MaxWait:  cfg.Admission.MaxWait,
+		})
+		newHandler.WithAdmissionController(admissionController)
+		newGRPCService.WithAdmissionController(admissionController)
+	}
+
+	// Certificates are reloaded on file changes until shutdown, like the config itself.
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+
+	serverTLS, err := newTLSManager(reloadCtx, logger, &cfg.Server.TLS)
+	if err != nil {
+		logger.Error("Failed to load server TLS certificates", "error", err)
+		os.Exit(1)
+	}
 
-	newCache := cache.NewInMemoryCache()
+	snapshotter, err := newSnapshotter(&cfg.Profiler)
+	if err != nil {
+		logger.Error("Failed to create profiling snapshotter", "error", err)
+		os.Exit(1)
+	}
 
-	newHandler := handler.New(logger, newPacker, newCache)
+	mux := http.NewServeMux()
+	newHandler.RegisterRoutes(mux)
 
 	srv := &http.Server{
 		Addr: fmt.Sprintf(":%d", cfg.Server.Port),
@@ -46,40 +134,125 @@ func main() {
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
 			)
-			mux := http.NewServeMux()
-			newHandler.RegisterRoutes(mux)
+			start := time.Now()
 			mux.ServeHTTP(w, r)
+			if snapshotter != nil {
+				snapshotter.ObserveLatency(time.Since(start))
+			}
 		}),
 		ReadTimeout:       cfg.Server.ReadTimeout,
 		WriteTimeout:      cfg.Server.WriteTimeout,
 		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
 	}
+	if serverTLS != nil {
+		srv.TLSConfig = serverTLS.TLSConfig()
+	}
 
 	go func() {
-		logger.Info("Server starting", "port", cfg.Server.Port)
-		if err = srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Info("Server starting", "port", cfg.Server.Port, "tls", serverTLS != nil)
+		if err = listenAndServe(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("Server failed", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		listener, listenErr := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if listenErr != nil {
+			logger.Error("Failed to listen for gRPC", "error", listenErr)
+			os.Exit(1)
+		}
+
+		var grpcOptions []grpc.ServerOption
+		if serverTLS != nil {
+			grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(serverTLS.TLSConfig())))
+		}
+		grpcServer = newGRPCService.NewServer(grpcOptions...)
+		go func() {
+			logger.Info("gRPC server starting", "port", cfg.GRPC.Port)
+			if serveErr := grpcServer.Serve(listener); serveErr != nil {
+				logger.Error("gRPC server failed", "error", serveErr)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	var newProfiler *profiler.Profiler
 	if cfg.Profiler.Enabled {
-		if err = profiler.New().WithConfig(&profiler.Config{
+		profilerTLS, tlsErr := newTLSManager(reloadCtx, logger, &cfg.Profiler.TLS)
+		if tlsErr != nil {
+			logger.Error("Failed to load profiler TLS certificates", "error", tlsErr)
+			os.Exit(1)
+		}
+
+		profilerConfig := &profiler.Config{
+			HTTPHost:                 cfg.Profiler.Host,
 			HTTPPort:                 cfg.Profiler.Port,
+			Token:                    cfg.Profiler.Token,
 			URLPathFirstSubdirectory: "pprof",
 			HTTPReadHeaderTimeout:    cfg.Profiler.ReadHeaderTimeout,
-		}).Start(context.TODO()); err != nil {
+			BlockProfileRate:         cfg.Profiler.BlockProfileRate,
+			MutexProfileFraction:     cfg.Profiler.MutexProfileFraction,
+		}
+		if profilerTLS != nil {
+			profilerConfig.TLSConfig = profilerTLS.TLSConfig()
+		}
+
+		newProfiler = profiler.New().WithConfig(profilerConfig)
+		if snapshotter != nil {
+			newProfiler.WithSnapshotter(snapshotter)
+		}
+		if err = newProfiler.Start(reloadCtx); err != nil {
 			logger.Error("Failed to start profiler server", "error", err)
 			os.Exit(1)
 		}
 	}
 
+	reloader := config.NewReloader(logger, *configPath, cfg).OnReload(func(previous, next *config.Config) {
+		level, _ := config.ParseLogLevel(next.Log.Level)
+		logLevel.Set(level)
+
+		if rateLimiter != nil {
+			rateLimiter.UpdateConfig(rateLimitConfig(&next.RateLimit))
+			newHandler.SetItemsPerCostUnit(next.RateLimit.ItemsPerCostUnit)
+		}
+
+		newHandler.WithCacheTTL(cacheTTL(&next.Cache))
+
+		// Sizes changed through the API are kept unless the configured defaults change.
+		if len(next.Packer.DefaultSizes) > 0 && !slices.Equal(previous.Packer.DefaultSizes, next.Packer.DefaultSizes) {
+			sizes := packetSizes(next.Packer.DefaultSizes)
+			if setErr := validation.ValidatePacketSizesCount(sizes, maxSizes); setErr != nil {
+				logger.Error("Failed to apply default packet sizes", "error", setErr)
+
+				return
+			}
+			if setErr := newPacker.SetPacketSizes(context.Background(), sizes, types.PacketSizeDetails{}); setErr != nil {
+				logger.Error("Failed to apply default packet sizes", "error", setErr)
+			}
+		}
+	})
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go reloader.Watch(reloadCtx, configWatchInterval, hangup)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("Shutting down server...")
 
+	signal.Stop(hangup)
+	stopReload()
+
+	newHandler.MarkShuttingDown()
+	if cfg.Server.ShutdownDrainDelay > 0 {
+		logger.Info("Draining traffic before shutdown", "delay", cfg.Server.ShutdownDrainDelay.String())
+		time.Sleep(cfg.Server.ShutdownDrainDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -87,7 +260,143 @@ func main() {
 		logger.Error("Server forced to shutdown", "error", err)
 	}
 
+	if grpcServer != nil {
+		grpcserver.GracefulStop(ctx, grpcServer)
+	}
+
+	if newProfiler != nil {
+		if err = newProfiler.Shutdown(ctx); err != nil {
+			logger.Error("Profiler forced to shutdown", "error", err)
+		}
+	}
+
 	newCache.Close()
+	if rateLimiter != nil {
+		rateLimiter.Close()
+	}
 
 	logger.Info("Server exited properly")
 }
+
+// newTLSManager loads the certificates of an enabled TLS section and reloads them when they change until ctx is done.
+// It returns nil when TLS is disabled.
+func newTLSManager(ctx context.Context, logger *slog.Logger, cfg *config.TLS) (*tlsconfig.Manager, error) {
+	if !cfg.Enabled {
+		return nil, nil //nolint:nilnil // TLS is optional.
+	}
+
+	manager, err := tlsconfig.New(logger, cfg.Config())
+	if err != nil {
+		return nil, err
+	}
+	go manager.Watch(ctx, configWatchInterval)
+
+	return manager, nil
+}
+
+func listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		// The certificate comes from the TLS config.
+		return srv.ListenAndServeTLS("", "")
+	}
+
+	return srv.ListenAndServe()
+}
+
+func packetSizes(sizes []int) []types.PacketSize {
+	packetSizes := make([]types.PacketSize, 0, len(sizes))
+	for _, size := range sizes {
+		packetSizes = append(packetSizes, types.PacketSize(size))
+	}
+
+	return packetSizes
+}
+
+// packerConfig returns the solver settings of a packer of the given sizes; catalogs share them with the default packer.
+func packerConfig(cfg *config.Packer, sizes []int) packer.Config {
+	return packer.Config{
+		Strategy:                packer.Strategy(cfg.DefaultStrategy),
+		DefaultSizes:            packetSizes(sizes),
+		Workers:                 cfg.Workers,
+		LookupTableMaxItems:     cfg.LookupTable.MaxItems,
+		LookupTableInBackground: cfg.LookupTable.Background,
+		DPTableMaxItems:         cfg.DPTable.MaxItems,
+	}
+}
+
+// packerLimits returns the configured limits, falling back to the handler defaults.
+func packerLimits(cfg *config.Packer) (int, int) {
+	maxItems, maxSizes := handler.MaxAllowedItems, handler.MaxAllowedSizes
+	if cfg.MaxItems > 0 {
+		maxItems = cfg.MaxItems
+	}
+	if cfg.MaxSizes > 0 {
+		maxSizes = cfg.MaxSizes
+	}
+
+	return maxItems, maxSizes
+}
+
+func cacheTTL(cfg *config.Cache) time.Duration {
+	if cfg.TTL <= 0 {
+		return handler.DefaultCacheTTL
+	}
+
+	return cfg.TTL
+}
+
+func rateLimitConfig(cfg *config.RateLimit) middleware.RateLimitConfig {
+	clients := make(map[string]middleware.ClientLimit, len(cfg.Clients))
+	for _, client := range cfg.Clients {
+		clients[strings.ToLower(client.APIKeySHA256)] = middleware.ClientLimit{
+			RequestsPerSecond: client.RequestsPerSecond,
+			Burst:             client.Burst,
+		}
+	}
+
+	return middleware.RateLimitConfig{
+		Clients: clients,
+		Default: middleware.ClientLimit{
+			RequestsPerSecond: cfg.RequestsPerSecond,
+			Burst:             cfg.Burst,
+		},
+		IdleTimeout:       cfg.IdleTimeout,
+		TrustForwardedFor: cfg.TrustForwardedFor,
+	}
+}
+
+func authConfig(cfg *config.Auth) middleware.AuthConfig {
+	apiKeys := make(map[string]middleware.Principal, len(cfg.APIKeys))
+	for _, apiKey := range cfg.APIKeys {
+		apiKeys[strings.ToLower(apiKey.SHA256)] = middleware.Principal{
+			Subject: apiKey.Name,
+			Role:    middleware.Role(apiKey.Role),
+		}
+	}
+
+	return middleware.AuthConfig{
+		APIKeys:     apiKeys,
+		JWTSecret:   []byte(cfg.JWTSecret),
+		JWTIssuer:   cfg.JWTIssuer,
+		JWTAudience: cfg.JWTAudience,
+		JWTLeeway:   cfg.JWTLeeway,
+	}
+}
+
+// newSnapshotter returns nil unless both the profiler and its snapshots are enabled.
+func newSnapshotter(cfg *config.Profiler) (*profiler.Snapshotter, error) {
+	if !cfg.Enabled || !cfg.Snapshots.Enabled {
+		return nil, nil //nolint:nilnil // Snapshots are disabled.
+	}
+
+	return profiler.NewSnapshotter(profiler.SnapshotConfig{
+		Dir:                cfg.Snapshots.Dir,
+		Interval:           cfg.Snapshots.Interval,
+		CPUDuration:        cfg.Snapshots.CPUDuration,
+		MaxAge:             cfg.Snapshots.MaxAge,
+		LatencyThreshold:   cfg.Snapshots.LatencyThreshold,
+		Cooldown:           cfg.Snapshots.Cooldown,
+		HeapThresholdBytes: cfg.Snapshots.HeapThresholdBytes,
+		MaxFiles:           cfg.Snapshots.MaxFiles,
+	})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/client"
+)
+
+var (
+	ErrUnknownAlgorithm  = errors.New("unknown algorithm, use v1, v2 or parallel")
+	ErrCalculationFailed = errors.New("calculations failed")
+)
+
+// defaultMaxItems bounds local calculations unless --max-items raises it, up to handler.MaxAllowedItems. The v1
+// solver takes 16 bytes per item, so this keeps a default run below about 160 MB.
+const defaultMaxItems = 10_000_000
+
+type pack struct {
+	Size     int `json:"size"`
+	Quantity int `json:"quantity"`
+}
+
+type calculation struct {
+	Error      string `json:"error,omitempty"`
+	Packs      []pack `json:"packs,omitempty"`
+	Items      int    `json:"items"`
+	TotalItems int    `json:"total_items"`
+	TotalPacks int    `json:"total_packs"`
+}
+
+func newCalculation(items int, packs map[int]int) calculation {
+	result := calculation{
+		Items: items,
+		Packs: make([]pack, 0, len(packs)),
+	}
+	for size, quantity := range packs {
+		result.Packs = append(result.Packs, pack{Size: size, Quantity: quantity})
+		result.TotalItems += size * quantity
+		result.TotalPacks += quantity
+	}
+	slices.SortFunc(result.Packs, func(a, b pack) int {
+		return b.Size - a.Size
+	})
+
+	return result
+}
+
+func runCalc(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("calc", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	var remote remoteFlags
+	remote.register(flags)
+	sizesFlag := flags.String("sizes", "250,500,1000,2000,5000", "comma-separated packet sizes for local calculations")
+	itemsFlag := flags.String("items", "", "comma-separated item counts")
+	itemsFile := flags.String("items-file", "", `file with item counts, "-" for stdin`)
+	algorithm := flags.String("algorithm", "v1", "local solver: v1 (dynamic programming), v2 (min-heap) or parallel")
+	maxItems := flags.Int("max-items", defaultMaxItems, "largest item count calculated locally, at most 1000000000")
+	output := flags.String("output", formatTable, "output format: table, json or csv")
+	isRemote := flags.Bool("remote", false, "calculate on the server instead of locally")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	items, err := readItems(*itemsFlag, *itemsFile, stdin)
+	if err != nil {
+		return err
+	}
+
+	var calculations []calculation
+	if *isRemote {
+		calculations, err = calculateRemotely(ctx, &remote, items)
+	} else {
+		calculations, err = calculateLocally(*sizesFlag, *algorithm, items, *maxItems)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = writeCalculations(stdout, *output, calculations); err != nil {
+		return err
+	}
+
+	return checkCalculations(calculations)
+}
+
+// checkCalculations returns ErrCalculationFailed when any calculation failed, so that scripts see a non-zero exit
+// code; the failures themselves are already in the output.
+func checkCalculations(calculations []calculation) error {
+	failed := 0
+	for _, result := range calculations {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d %w", failed, len(calculations), ErrCalculationFailed)
+	}
+
+	return nil
+}
+
+// calculateLocally solves each item count of at most maxItems; the solvers' memory grows with the items.
+func calculateLocally(sizesFlag, algorithm string, items []int, maxItems int) ([]calculation, error) {
+	var solve func(params *packer.CalculateOptimalPacketsForItemsParams) (packer.Result, error)
+	switch algorithm {
+	case "v1":
+		solve = packer.CalculateOptimalPacketsForItemsV1
+	case "v2":
+		solve = packer.CalculateOptimalPacketsForItemsV2
+	case "parallel":
+		solve = packer.CalculateOptimalPacketsForItemsParallel
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+	}
+
+	sizes, err := parseInts(sizesFlag)
+	if err != nil {
+		return nil, err
+	}
+	if len(sizes) == 0 {
+		return nil, ErrMissingSizes
+	}
+
+	packetSizes := make([]types.PacketSize, 0, len(sizes))
+	for _, size := range sizes {
+		packetSizes = append(packetSizes, types.PacketSize(size))
+	}
+	if err = validation.ValidatePacketSizes(packetSizes); err != nil {
+		return nil, err
+	}
+	slices.Sort(packetSizes)
+
+	calculations := make([]calculation, 0, len(items))
+	for _, itemCount := range items {
+		if err = validation.ValidateItems(itemCount, min(maxItems, handler.MaxAllowedItems)); err != nil {
+			calculations = append(calculations, calculation{Items: itemCount, Error: err.Error()})
+
+			continue
+		}
+
+		packets, solveErr := solve(&packer.CalculateOptimalPacketsForItemsParams{
+			Items:       itemCount,
+			PacketSizes: packetSizes,
+		})
+		if solveErr != nil {
+			calculations = append(calculations, calculation{Items: itemCount, Error: solveErr.Error()})
+
+			continue
+		}
+		packs := make(map[int]int, len(packets))
+		for size, quantity := range packets {
+			packs[int(size)] = int(quantity)
+		}
+		calculations = append(calculations, newCalculation(itemCount, packs))
+	}
+
+	return calculations, nil
+}
+
+func calculateRemotely(ctx context.Context, remote *remoteFlags, items []int) ([]calculation, error) {
+	packerClient, err := remote.client()
+	if err != nil {
+		return nil, err
+	}
+
+	results := packerClient.CalculateBatch(ctx, items)
+	calculations := make([]calculation, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			calculations = append(calculations, calculation{Items: result.Items, Error: errorMessage(result.Err)})
+
+			continue
+		}
+		calculations = append(calculations, newCalculation(result.Items, result.Packs))
+	}
+
+	return calculations, nil
+}
+
+func errorMessage(err error) string {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+
+	return err.Error()
+}
+
+// readItems collects the item counts from the flag, the file or stdin, in that order of preference.
+func readItems(itemsFlag, itemsFile string, stdin io.Reader) ([]int, error) {
+	if itemsFlag != "" {
+		return parseInts(itemsFlag)
+	}
+
+	reader := stdin
+	switch {
+	case itemsFile != "" && itemsFile != "-":
+		file, err := os.Open(itemsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	case itemsFile == "" && isTerminal(stdin):
+		return nil, ErrMissingItems
+	}
+
+	var items []int
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parsed, err := parseInts(line)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, parsed...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, ErrMissingItems
+	}
+
+	return items, nil
+}
+
+// parseInts parses integers separated by commas and/or whitespace.
+func parseInts(value string) ([]int, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	ints := make([]int, 0, len(fields))
+	for _, field := range fields {
+		parsed, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q: %w", field, err)
+		}
+		ints = append(ints, parsed)
+	}
+
+	return ints, nil
+}
+
+func isTerminal(reader io.Reader) bool {
+	file, ok := reader.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
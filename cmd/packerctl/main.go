@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrMissingItems   = errors.New("no item counts given, use --items, --items-file or pipe them to stdin")
+	ErrMissingSizes   = errors.New("packet sizes are required, use --sizes")
+)
+
+const usage = `packerctl calculates optimal packs and manages a packer server.
+
+Usage:
+  packerctl calc  [--sizes 250,500,...] [--items N,...] [--items-file FILE|-] [--algorithm v1|v2|parallel] [--output table|json|csv]
+  packerctl calc  --remote [--server URL] [--items N,...] [--items-file FILE|-] [--output table|json|csv]
+  packerctl sizes get [--server URL] [--output table|json|csv]
+  packerctl sizes set --sizes 250,500,... [--server URL]
+
+Without --remote, calc runs the solver locally. Item counts are read from stdin when neither --items nor --items-file
+is given, one or more per line, and calc exits with status 1 when any calculation failed. Remote commands
+authenticate with --api-key/$PACKER_API_KEY or --token/$PACKER_TOKEN.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "calc":
+		err = runCalc(ctx, args[1:], stdin, stdout)
+	case "sizes":
+		err = runSizes(ctx, args[1:], stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+
+		return 0
+	default:
+		err = fmt.Errorf("%w %q", ErrUnknownCommand, args[0])
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stdout, usage)
+
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+
+		return 1
+	}
+
+	return 0
+}
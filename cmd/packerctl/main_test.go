@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+func runForTest(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), code
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	newCache := cache.NewInMemoryCache()
+	t.Cleanup(newCache.Close)
+
+	mux := http.NewServeMux()
+	handler.New(slog.New(slog.DiscardHandler), packer.New(), newCache).RegisterRoutes(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRun_LocalCalcJSON(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr, code := runForTest(t, "", "calc", "--sizes", "250,500,1000,2000,5000", "--items", "12001", "--output", "json")
+	require.Equal(t, 0, code, stderr)
+
+	var calculations []calculation
+	require.NoError(t, json.Unmarshal([]byte(stdout), &calculations))
+	require.Equal(t, []calculation{{
+		Items:      12001,
+		Packs:      []pack{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}},
+		TotalItems: 12250,
+		TotalPacks: 4,
+	}}, calculations)
+}
+
+func TestRun_LocalCalcFromStdinCSV(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr, code := runForTest(t, "# orders\n1\n251, 501\n\n", "calc", "--sizes", "500,250", "--algorithm", "v2", "--output", "csv")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, strings.Join([]string{
+		"items,size,quantity,total_items,total_packs,error",
+		"1,250,1,250,1,",
+		"251,500,1,500,1,",
+		"501,500,1,750,2,",
+		"501,250,1,750,2,",
+		"",
+	}, "\n"), stdout)
+}
+
+func TestRun_LocalCalcFromFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "items.txt")
+	require.NoError(t, os.WriteFile(path, []byte("250\n"), 0o600))
+
+	stdout, stderr, code := runForTest(t, "", "calc", "--items-file", path)
+	require.Equal(t, 0, code, stderr)
+	require.Contains(t, stdout, "ITEMS")
+	require.Contains(t, stdout, "250")
+}
+
+func TestRun_LocalCalcMaxItems(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr, code := runForTest(t, "", "calc", "--items", "1000000000000000000,1001,1000", "--max-items", "1000", "--output", "csv")
+	require.Equal(t, 1, code, "a failed calculation fails the command")
+	require.Contains(t, stderr, "2 of 3 "+ErrCalculationFailed.Error())
+	require.Equal(t, strings.Join([]string{
+		"items,size,quantity,total_items,total_packs,error",
+		"1000000000000000000,,,,,items exceed maximum allowed value of 1000",
+		"1001,,,,,items exceed maximum allowed value of 1000",
+		"1000,1000,1,1000,1,",
+		"",
+	}, "\n"), stdout)
+
+	stdout, _, code = runForTest(t, "", "calc", "--items", "1000000000000000000", "--output", "csv")
+	require.Equal(t, 1, code)
+	require.Contains(t, stdout, validation.ErrItemsTooLarge.Error())
+}
+
+func TestRun_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		expectedError string
+		args          []string
+	}{
+		{name: "no command", args: nil, expectedError: "Usage"},
+		{name: "unknown command", args: []string{"pack"}, expectedError: ErrUnknownCommand.Error()},
+		{name: "missing items", args: []string{"calc"}, expectedError: ErrMissingItems.Error()},
+		{name: "invalid items", args: []string{"calc", "--items", "abc"}, expectedError: "invalid integer"},
+		{name: "duplicated sizes", args: []string{"calc", "--items", "1", "--sizes", "5,5"}, expectedError: "sizes should be unique"},
+		{name: "unknown algorithm", args: []string{"calc", "--items", "1", "--algorithm", "v9"}, expectedError: ErrUnknownAlgorithm.Error()},
+		{name: "unknown format", args: []string{"calc", "--items", "1", "--output", "xml"}, expectedError: ErrUnknownFormat.Error()},
+		{name: "unknown sizes command", args: []string{"sizes", "drop"}, expectedError: ErrUnknownSizesCommand.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, stderr, code := runForTest(t, "", tt.args...)
+			require.NotEqual(t, 0, code)
+			require.Contains(t, stderr, tt.expectedError)
+		})
+	}
+}
+
+func TestRun_Remote(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	stdout, stderr, code := runForTest(t, "", "sizes", "set", "--server", server.URL, "--sizes", "53,23,31")
+	require.Equal(t, 0, code, stderr)
+	require.Contains(t, stdout, "Packet sizes have been set")
+
+	stdout, stderr, code = runForTest(t, "", "sizes", "get", "--server", server.URL, "--output", "json")
+	require.Equal(t, 0, code, stderr)
+	require.JSONEq(t, `{"packet_sizes":[23,31,53]}`, stdout)
+
+	stdout, stderr, code = runForTest(t, "", "sizes", "get", "--server", server.URL, "--output", "csv")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, "size\n23\n31\n53\n", stdout)
+
+	stdout, stderr, code = runForTest(t, "500000\n0\n", "calc", "--remote", "--server", server.URL, "--output", "csv")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "1 of 2 "+ErrCalculationFailed.Error())
+	require.Equal(t, strings.Join([]string{
+		"items,size,quantity,total_items,total_packs,error",
+		"500000,53,9429,500000,9438,",
+		"500000,31,7,500000,9438,",
+		"500000,23,2,500000,9438,",
+		"0,,,,,items should be positive integer",
+		"",
+	}, "\n"), stdout)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/goccy/go-json"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format, use table, json or csv")
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// writeCalculations prints one row per pack size of every calculation.
+func writeCalculations(w io.Writer, format string, calculations []calculation) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, calculations)
+	case formatCSV:
+		csvWriter := csv.NewWriter(w)
+		_ = csvWriter.Write([]string{"items", "size", "quantity", "total_items", "total_packs", "error"})
+		for _, result := range calculations {
+			for _, row := range calculationRows(result) {
+				_ = csvWriter.Write(row)
+			}
+		}
+		csvWriter.Flush()
+
+		return csvWriter.Error()
+	case formatTable:
+		tableWriter := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tableWriter, "ITEMS\tSIZE\tQUANTITY\tTOTAL ITEMS\tTOTAL PACKS\tERROR")
+		for _, result := range calculations {
+			for _, row := range calculationRows(result) {
+				fmt.Fprintf(tableWriter, "%s\t%s\t%s\t%s\t%s\t%s\n", row[0], row[1], row[2], row[3], row[4], row[5])
+			}
+		}
+
+		return tableWriter.Flush()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func calculationRows(result calculation) [][]string {
+	items := strconv.Itoa(result.Items)
+	if result.Error != "" {
+		return [][]string{{items, "", "", "", "", result.Error}}
+	}
+
+	totalItems := strconv.Itoa(result.TotalItems)
+	totalPacks := strconv.Itoa(result.TotalPacks)
+	rows := make([][]string, 0, len(result.Packs))
+	for _, p := range result.Packs {
+		rows = append(rows, []string{items, strconv.Itoa(p.Size), strconv.Itoa(p.Quantity), totalItems, totalPacks, ""})
+	}
+
+	return rows
+}
+
+func writeSizes(w io.Writer, format string, sizes []int) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, map[string][]int{"packet_sizes": sizes})
+	case formatCSV:
+		csvWriter := csv.NewWriter(w)
+		_ = csvWriter.Write([]string{"size"})
+		for _, size := range sizes {
+			_ = csvWriter.Write([]string{strconv.Itoa(size)})
+		}
+		csvWriter.Flush()
+
+		return csvWriter.Error()
+	case formatTable:
+		fmt.Fprintln(w, "size")
+		for _, size := range sizes {
+			fmt.Fprintln(w, size)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
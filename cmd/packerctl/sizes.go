@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsha256/packer/pkg/client"
+)
+
+var ErrUnknownSizesCommand = errors.New("unknown sizes command, use get or set")
+
+const defaultServer = "http://localhost:3000"
+
+// remoteFlags are the flags shared by the commands talking to a packer server.
+type remoteFlags struct {
+	server string
+	apiKey string
+	token  string
+}
+
+func (remote *remoteFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&remote.server, "server", envOrDefault("PACKER_SERVER", defaultServer), "packer server URL")
+	flags.StringVar(&remote.apiKey, "api-key", os.Getenv("PACKER_API_KEY"), "API key")
+	flags.StringVar(&remote.token, "token", os.Getenv("PACKER_TOKEN"), "JWT bearer token")
+}
+
+func (remote *remoteFlags) client() (*client.Client, error) {
+	packerClient, err := client.New(remote.server)
+	if err != nil {
+		return nil, err
+	}
+
+	return packerClient.WithAPIKey(remote.apiKey).WithBearerToken(remote.token), nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func runSizes(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return ErrUnknownSizesCommand
+	}
+
+	flags := flag.NewFlagSet("sizes "+args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	var remote remoteFlags
+	remote.register(flags)
+	sizesFlag := flags.String("sizes", "", "comma-separated packet sizes")
+	output := flags.String("output", formatTable, "output format: table, json or csv")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	packerClient, err := remote.client()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		sizes, listErr := packerClient.ListPacketSizes(ctx)
+		if listErr != nil {
+			return listErr
+		}
+
+		return writeSizes(stdout, *output, sizes)
+	case "set":
+		sizes, parseErr := parseInts(*sizesFlag)
+		if parseErr != nil {
+			return parseErr
+		}
+		if len(sizes) == 0 {
+			return ErrMissingSizes
+		}
+
+		if err = packerClient.SetPacketSizes(ctx, sizes); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "Packet sizes have been set")
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSizesCommand, args[0])
+	}
+}
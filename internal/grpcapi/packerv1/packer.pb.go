@@ -0,0 +1,874 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: packer/v1/packer.proto
+
+package packerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListPacketSizesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPacketSizesRequest) Reset() {
+	*x = ListPacketSizesRequest{}
+	mi := &file_packer_v1_packer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPacketSizesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPacketSizesRequest) ProtoMessage() {}
+
+func (x *ListPacketSizesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPacketSizesRequest.ProtoReflect.Descriptor instead.
+func (*ListPacketSizesRequest) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{0}
+}
+
+type ListPacketSizesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sizes         []int64                `protobuf:"varint,1,rep,packed,name=sizes,proto3" json:"sizes,omitempty"`
+	Constraints   []*PacketConstraint    `protobuf:"bytes,2,rep,name=constraints,proto3" json:"constraints,omitempty"`
+	Definitions   []*PacketDefinition    `protobuf:"bytes,3,rep,name=definitions,proto3" json:"definitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPacketSizesResponse) Reset() {
+	*x = ListPacketSizesResponse{}
+	mi := &file_packer_v1_packer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPacketSizesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPacketSizesResponse) ProtoMessage() {}
+
+func (x *ListPacketSizesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPacketSizesResponse.ProtoReflect.Descriptor instead.
+func (*ListPacketSizesResponse) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListPacketSizesResponse) GetSizes() []int64 {
+	if x != nil {
+		return x.Sizes
+	}
+	return nil
+}
+
+func (x *ListPacketSizesResponse) GetConstraints() []*PacketConstraint {
+	if x != nil {
+		return x.Constraints
+	}
+	return nil
+}
+
+func (x *ListPacketSizesResponse) GetDefinitions() []*PacketDefinition {
+	if x != nil {
+		return x.Definitions
+	}
+	return nil
+}
+
+type SetPacketSizesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Sizes []int64                `protobuf:"varint,1,rep,packed,name=sizes,proto3" json:"sizes,omitempty"`
+	// Constraints and definitions are replaced together with the sizes: sizes sent without them have none.
+	Constraints   []*PacketConstraint `protobuf:"bytes,2,rep,name=constraints,proto3" json:"constraints,omitempty"`
+	Definitions   []*PacketDefinition `protobuf:"bytes,3,rep,name=definitions,proto3" json:"definitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPacketSizesRequest) Reset() {
+	*x = SetPacketSizesRequest{}
+	mi := &file_packer_v1_packer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPacketSizesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPacketSizesRequest) ProtoMessage() {}
+
+func (x *SetPacketSizesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPacketSizesRequest.ProtoReflect.Descriptor instead.
+func (*SetPacketSizesRequest) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetPacketSizesRequest) GetSizes() []int64 {
+	if x != nil {
+		return x.Sizes
+	}
+	return nil
+}
+
+func (x *SetPacketSizesRequest) GetConstraints() []*PacketConstraint {
+	if x != nil {
+		return x.Constraints
+	}
+	return nil
+}
+
+func (x *SetPacketSizesRequest) GetDefinitions() []*PacketDefinition {
+	if x != nil {
+		return x.Definitions
+	}
+	return nil
+}
+
+type SetPacketSizesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPacketSizesResponse) Reset() {
+	*x = SetPacketSizesResponse{}
+	mi := &file_packer_v1_packer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPacketSizesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPacketSizesResponse) ProtoMessage() {}
+
+func (x *SetPacketSizesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPacketSizesResponse.ProtoReflect.Descriptor instead.
+func (*SetPacketSizesResponse) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{3}
+}
+
+type GetOptimalPacketsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items int64                  `protobuf:"varint,1,opt,name=items,proto3" json:"items,omitempty"`
+	// FillPolicy bounds the overshoot of the packing: min-overshoot (the default when empty), exact, max-overshoot=N or
+	// max-overshoot=N%. A packing the policy rejects fails with FAILED_PRECONDITION.
+	FillPolicy    string `protobuf:"bytes,2,opt,name=fill_policy,json=fillPolicy,proto3" json:"fill_policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOptimalPacketsRequest) Reset() {
+	*x = GetOptimalPacketsRequest{}
+	mi := &file_packer_v1_packer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOptimalPacketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOptimalPacketsRequest) ProtoMessage() {}
+
+func (x *GetOptimalPacketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOptimalPacketsRequest.ProtoReflect.Descriptor instead.
+func (*GetOptimalPacketsRequest) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetOptimalPacketsRequest) GetItems() int64 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsRequest) GetFillPolicy() string {
+	if x != nil {
+		return x.FillPolicy
+	}
+	return ""
+}
+
+// PacketConstraint restricts the quantity of a packet size in a packing: either none of it, or at least min_quantity
+// packets in multiples of step. Zero min_quantity and step leave the size unconstrained.
+type PacketConstraint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	MinQuantity   int64                  `protobuf:"varint,2,opt,name=min_quantity,json=minQuantity,proto3" json:"min_quantity,omitempty"`
+	Step          int64                  `protobuf:"varint,3,opt,name=step,proto3" json:"step,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketConstraint) Reset() {
+	*x = PacketConstraint{}
+	mi := &file_packer_v1_packer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketConstraint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketConstraint) ProtoMessage() {}
+
+func (x *PacketConstraint) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketConstraint.ProtoReflect.Descriptor instead.
+func (*PacketConstraint) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PacketConstraint) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PacketConstraint) GetMinQuantity() int64 {
+	if x != nil {
+		return x.MinQuantity
+	}
+	return 0
+}
+
+func (x *PacketConstraint) GetStep() int64 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+// Dimensions are the outer measurements of a pack.
+type Dimensions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Length        float64                `protobuf:"fixed64,1,opt,name=length,proto3" json:"length,omitempty"`
+	Width         float64                `protobuf:"fixed64,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height        float64                `protobuf:"fixed64,3,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Dimensions) Reset() {
+	*x = Dimensions{}
+	mi := &file_packer_v1_packer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dimensions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dimensions) ProtoMessage() {}
+
+func (x *Dimensions) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dimensions.ProtoReflect.Descriptor instead.
+func (*Dimensions) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Dimensions) GetLength() float64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Dimensions) GetWidth() float64 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Dimensions) GetHeight() float64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+// PacketDefinition describes the pack of a packet size for downstream systems. The fields other than size are optional
+// and never change a packing.
+type PacketDefinition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Label         string                 `protobuf:"bytes,3,opt,name=label,proto3" json:"label,omitempty"`
+	Weight        float64                `protobuf:"fixed64,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	Cost          float64                `protobuf:"fixed64,5,opt,name=cost,proto3" json:"cost,omitempty"`
+	Dimensions    *Dimensions            `protobuf:"bytes,6,opt,name=dimensions,proto3" json:"dimensions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketDefinition) Reset() {
+	*x = PacketDefinition{}
+	mi := &file_packer_v1_packer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketDefinition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketDefinition) ProtoMessage() {}
+
+func (x *PacketDefinition) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketDefinition.ProtoReflect.Descriptor instead.
+func (*PacketDefinition) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PacketDefinition) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PacketDefinition) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PacketDefinition) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PacketDefinition) GetWeight() float64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *PacketDefinition) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *PacketDefinition) GetDimensions() *Dimensions {
+	if x != nil {
+		return x.Dimensions
+	}
+	return nil
+}
+
+// Pack is a number of packets of the same size, with the definition of the size when it has one.
+type Pack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Definition    *PacketDefinition      `protobuf:"bytes,3,opt,name=definition,proto3" json:"definition,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pack) Reset() {
+	*x = Pack{}
+	mi := &file_packer_v1_packer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pack) ProtoMessage() {}
+
+func (x *Pack) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pack.ProtoReflect.Descriptor instead.
+func (*Pack) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Pack) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Pack) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Pack) GetDefinition() *PacketDefinition {
+	if x != nil {
+		return x.Definition
+	}
+	return nil
+}
+
+type GetOptimalPacketsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items int64                  `protobuf:"varint,1,opt,name=items,proto3" json:"items,omitempty"`
+	// Packs are ordered by size, descending.
+	Packs []*Pack `protobuf:"bytes,2,rep,name=packs,proto3" json:"packs,omitempty"`
+	// TotalShipped is the number of items the packs hold, at least items.
+	TotalShipped int64 `protobuf:"varint,3,opt,name=total_shipped,json=totalShipped,proto3" json:"total_shipped,omitempty"`
+	// Overshoot is total_shipped - items.
+	Overshoot int64 `protobuf:"varint,4,opt,name=overshoot,proto3" json:"overshoot,omitempty"`
+	PackCount int64 `protobuf:"varint,5,opt,name=pack_count,json=packCount,proto3" json:"pack_count,omitempty"`
+	// Strategy is the solver that calculated the packs, or "lookup" when they were read from the precomputed table.
+	Strategy string `protobuf:"bytes,6,opt,name=strategy,proto3" json:"strategy,omitempty"`
+	// SizeSetVersion identifies the packet sizes used. It changes whenever the sizes are replaced.
+	SizeSetVersion uint64 `protobuf:"varint,7,opt,name=size_set_version,json=sizeSetVersion,proto3" json:"size_set_version,omitempty"`
+	ComputeTimeNs  int64  `protobuf:"varint,8,opt,name=compute_time_ns,json=computeTimeNs,proto3" json:"compute_time_ns,omitempty"`
+	// TotalWeight, TotalVolume and TotalCost sum the weight, volume and cost of the packs whose definitions give them.
+	TotalWeight float64 `protobuf:"fixed64,9,opt,name=total_weight,json=totalWeight,proto3" json:"total_weight,omitempty"`
+	TotalVolume float64 `protobuf:"fixed64,10,opt,name=total_volume,json=totalVolume,proto3" json:"total_volume,omitempty"`
+	TotalCost   float64 `protobuf:"fixed64,11,opt,name=total_cost,json=totalCost,proto3" json:"total_cost,omitempty"`
+	// Error is set, and the other fields but items are not, when a calculation of CalculateStream failed.
+	Error         *CalculationError `protobuf:"bytes,12,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOptimalPacketsResponse) Reset() {
+	*x = GetOptimalPacketsResponse{}
+	mi := &file_packer_v1_packer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOptimalPacketsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOptimalPacketsResponse) ProtoMessage() {}
+
+func (x *GetOptimalPacketsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOptimalPacketsResponse.ProtoReflect.Descriptor instead.
+func (*GetOptimalPacketsResponse) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetOptimalPacketsResponse) GetItems() int64 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetPacks() []*Pack {
+	if x != nil {
+		return x.Packs
+	}
+	return nil
+}
+
+func (x *GetOptimalPacketsResponse) GetTotalShipped() int64 {
+	if x != nil {
+		return x.TotalShipped
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetOvershoot() int64 {
+	if x != nil {
+		return x.Overshoot
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetPackCount() int64 {
+	if x != nil {
+		return x.PackCount
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+func (x *GetOptimalPacketsResponse) GetSizeSetVersion() uint64 {
+	if x != nil {
+		return x.SizeSetVersion
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetComputeTimeNs() int64 {
+	if x != nil {
+		return x.ComputeTimeNs
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetTotalWeight() float64 {
+	if x != nil {
+		return x.TotalWeight
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetTotalVolume() float64 {
+	if x != nil {
+		return x.TotalVolume
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetTotalCost() float64 {
+	if x != nil {
+		return x.TotalCost
+	}
+	return 0
+}
+
+func (x *GetOptimalPacketsResponse) GetError() *CalculationError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// CalculationError is the status GetOptimalPackets would have failed with.
+type CalculationError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Code is a google.rpc.Code, e.g. 3 for INVALID_ARGUMENT.
+	Code          int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CalculationError) Reset() {
+	*x = CalculationError{}
+	mi := &file_packer_v1_packer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculationError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculationError) ProtoMessage() {}
+
+func (x *CalculationError) ProtoReflect() protoreflect.Message {
+	mi := &file_packer_v1_packer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculationError.ProtoReflect.Descriptor instead.
+func (*CalculationError) Descriptor() ([]byte, []int) {
+	return file_packer_v1_packer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CalculationError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *CalculationError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_packer_v1_packer_proto protoreflect.FileDescriptor
+
+const file_packer_v1_packer_proto_rawDesc = "" +
+	"\n" +
+	"\x16packer/v1/packer.proto\x12\tpacker.v1\"\x18\n" +
+	"\x16ListPacketSizesRequest\"\xad\x01\n" +
+	"\x17ListPacketSizesResponse\x12\x14\n" +
+	"\x05sizes\x18\x01 \x03(\x03R\x05sizes\x12=\n" +
+	"\vconstraints\x18\x02 \x03(\v2\x1b.packer.v1.PacketConstraintR\vconstraints\x12=\n" +
+	"\vdefinitions\x18\x03 \x03(\v2\x1b.packer.v1.PacketDefinitionR\vdefinitions\"\xab\x01\n" +
+	"\x15SetPacketSizesRequest\x12\x14\n" +
+	"\x05sizes\x18\x01 \x03(\x03R\x05sizes\x12=\n" +
+	"\vconstraints\x18\x02 \x03(\v2\x1b.packer.v1.PacketConstraintR\vconstraints\x12=\n" +
+	"\vdefinitions\x18\x03 \x03(\v2\x1b.packer.v1.PacketDefinitionR\vdefinitions\"\x18\n" +
+	"\x16SetPacketSizesResponse\"Q\n" +
+	"\x18GetOptimalPacketsRequest\x12\x14\n" +
+	"\x05items\x18\x01 \x01(\x03R\x05items\x12\x1f\n" +
+	"\vfill_policy\x18\x02 \x01(\tR\n" +
+	"fillPolicy\"]\n" +
+	"\x10PacketConstraint\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12!\n" +
+	"\fmin_quantity\x18\x02 \x01(\x03R\vminQuantity\x12\x12\n" +
+	"\x04step\x18\x03 \x01(\x03R\x04step\"R\n" +
+	"\n" +
+	"Dimensions\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x01R\x06length\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x01R\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x01R\x06height\"\xaf\x01\n" +
+	"\x10PacketDefinition\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x14\n" +
+	"\x05label\x18\x03 \x01(\tR\x05label\x12\x16\n" +
+	"\x06weight\x18\x04 \x01(\x01R\x06weight\x12\x12\n" +
+	"\x04cost\x18\x05 \x01(\x01R\x04cost\x125\n" +
+	"\n" +
+	"dimensions\x18\x06 \x01(\v2\x15.packer.v1.DimensionsR\n" +
+	"dimensions\"s\n" +
+	"\x04Pack\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\x12;\n" +
+	"\n" +
+	"definition\x18\x03 \x01(\v2\x1b.packer.v1.PacketDefinitionR\n" +
+	"definition\"\xc0\x03\n" +
+	"\x19GetOptimalPacketsResponse\x12\x14\n" +
+	"\x05items\x18\x01 \x01(\x03R\x05items\x12%\n" +
+	"\x05packs\x18\x02 \x03(\v2\x0f.packer.v1.PackR\x05packs\x12#\n" +
+	"\rtotal_shipped\x18\x03 \x01(\x03R\ftotalShipped\x12\x1c\n" +
+	"\tovershoot\x18\x04 \x01(\x03R\tovershoot\x12\x1d\n" +
+	"\n" +
+	"pack_count\x18\x05 \x01(\x03R\tpackCount\x12\x1a\n" +
+	"\bstrategy\x18\x06 \x01(\tR\bstrategy\x12(\n" +
+	"\x10size_set_version\x18\a \x01(\x04R\x0esizeSetVersion\x12&\n" +
+	"\x0fcompute_time_ns\x18\b \x01(\x03R\rcomputeTimeNs\x12!\n" +
+	"\ftotal_weight\x18\t \x01(\x01R\vtotalWeight\x12!\n" +
+	"\ftotal_volume\x18\n" +
+	" \x01(\x01R\vtotalVolume\x12\x1d\n" +
+	"\n" +
+	"total_cost\x18\v \x01(\x01R\ttotalCost\x121\n" +
+	"\x05error\x18\f \x01(\v2\x1b.packer.v1.CalculationErrorR\x05error\"@\n" +
+	"\x10CalculationError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage2\x82\x03\n" +
+	"\rPackerService\x12X\n" +
+	"\x0fListPacketSizes\x12!.packer.v1.ListPacketSizesRequest\x1a\".packer.v1.ListPacketSizesResponse\x12U\n" +
+	"\x0eSetPacketSizes\x12 .packer.v1.SetPacketSizesRequest\x1a!.packer.v1.SetPacketSizesResponse\x12^\n" +
+	"\x11GetOptimalPackets\x12#.packer.v1.GetOptimalPacketsRequest\x1a$.packer.v1.GetOptimalPacketsResponse\x12`\n" +
+	"\x0fCalculateStream\x12#.packer.v1.GetOptimalPacketsRequest\x1a$.packer.v1.GetOptimalPacketsResponse(\x010\x01B>Z<github.com/dsha256/packer/internal/grpcapi/packerv1;packerv1b\x06proto3"
+
+var (
+	file_packer_v1_packer_proto_rawDescOnce sync.Once
+	file_packer_v1_packer_proto_rawDescData []byte
+)
+
+func file_packer_v1_packer_proto_rawDescGZIP() []byte {
+	file_packer_v1_packer_proto_rawDescOnce.Do(func() {
+		file_packer_v1_packer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_packer_v1_packer_proto_rawDesc), len(file_packer_v1_packer_proto_rawDesc)))
+	})
+	return file_packer_v1_packer_proto_rawDescData
+}
+
+var file_packer_v1_packer_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_packer_v1_packer_proto_goTypes = []any{
+	(*ListPacketSizesRequest)(nil),    // 0: packer.v1.ListPacketSizesRequest
+	(*ListPacketSizesResponse)(nil),   // 1: packer.v1.ListPacketSizesResponse
+	(*SetPacketSizesRequest)(nil),     // 2: packer.v1.SetPacketSizesRequest
+	(*SetPacketSizesResponse)(nil),    // 3: packer.v1.SetPacketSizesResponse
+	(*GetOptimalPacketsRequest)(nil),  // 4: packer.v1.GetOptimalPacketsRequest
+	(*PacketConstraint)(nil),          // 5: packer.v1.PacketConstraint
+	(*Dimensions)(nil),                // 6: packer.v1.Dimensions
+	(*PacketDefinition)(nil),          // 7: packer.v1.PacketDefinition
+	(*Pack)(nil),                      // 8: packer.v1.Pack
+	(*GetOptimalPacketsResponse)(nil), // 9: packer.v1.GetOptimalPacketsResponse
+	(*CalculationError)(nil),          // 10: packer.v1.CalculationError
+}
+var file_packer_v1_packer_proto_depIdxs = []int32{
+	5,  // 0: packer.v1.ListPacketSizesResponse.constraints:type_name -> packer.v1.PacketConstraint
+	7,  // 1: packer.v1.ListPacketSizesResponse.definitions:type_name -> packer.v1.PacketDefinition
+	5,  // 2: packer.v1.SetPacketSizesRequest.constraints:type_name -> packer.v1.PacketConstraint
+	7,  // 3: packer.v1.SetPacketSizesRequest.definitions:type_name -> packer.v1.PacketDefinition
+	6,  // 4: packer.v1.PacketDefinition.dimensions:type_name -> packer.v1.Dimensions
+	7,  // 5: packer.v1.Pack.definition:type_name -> packer.v1.PacketDefinition
+	8,  // 6: packer.v1.GetOptimalPacketsResponse.packs:type_name -> packer.v1.Pack
+	10, // 7: packer.v1.GetOptimalPacketsResponse.error:type_name -> packer.v1.CalculationError
+	0,  // 8: packer.v1.PackerService.ListPacketSizes:input_type -> packer.v1.ListPacketSizesRequest
+	2,  // 9: packer.v1.PackerService.SetPacketSizes:input_type -> packer.v1.SetPacketSizesRequest
+	4,  // 10: packer.v1.PackerService.GetOptimalPackets:input_type -> packer.v1.GetOptimalPacketsRequest
+	4,  // 11: packer.v1.PackerService.CalculateStream:input_type -> packer.v1.GetOptimalPacketsRequest
+	1,  // 12: packer.v1.PackerService.ListPacketSizes:output_type -> packer.v1.ListPacketSizesResponse
+	3,  // 13: packer.v1.PackerService.SetPacketSizes:output_type -> packer.v1.SetPacketSizesResponse
+	9,  // 14: packer.v1.PackerService.GetOptimalPackets:output_type -> packer.v1.GetOptimalPacketsResponse
+	9,  // 15: packer.v1.PackerService.CalculateStream:output_type -> packer.v1.GetOptimalPacketsResponse
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_packer_v1_packer_proto_init() }
+func file_packer_v1_packer_proto_init() {
+	if File_packer_v1_packer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_packer_v1_packer_proto_rawDesc), len(file_packer_v1_packer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_packer_v1_packer_proto_goTypes,
+		DependencyIndexes: file_packer_v1_packer_proto_depIdxs,
+		MessageInfos:      file_packer_v1_packer_proto_msgTypes,
+	}.Build()
+	File_packer_v1_packer_proto = out.File
+	file_packer_v1_packer_proto_goTypes = nil
+	file_packer_v1_packer_proto_depIdxs = nil
+}
@@ -0,0 +1,246 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: packer/v1/packer.proto
+
+package packerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PackerService_ListPacketSizes_FullMethodName   = "/packer.v1.PackerService/ListPacketSizes"
+	PackerService_SetPacketSizes_FullMethodName    = "/packer.v1.PackerService/SetPacketSizes"
+	PackerService_GetOptimalPackets_FullMethodName = "/packer.v1.PackerService/GetOptimalPackets"
+	PackerService_CalculateStream_FullMethodName   = "/packer.v1.PackerService/CalculateStream"
+)
+
+// PackerServiceClient is the client API for PackerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PackerService exposes the packet size management and optimal packing calculations.
+type PackerServiceClient interface {
+	// ListPacketSizes returns the currently configured packet sizes in ascending order, with their constraints and
+	// definitions.
+	ListPacketSizes(ctx context.Context, in *ListPacketSizesRequest, opts ...grpc.CallOption) (*ListPacketSizesResponse, error)
+	// SetPacketSizes replaces the configured packet sizes together with their constraints and definitions.
+	SetPacketSizes(ctx context.Context, in *SetPacketSizesRequest, opts ...grpc.CallOption) (*SetPacketSizesResponse, error)
+	// GetOptimalPackets calculates the optimal packs for a number of items.
+	GetOptimalPackets(ctx context.Context, in *GetOptimalPacketsRequest, opts ...grpc.CallOption) (*GetOptimalPacketsResponse, error)
+	// CalculateStream calculates the optimal packs for every request received on the stream, in order. A failed
+	// calculation answers a response with its error and the stream goes on.
+	CalculateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetOptimalPacketsRequest, GetOptimalPacketsResponse], error)
+}
+
+type packerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPackerServiceClient(cc grpc.ClientConnInterface) PackerServiceClient {
+	return &packerServiceClient{cc}
+}
+
+func (c *packerServiceClient) ListPacketSizes(ctx context.Context, in *ListPacketSizesRequest, opts ...grpc.CallOption) (*ListPacketSizesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPacketSizesResponse)
+	err := c.cc.Invoke(ctx, PackerService_ListPacketSizes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packerServiceClient) SetPacketSizes(ctx context.Context, in *SetPacketSizesRequest, opts ...grpc.CallOption) (*SetPacketSizesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPacketSizesResponse)
+	err := c.cc.Invoke(ctx, PackerService_SetPacketSizes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packerServiceClient) GetOptimalPackets(ctx context.Context, in *GetOptimalPacketsRequest, opts ...grpc.CallOption) (*GetOptimalPacketsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOptimalPacketsResponse)
+	err := c.cc.Invoke(ctx, PackerService_GetOptimalPackets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packerServiceClient) CalculateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetOptimalPacketsRequest, GetOptimalPacketsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PackerService_ServiceDesc.Streams[0], PackerService_CalculateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetOptimalPacketsRequest, GetOptimalPacketsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackerService_CalculateStreamClient = grpc.BidiStreamingClient[GetOptimalPacketsRequest, GetOptimalPacketsResponse]
+
+// PackerServiceServer is the server API for PackerService service.
+// All implementations must embed UnimplementedPackerServiceServer
+// for forward compatibility.
+//
+// PackerService exposes the packet size management and optimal packing calculations.
+type PackerServiceServer interface {
+	// ListPacketSizes returns the currently configured packet sizes in ascending order, with their constraints and
+	// definitions.
+	ListPacketSizes(context.Context, *ListPacketSizesRequest) (*ListPacketSizesResponse, error)
+	// SetPacketSizes replaces the configured packet sizes together with their constraints and definitions.
+	SetPacketSizes(context.Context, *SetPacketSizesRequest) (*SetPacketSizesResponse, error)
+	// GetOptimalPackets calculates the optimal packs for a number of items.
+	GetOptimalPackets(context.Context, *GetOptimalPacketsRequest) (*GetOptimalPacketsResponse, error)
+	// CalculateStream calculates the optimal packs for every request received on the stream, in order. A failed
+	// calculation answers a response with its error and the stream goes on.
+	CalculateStream(grpc.BidiStreamingServer[GetOptimalPacketsRequest, GetOptimalPacketsResponse]) error
+	mustEmbedUnimplementedPackerServiceServer()
+}
+
+// UnimplementedPackerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPackerServiceServer struct{}
+
+func (UnimplementedPackerServiceServer) ListPacketSizes(context.Context, *ListPacketSizesRequest) (*ListPacketSizesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPacketSizes not implemented")
+}
+func (UnimplementedPackerServiceServer) SetPacketSizes(context.Context, *SetPacketSizesRequest) (*SetPacketSizesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPacketSizes not implemented")
+}
+func (UnimplementedPackerServiceServer) GetOptimalPackets(context.Context, *GetOptimalPacketsRequest) (*GetOptimalPacketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOptimalPackets not implemented")
+}
+func (UnimplementedPackerServiceServer) CalculateStream(grpc.BidiStreamingServer[GetOptimalPacketsRequest, GetOptimalPacketsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method CalculateStream not implemented")
+}
+func (UnimplementedPackerServiceServer) mustEmbedUnimplementedPackerServiceServer() {}
+func (UnimplementedPackerServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafePackerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PackerServiceServer will
+// result in compilation errors.
+type UnsafePackerServiceServer interface {
+	mustEmbedUnimplementedPackerServiceServer()
+}
+
+func RegisterPackerServiceServer(s grpc.ServiceRegistrar, srv PackerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPackerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PackerService_ServiceDesc, srv)
+}
+
+func _PackerService_ListPacketSizes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPacketSizesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackerServiceServer).ListPacketSizes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackerService_ListPacketSizes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackerServiceServer).ListPacketSizes(ctx, req.(*ListPacketSizesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackerService_SetPacketSizes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPacketSizesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackerServiceServer).SetPacketSizes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackerService_SetPacketSizes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackerServiceServer).SetPacketSizes(ctx, req.(*SetPacketSizesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackerService_GetOptimalPackets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOptimalPacketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackerServiceServer).GetOptimalPackets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackerService_GetOptimalPackets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackerServiceServer).GetOptimalPackets(ctx, req.(*GetOptimalPacketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackerService_CalculateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PackerServiceServer).CalculateStream(&grpc.GenericServerStream[GetOptimalPacketsRequest, GetOptimalPacketsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PackerService_CalculateStreamServer = grpc.BidiStreamingServer[GetOptimalPacketsRequest, GetOptimalPacketsResponse]
+
+// PackerService_ServiceDesc is the grpc.ServiceDesc for PackerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PackerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "packer.v1.PackerService",
+	HandlerType: (*PackerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPacketSizes",
+			Handler:    _PackerService_ListPacketSizes_Handler,
+		},
+		{
+			MethodName: "SetPacketSizes",
+			Handler:    _PackerService_SetPacketSizes_Handler,
+		},
+		{
+			MethodName: "GetOptimalPackets",
+			Handler:    _PackerService_GetOptimalPackets_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CalculateStream",
+			Handler:       _PackerService_CalculateStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "packer/v1/packer.proto",
+}
@@ -0,0 +1,403 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dsha256/packer/internal/grpcapi/packerv1"
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/admission"
+)
+
+var _ packerv1.PackerServiceServer = (*Service)(nil)
+
+// Service implements the gRPC PackerService on top of packer.Packer.
+type Service struct {
+	packerv1.UnimplementedPackerServiceServer
+
+	logger        *slog.Logger
+	packer        packer.Packer
+	authenticator *middleware.Authenticator
+	admission     *admission.Controller
+	maxItems      int
+	maxSizes      int
+}
+
+// New creates a new Service. Calculations for more than maxItems items and more than maxSizes packet sizes are rejected.
+func New(logger *slog.Logger, packer packer.Packer, maxItems, maxSizes int) *Service {
+	return &Service{
+		logger:   logger,
+		packer:   packer,
+		maxItems: maxItems,
+		maxSizes: maxSizes,
+	}
+}
+
+// WithAuthenticator requires the same credentials as the HTTP API, passed as "x-api-key" or "authorization" metadata.
+func (s *Service) WithAuthenticator(authenticator *middleware.Authenticator) *Service {
+	s.authenticator = authenticator
+
+	return s
+}
+
+// WithAdmissionController bounds the total solver work running concurrently.
+func (s *Service) WithAdmissionController(controller *admission.Controller) *Service {
+	s.admission = controller
+
+	return s
+}
+
+// NewServer creates a grpc.Server with the service and its interceptors registered.
+func (s *Service) NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	server := grpc.NewServer(opts...)
+	packerv1.RegisterPackerServiceServer(server, s)
+
+	return server
+}
+
+// GracefulStop stops the server gracefully, or forcefully once ctx is done.
+func GracefulStop(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+func (s *Service) ListPacketSizes(ctx context.Context, _ *packerv1.ListPacketSizesRequest) (*packerv1.ListPacketSizesResponse, error) {
+	sizes, err := s.packer.ListPacketSizes(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	constraints, err := s.packer.ListPacketConstraints(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	definitions, err := s.packer.ListPacketDefinitions(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &packerv1.ListPacketSizesResponse{
+		Sizes:       make([]int64, 0, len(sizes)),
+		Constraints: make([]*packerv1.PacketConstraint, 0, len(constraints)),
+		Definitions: make([]*packerv1.PacketDefinition, 0, len(definitions)),
+	}
+	for _, size := range sizes {
+		response.Sizes = append(response.Sizes, int64(size))
+	}
+	for _, constraint := range constraints {
+		response.Constraints = append(response.Constraints, &packerv1.PacketConstraint{
+			Size:        int64(constraint.Size),
+			MinQuantity: int64(constraint.MinQuantity),
+			Step:        int64(constraint.Step),
+		})
+	}
+	for _, definition := range definitions {
+		response.Definitions = append(response.Definitions, toPacketDefinition(definition))
+	}
+
+	return response, nil
+}
+
+func (s *Service) SetPacketSizes(ctx context.Context, request *packerv1.SetPacketSizesRequest) (*packerv1.SetPacketSizesResponse, error) {
+	sizes := make([]types.PacketSize, 0, len(request.GetSizes()))
+	for _, size := range request.GetSizes() {
+		sizes = append(sizes, types.PacketSize(size))
+	}
+
+	details := types.PacketSizeDetails{
+		Constraints: make([]types.PacketConstraint, 0, len(request.GetConstraints())),
+		Definitions: make([]types.PacketDefinition, 0, len(request.GetDefinitions())),
+	}
+	for _, constraint := range request.GetConstraints() {
+		details.Constraints = append(details.Constraints, types.PacketConstraint{
+			Size:        types.PacketSize(constraint.GetSize()),
+			MinQuantity: types.PacketQuantity(constraint.GetMinQuantity()),
+			Step:        types.PacketQuantity(constraint.GetStep()),
+		})
+	}
+	for _, definition := range request.GetDefinitions() {
+		details.Definitions = append(details.Definitions, fromPacketDefinition(definition))
+	}
+
+	if err := validation.ValidatePacketSizes(sizes, details.Constraints...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := validation.ValidatePacketDefinitions(sizes, details.Definitions); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := validation.ValidatePacketSizesCount(sizes, s.maxSizes); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.packer.SetPacketSizes(ctx, sizes, details); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &packerv1.SetPacketSizesResponse{}, nil
+}
+
+func (s *Service) GetOptimalPackets(ctx context.Context, request *packerv1.GetOptimalPacketsRequest) (*packerv1.GetOptimalPacketsResponse, error) {
+	return s.calculate(ctx, request)
+}
+
+func (s *Service) CalculateStream(stream grpc.BidiStreamingServer[packerv1.GetOptimalPacketsRequest, packerv1.GetOptimalPacketsResponse]) error {
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := s.calculate(stream.Context(), request)
+		if err != nil {
+			// A failed calculation is answered in its response; only a closed stream ends it.
+			if stream.Context().Err() != nil {
+				return err
+			}
+			response = &packerv1.GetOptimalPacketsResponse{
+				Items: request.GetItems(),
+				Error: &packerv1.CalculationError{
+					Code:    int32(status.Code(err)), //nolint:gosec // gRPC codes are small.
+					Message: status.Convert(err).Message(),
+				},
+			}
+		}
+
+		if err = stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Service) calculate(ctx context.Context, request *packerv1.GetOptimalPacketsRequest) (*packerv1.GetOptimalPacketsResponse, error) {
+	items := request.GetItems()
+	if err := validation.ValidateItems(int(items), s.maxItems); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	policy, err := packer.ParseFillPolicy(request.GetFillPolicy())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	release, err := s.admit(ctx, int(items))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := s.packer.GetOptimalPackets(ctx, int(items))
+	if err == nil {
+		err = policy.Check(int(items), result.TotalShipped)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &packerv1.GetOptimalPacketsResponse{
+		Items:          items,
+		Packs:          make([]*packerv1.Pack, 0, len(result.Lines)),
+		TotalShipped:   int64(result.TotalShipped),
+		Overshoot:      int64(result.Overshoot),
+		PackCount:      int64(result.PackCount),
+		Strategy:       result.Strategy,
+		SizeSetVersion: result.SizeSetVersion,
+		ComputeTimeNs:  result.ComputeTime.Nanoseconds(),
+		TotalWeight:    result.TotalWeight,
+		TotalVolume:    result.TotalVolume,
+		TotalCost:      result.TotalCost,
+	}
+	// The lines are already ordered by size, descending.
+	for _, line := range result.Lines {
+		pack := &packerv1.Pack{
+			Size:     int64(line.Size),
+			Quantity: int64(line.Quantity),
+		}
+		if line.Definition != nil {
+			pack.Definition = toPacketDefinition(*line.Definition)
+		}
+		response.Packs = append(response.Packs, pack)
+	}
+
+	return response, nil
+}
+
+func toPacketDefinition(definition types.PacketDefinition) *packerv1.PacketDefinition {
+	message := &packerv1.PacketDefinition{
+		Size:   int64(definition.Size),
+		Id:     definition.ID,
+		Label:  definition.Label,
+		Weight: definition.Weight,
+		Cost:   definition.Cost,
+	}
+	if definition.Dimensions != nil {
+		message.Dimensions = &packerv1.Dimensions{
+			Length: definition.Dimensions.Length,
+			Width:  definition.Dimensions.Width,
+			Height: definition.Dimensions.Height,
+		}
+	}
+
+	return message
+}
+
+func fromPacketDefinition(message *packerv1.PacketDefinition) types.PacketDefinition {
+	definition := types.PacketDefinition{
+		Size:   types.PacketSize(message.GetSize()),
+		ID:     message.GetId(),
+		Label:  message.GetLabel(),
+		Weight: message.GetWeight(),
+		Cost:   message.GetCost(),
+	}
+	if dimensions := message.GetDimensions(); dimensions != nil {
+		definition.Dimensions = &types.Dimensions{
+			Length: dimensions.GetLength(),
+			Width:  dimensions.GetWidth(),
+			Height: dimensions.GetHeight(),
+		}
+	}
+
+	return definition
+}
+
+func (s *Service) admit(ctx context.Context, items int) (func(), error) {
+	if s.admission == nil {
+		return func() {}, nil
+	}
+
+	sizes, err := s.packer.ListPacketSizes(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	constraints, err := s.packer.ListPacketConstraints(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	release, err := s.admission.Acquire(ctx, packer.SolverWork(items, sizes, types.PacketSizeDetails{
+		Constraints: constraints,
+	}))
+	if err != nil {
+		if errors.Is(err, admission.ErrExceedsCapacity) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return release, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, packer.ErrNoPacketSizes):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, packer.ErrNoFeasiblePacking) || errors.Is(err, packer.ErrTableTooLarge):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// requiredRole mirrors the HTTP routes: only changing the packet sizes requires the admin role.
+func requiredRole(fullMethod string) middleware.Role {
+	if fullMethod == packerv1.PackerService_SetPacketSizes_FullMethodName {
+		return middleware.RoleAdmin
+	}
+
+	return middleware.RoleReader
+}
+
+func (s *Service) authorize(ctx context.Context, fullMethod string) error {
+	if s.authenticator == nil {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := s.authenticator.AuthenticateCredentials(firstValue(md, "x-api-key"), firstValue(md, "authorization"))
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if !principal.Role.Allows(requiredRole(fullMethod)) {
+		return status.Error(codes.PermissionDenied, middleware.ErrForbidden.Error())
+	}
+
+	return nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (s *Service) unaryInterceptor(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Error("Recovery from panic", "method", info.FullMethod, "error", recovered)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		s.logger.Info("gRPC request completed", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start).String())
+	}()
+
+	if err = s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, request)
+}
+
+func (s *Service) streamInterceptor(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Error("Recovery from panic", "method", info.FullMethod, "error", recovered)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		s.logger.Info("gRPC stream completed", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start).String())
+	}()
+
+	if err = s.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(server, stream)
+}
@@ -0,0 +1,249 @@
+package grpcserver_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/dsha256/packer/internal/grpcapi/packerv1"
+	"github.com/dsha256/packer/internal/grpcserver"
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+)
+
+func newTestClient(t *testing.T, service *grpcserver.Service) packerv1.PackerServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := service.NewServer()
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		grpcserver.GracefulStop(context.Background(), server)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return packerv1.NewPackerServiceClient(conn)
+}
+
+func newTestService() *grpcserver.Service {
+	return grpcserver.New(slog.New(slog.DiscardHandler), packer.New(), 1_000_000, 10)
+}
+
+func TestService_PacketSizes(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, newTestService())
+	ctx := context.Background()
+
+	listed, err := client.ListPacketSizes(ctx, &packerv1.ListPacketSizesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []int64{250, 500, 1000, 2000, 5000}, listed.GetSizes())
+
+	_, err = client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{Sizes: []int64{53, 23, 31}})
+	require.NoError(t, err)
+
+	listed, err = client.ListPacketSizes(ctx, &packerv1.ListPacketSizesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []int64{23, 31, 53}, listed.GetSizes())
+
+	_, err = client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{Sizes: []int64{10, 10}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_PacketSizeDetails(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, newTestService())
+	ctx := context.Background()
+
+	_, err := client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{
+		Sizes:       []int64{250, 500, 1000},
+		Constraints: []*packerv1.PacketConstraint{{Size: 250, MinQuantity: 2}},
+		Definitions: []*packerv1.PacketDefinition{{
+			Size:       500,
+			Id:         "BOX-500",
+			Weight:     1.5,
+			Cost:       2,
+			Dimensions: &packerv1.Dimensions{Length: 2, Width: 3, Height: 4},
+		}},
+	})
+	require.NoError(t, err)
+
+	listed, err := client.ListPacketSizes(ctx, &packerv1.ListPacketSizesRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.GetConstraints(), 1)
+	require.Equal(t, int64(2), listed.GetConstraints()[0].GetMinQuantity())
+	require.Len(t, listed.GetDefinitions(), 1)
+	require.Equal(t, "BOX-500", listed.GetDefinitions()[0].GetId())
+
+	// The constraint rules out a single 250 pack.
+	response, err := client.GetOptimalPackets(ctx, &packerv1.GetOptimalPacketsRequest{Items: 250})
+	require.NoError(t, err)
+	require.Len(t, response.GetPacks(), 1)
+	require.Equal(t, int64(500), response.GetPacks()[0].GetSize())
+	require.Equal(t, "BOX-500", response.GetPacks()[0].GetDefinition().GetId())
+	require.InDelta(t, 4.0, response.GetPacks()[0].GetDefinition().GetDimensions().GetHeight(), 1e-9)
+	require.InDelta(t, 1.5, response.GetTotalWeight(), 1e-9)
+	require.InDelta(t, 24.0, response.GetTotalVolume(), 1e-9)
+	require.InDelta(t, 2.0, response.GetTotalCost(), 1e-9)
+
+	_, err = client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{
+		Sizes:       []int64{250, 500},
+		Definitions: []*packerv1.PacketDefinition{{Size: 1000}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "a definition of a missing size")
+
+	_, err = client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{
+		Sizes:       []int64{250, 500},
+		Constraints: []*packerv1.PacketConstraint{{Size: 250, MinQuantity: -1}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "an invalid constraint")
+}
+
+func TestService_GetOptimalPackets(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, newTestService())
+
+	response, err := client.GetOptimalPackets(context.Background(), &packerv1.GetOptimalPacketsRequest{Items: 12001})
+	require.NoError(t, err)
+	require.Equal(t, int64(12001), response.GetItems())
+	require.Len(t, response.GetPacks(), 3)
+	require.Equal(t, int64(5000), response.GetPacks()[0].GetSize())
+	require.Equal(t, int64(2), response.GetPacks()[0].GetQuantity())
+	require.Equal(t, int64(2000), response.GetPacks()[1].GetSize())
+	require.Equal(t, int64(250), response.GetPacks()[2].GetSize())
+	require.Equal(t, int64(12250), response.GetTotalShipped())
+	require.Equal(t, int64(249), response.GetOvershoot())
+	require.Equal(t, int64(4), response.GetPackCount())
+	require.Equal(t, "v1", response.GetStrategy())
+	require.Equal(t, uint64(1), response.GetSizeSetVersion())
+
+	for _, items := range []int64{0, -1, 1_000_001} {
+		_, err = client.GetOptimalPackets(context.Background(), &packerv1.GetOptimalPacketsRequest{Items: items})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "items %d", items)
+	}
+	_, err = client.GetOptimalPackets(context.Background(), &packerv1.GetOptimalPacketsRequest{Items: 12001, FillPolicy: "cheapest"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetOptimalPackets(context.Background(), &packerv1.GetOptimalPacketsRequest{Items: 12001, FillPolicy: "max-overshoot=248"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	response, err = client.GetOptimalPackets(context.Background(), &packerv1.GetOptimalPacketsRequest{Items: 12001, FillPolicy: "max-overshoot=249"})
+	require.NoError(t, err)
+	require.Equal(t, int64(249), response.GetOvershoot())
+}
+
+func TestService_CalculateStream(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, newTestService())
+
+	stream, err := client.CalculateStream(context.Background())
+	require.NoError(t, err)
+
+	requests := []int64{1, 251, 501}
+	for _, items := range requests {
+		require.NoError(t, stream.Send(&packerv1.GetOptimalPacketsRequest{Items: items}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var received []int64
+	for {
+		response, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		require.NoError(t, recvErr)
+		received = append(received, response.GetItems())
+	}
+	require.Equal(t, requests, received)
+}
+
+func TestService_CalculateStreamErrors(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, newTestService())
+
+	stream, err := client.CalculateStream(context.Background())
+	require.NoError(t, err)
+
+	requests := []*packerv1.GetOptimalPacketsRequest{
+		{Items: 1},
+		{Items: 0},
+		{Items: 12001, FillPolicy: "max-overshoot=248"},
+		{Items: 501},
+	}
+	for _, request := range requests {
+		require.NoError(t, stream.Send(request))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var responses []*packerv1.GetOptimalPacketsResponse
+	for {
+		response, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		require.NoError(t, recvErr, "a failed calculation does not close the stream")
+		responses = append(responses, response)
+	}
+	require.Len(t, responses, len(requests))
+
+	require.Nil(t, responses[0].GetError())
+	require.Equal(t, int64(0), responses[1].GetItems())
+	require.Equal(t, int32(codes.InvalidArgument), responses[1].GetError().GetCode())
+	require.NotEmpty(t, responses[1].GetError().GetMessage())
+	require.Empty(t, responses[1].GetPacks())
+	require.Equal(t, int64(12001), responses[2].GetItems())
+	require.Equal(t, int32(codes.FailedPrecondition), responses[2].GetError().GetCode())
+	require.Nil(t, responses[3].GetError())
+	require.Equal(t, int64(2), responses[3].GetPackCount())
+}
+
+func TestService_Authentication(t *testing.T) {
+	t.Parallel()
+
+	readerDigest := sha256.Sum256([]byte("reader-key"))
+	service := newTestService().WithAuthenticator(middleware.NewAuthenticator(middleware.AuthConfig{
+		APIKeys: map[string]middleware.Principal{
+			hex.EncodeToString(readerDigest[:]): {Subject: "reader", Role: middleware.RoleReader},
+		},
+	}))
+	client := newTestClient(t, service)
+
+	_, err := client.ListPacketSizes(context.Background(), &packerv1.ListPacketSizesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "reader-key")
+	_, err = client.ListPacketSizes(ctx, &packerv1.ListPacketSizesRequest{})
+	require.NoError(t, err)
+
+	_, err = client.SetPacketSizes(ctx, &packerv1.SetPacketSizesRequest{Sizes: []int64{1}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
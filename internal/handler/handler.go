@@ -4,42 +4,137 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/goccy/go-json"
 
+	"github.com/dsha256/packer/internal/health"
 	"github.com/dsha256/packer/internal/middleware"
 	"github.com/dsha256/packer/internal/packer"
 	"github.com/dsha256/packer/internal/responder"
+	"github.com/dsha256/packer/pkg/admission"
 	"github.com/dsha256/packer/pkg/cache"
 )
 
-var ErrMethodNotAllowed = errors.New("method not allowed")
+var (
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrNotReady         = errors.New("service is not ready")
+)
 
 type Handler struct {
-	logger *slog.Logger
-	packer packer.Packer
-	cache  cache.Cache
+	logger           *slog.Logger
+	packer           packer.Packer
+	cache            cache.Cache
+	rateLimiter      *middleware.RateLimiter
+	authenticator    *middleware.Authenticator
+	admission        *admission.Controller
+	prober           *health.Prober
+	catalogs         map[string]packer.Packer
+	v1Deprecation    Deprecation
+	cacheTTL         atomic.Int64
+	itemsPerCostUnit atomic.Int64
+	maxItems         int
+	maxSizes         int
 }
 
+// DefaultCacheTTL is how long calculation results are cached unless WithCacheTTL says otherwise.
+const DefaultCacheTTL = time.Hour
+
 func New(
 	logger *slog.Logger,
 	packer packer.Packer,
 	cache cache.ClosableCache,
 ) *Handler {
-	return &Handler{
-		logger: logger,
-		packer: packer,
-		cache:  cache,
+	h := &Handler{
+		logger:   logger,
+		packer:   packer,
+		cache:    cache,
+		maxItems: MaxAllowedItems,
+		maxSizes: MaxAllowedSizes,
+		v1Deprecation: Deprecation{
+			At:     DefaultV1DeprecatedAt,
+			Sunset: DefaultV1Sunset,
+		},
+		prober: health.NewProber(
+			health.CacheChecker(cache),
+			health.PacketSizesChecker(packer),
+			health.SolverChecker(),
+		),
 	}
+	h.cacheTTL.Store(int64(DefaultCacheTTL))
+
+	return h
+}
+
+// WithLimits bounds the items of a calculation and the number of packet sizes that can be set.
+func (h *Handler) WithLimits(maxItems, maxSizes int) *Handler {
+	h.maxItems = maxItems
+	h.maxSizes = maxSizes
+
+	return h
+}
+
+// WithCacheTTL sets how long calculation results are cached. It is safe to call while serving requests.
+func (h *Handler) WithCacheTTL(ttl time.Duration) *Handler {
+	h.cacheTTL.Store(int64(ttl))
+
+	return h
+}
+
+// WithRateLimiter enables per-client rate limiting of the packet routes.
+// Calculations are charged an extra token per itemsPerCostUnit requested items.
+func (h *Handler) WithRateLimiter(limiter *middleware.RateLimiter, itemsPerCostUnit int) *Handler {
+	h.rateLimiter = limiter
+	h.SetItemsPerCostUnit(itemsPerCostUnit)
+
+	return h
+}
+
+// SetItemsPerCostUnit changes the calculation cost of an enabled rate limiter. It is safe to call while serving requests.
+func (h *Handler) SetItemsPerCostUnit(itemsPerCostUnit int) {
+	h.itemsPerCostUnit.Store(int64(itemsPerCostUnit))
+}
+
+// WithAuthenticator requires authentication on the packet routes.
+// Readers may calculate and list, only admins may change state.
+func (h *Handler) WithAuthenticator(authenticator *middleware.Authenticator) *Handler {
+	h.authenticator = authenticator
+
+	return h
+}
+
+// WithAdmissionController bounds the total solver work running concurrently.
+func (h *Handler) WithAdmissionController(controller *admission.Controller) *Handler {
+	h.admission = controller
+
+	return h
+}
+
+// WithHealthCheckers adds readiness checks on top of the cache, packet sizes and solver checks.
+func (h *Handler) WithHealthCheckers(checkers ...health.Checker) *Handler {
+	h.prober.AddCheckers(checkers...)
+
+	return h
+}
+
+// MarkShuttingDown makes /readyz report not ready so that load balancers stop routing new traffic.
+func (h *Handler) MarkShuttingDown() {
+	h.prober.MarkShuttingDown()
 }
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.Handle("/api/v1/packet/calculate", h.wrapHandler(h.handlePacketsCalculation))
-	mux.Handle("/api/v1/packet/size", h.wrapHandler(h.handlePacketSizes))
-	mux.Handle("/api/v1/health", h.wrapHandler(h.handleHealth))
+	h.registerV1Routes(mux)
+	h.registerV2Routes(mux)
+	mux.Handle("/livez", h.wrapHandler(h.handleLiveness))
+	mux.Handle("/readyz", h.wrapHandler(h.handleReadiness))
 	h.logger.Info("Routes registered")
 }
 
+func (h *Handler) calculationCost(r *http.Request) float64 {
+	return middleware.QueryItemsCost("items", int(h.itemsPerCostUnit.Load()))(r)
+}
+
 func (h *Handler) wrapHandler(handler http.HandlerFunc) http.Handler {
 	return middleware.LoggingMiddleware(
 		h.logger,
@@ -50,8 +145,55 @@ func (h *Handler) wrapHandler(handler http.HandlerFunc) http.Handler {
 	)
 }
 
+// wrapProtectedHandler applies the optional rate limiting and authentication on top of wrapHandler.
+// Rate limiting runs first so that credential guessing is throttled too.
+func (h *Handler) wrapProtectedHandler(handler http.HandlerFunc, cost middleware.CostFunc, role middleware.RoleFunc) http.Handler {
+	var next http.Handler = handler
+	if h.authenticator != nil {
+		next = middleware.AuthMiddleware(h.authenticator, role, next)
+	}
+	if h.rateLimiter != nil {
+		next = middleware.RateLimitMiddleware(h.rateLimiter, cost, next)
+	}
+
+	return h.wrapHandler(next.ServeHTTP)
+}
+
 func (h *Handler) handleHealth(w http.ResponseWriter, _ *http.Request) {
-	responder.WriteSuccess(w, http.StatusOK, "All services are up and running", json.RawMessage{})
+	stats := make(map[string]any)
+	if h.admission != nil {
+		stats["admission"] = h.admission.Stats()
+	}
+	if reporter, ok := h.packer.(packer.LookupTableReporter); ok {
+		stats["lookup_table"] = reporter.LookupTableStats()
+	}
+	if reporter, ok := h.packer.(packer.DPTableReporter); ok {
+		stats["dp_table"] = reporter.DPTableStats()
+	}
+
+	if len(stats) == 0 {
+		responder.WriteSuccess(w, http.StatusOK, "All services are up and running", json.RawMessage{})
+
+		return
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "All services are up and running", stats)
+}
+
+func (h *Handler) handleLiveness(w http.ResponseWriter, _ *http.Request) {
+	responder.WriteSuccess(w, http.StatusOK, "alive", json.RawMessage{})
+}
+
+func (h *Handler) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := h.prober.Readiness(r.Context())
+	if !report.Ready() {
+		h.logger.Warn("Service is not ready", "report", report)
+		responder.WriteErrorWithData(w, http.StatusServiceUnavailable, ErrNotReady, report)
+
+		return
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "ready", report)
 }
 
 func (h *Handler) handleError(w http.ResponseWriter, err error, status int) {
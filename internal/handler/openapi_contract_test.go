@@ -0,0 +1,531 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+func newContractRouter(t *testing.T) routers.Router {
+	t.Helper()
+
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(handler.OpenAPISpec())
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(context.Background()))
+
+	router, err := legacy.NewRouter(spec)
+	require.NoError(t, err)
+
+	return router
+}
+
+func newContractMux(t *testing.T, configure func(h *handler.Handler)) *http.ServeMux {
+	t.Helper()
+
+	newCache := cache.NewInMemoryCache()
+	t.Cleanup(newCache.Close)
+
+	newHandler := handler.New(slog.New(slog.DiscardHandler), packer.New(), newCache)
+	if configure != nil {
+		configure(newHandler)
+	}
+
+	mux := http.NewServeMux()
+	newHandler.RegisterRoutes(mux)
+
+	return mux
+}
+
+// assertContract sends the request to the real handler and validates the response against the OpenAPI spec.
+func assertContract(t *testing.T, router routers.Router, mux http.Handler, req *http.Request, expectedStatus int) {
+	t.Helper()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		require.NoError(t, err)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, expectedStatus, rec.Code, rec.Body.String())
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	route, pathParams, err := router.FindRoute(req)
+	require.NoError(t, err)
+
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 rec.Code,
+		Header:                 rec.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+	})
+	require.NoError(t, err, rec.Body.String())
+}
+
+func newJSONRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	t.Parallel()
+
+	router := newContractRouter(t)
+
+	tests := []struct {
+		configure      func(h *handler.Handler)
+		newRequest     func() *http.Request
+		name           string
+		expectedStatus int
+	}{
+		{
+			name: "calculate",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=12001", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "calculate invalid items",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=0", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "calculate exact",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=750&fill_policy=exact", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "calculate no feasible packing",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=12001&fill_policy=max-overshoot%3D1%25", nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "list sizes",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/size", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "put sizes",
+			newRequest: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPut, "/api/v1/packet/size", bytes.NewBufferString(`{"sizes":[23,31,53]}`))
+				req.Header.Set("Content-Type", "application/json")
+
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "put sizes with constraints",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v1/packet/size",
+					`{"sizes":[250,500,5000],"constraints":[{"size":5000,"step":2},{"size":500,"min_quantity":3}]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "list sizes with constraints",
+			configure: func(h *handler.Handler) {
+				rec := httptest.NewRecorder()
+				mux := http.NewServeMux()
+				h.RegisterRoutes(mux)
+				mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size",
+					`{"sizes":[250,5000],"constraints":[{"size":5000,"step":2}]}`))
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/size", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "calculate with definitions",
+			configure: func(h *handler.Handler) {
+				rec := httptest.NewRecorder()
+				mux := http.NewServeMux()
+				h.RegisterRoutes(mux)
+				mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size", `{"sizes":[250,5000],"definitions":[
+					{"size":5000,"id":"box-xl","label":"Pallet box","weight":52.5,"cost":4,"dimensions":{"length":1.2,"width":0.8,"height":0.5}},
+					{"size":250,"weight":2.5}
+				]}`))
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=5001", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "list sizes with definitions",
+			configure: func(h *handler.Handler) {
+				rec := httptest.NewRecorder()
+				mux := http.NewServeMux()
+				h.RegisterRoutes(mux)
+				mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size",
+					`{"sizes":[250,5000],"definitions":[{"size":5000,"id":"box-xl","weight":52.5}]}`))
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/size", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "put definition with a negative weight",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v1/packet/size", `{"sizes":[250],"definitions":[{"size":250,"weight":-1}]}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "put duplicated sizes",
+			newRequest: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPut, "/api/v1/packet/size", bytes.NewBufferString(`{"sizes":[23,23]}`))
+				req.Header.Set("Content-Type", "application/json")
+
+				return req
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "put no sizes",
+			newRequest: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPut, "/api/v1/packet/size", bytes.NewBufferString(`{"sizes":[]}`))
+				req.Header.Set("Content-Type", "application/json")
+
+				return req
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "put too many sizes",
+			configure: func(h *handler.Handler) {
+				h.WithLimits(handler.MaxAllowedItems, 2)
+			},
+			newRequest: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPut, "/api/v1/packet/size", bytes.NewBufferString(`{"sizes":[23,31,53]}`))
+				req.Header.Set("Content-Type", "application/json")
+
+				return req
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "calculate above max items",
+			configure: func(h *handler.Handler) {
+				h.WithLimits(100, handler.MaxAllowedSizes)
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=101", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "order",
+			configure: func(h *handler.Handler) {
+				h.WithCatalogs(map[string]packer.Packer{"bolts": packer.New()})
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/orders/calculate",
+					`{"lines":[{"sku":"nut","quantity":251},{"sku":"bolt","catalog":"bolts","quantity":12001}]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "order unknown catalog",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/orders/calculate", `{"lines":[{"catalog":"bolts","quantity":1}]}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "shipment plan",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/shipment/plan", `{"items":12001,"packs":[
+					{"size":5000,"weight":52,"dimensions":{"length":1.2,"width":0.8,"height":0.5}},
+					{"size":2000,"weight":21,"dimensions":{"length":0.6,"width":0.4,"height":0.5}},
+					{"size":250,"weight":3,"dimensions":{"length":0.3,"width":0.2,"height":0.2}}
+				],"container":{"max_weight":100,"max_volume":1.5}}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "shipment plan of lines",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/shipment/plan",
+					`{"lines":[{"size":250,"quantity":3}],"packs":[{"size":250,"weight":3}],"container":{"max_packs":2},"mode":"exact"}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "shipment plan without container limits",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/shipment/plan",
+					`{"lines":[{"size":250,"quantity":3}],"packs":[{"size":250,"weight":3}],"container":{}}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "health",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "liveness",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/livez", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "readiness",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not ready while shutting down",
+			configure: func(h *handler.Handler) {
+				h.MarkShuttingDown()
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "unauthenticated",
+			configure: func(h *handler.Handler) {
+				h.WithAuthenticator(middleware.NewAuthenticator(middleware.AuthConfig{}))
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/size", nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "rate limited",
+			configure: func(h *handler.Handler) {
+				limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+					Default: middleware.ClientLimit{RequestsPerSecond: 0.001, Burst: 1},
+				})
+				t.Cleanup(limiter.Close)
+				_, err := limiter.Allow(httptest.NewRequest(http.MethodGet, "/", nil), 1)
+				require.NoError(t, err)
+				h.WithRateLimiter(limiter, 1000)
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=1", nil)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "v2 calculate",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":12001}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "v2 calculate invalid items",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":0}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "v2 calculate unknown field",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":1,"sizes":[1]}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "v2 calculate no feasible packing",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":251,"fill_policy":"max-overshoot=10%"}`)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "v2 get sizes",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v2/packet-sizes", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "v2 put sizes",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[23,31,53]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "v2 put sizes with constraints",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes",
+					`{"sizes":[250,5000],"constraints":[{"size":5000,"min_quantity":2,"step":2}]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "v2 put sizes with definitions",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes",
+					`{"sizes":[250,5000],"definitions":[{"size":5000,"id":"box-xl","label":"Pallet box","weight":52.5}]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "v2 put constraint of an unknown size",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[250],"constraints":[{"size":500,"step":2}]}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "v2 put duplicated sizes",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[23,23]}`)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "v2 unauthenticated",
+			configure: func(h *handler.Handler) {
+				h.WithAuthenticator(middleware.NewAuthenticator(middleware.AuthConfig{}))
+			},
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v2/packet-sizes", nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "v2 rate limited",
+			configure: func(h *handler.Handler) {
+				limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+					Default: middleware.ClientLimit{RequestsPerSecond: 0.001, Burst: 1},
+				})
+				t.Cleanup(limiter.Close)
+				_, err := limiter.Allow(httptest.NewRequest(http.MethodGet, "/", nil), 1)
+				require.NoError(t, err)
+				h.WithRateLimiter(limiter, 1000)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":1}`)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "v2 cost exceeds burst",
+			configure: func(h *handler.Handler) {
+				limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+					Default: middleware.ClientLimit{RequestsPerSecond: 1, Burst: 5},
+				})
+				t.Cleanup(limiter.Close)
+				h.WithRateLimiter(limiter, 1000)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":5000}`)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "order cost capped at the largest calculation",
+			configure: func(h *handler.Handler) {
+				limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+					Default: middleware.ClientLimit{RequestsPerSecond: 1, Burst: 11},
+				})
+				t.Cleanup(limiter.Close)
+				h.WithLimits(10_000, handler.MaxAllowedSizes).WithRateLimiter(limiter, 1000)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/orders/calculate",
+					`{"lines":[{"quantity":10000},{"quantity":9999},{"quantity":9000}]}`)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "shipment plan of lines costs its packs",
+			configure: func(h *handler.Handler) {
+				limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+					Default: middleware.ClientLimit{RequestsPerSecond: 1, Burst: 5},
+				})
+				t.Cleanup(limiter.Close)
+				h.WithRateLimiter(limiter, 1000)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v1/shipment/plan",
+					`{"lines":[{"size":250,"quantity":3000},{"size":500,"quantity":3000}],"container":{"max_packs":10}}`)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertContract(t, router, newContractMux(t, tt.configure), tt.newRequest(), tt.expectedStatus)
+		})
+	}
+}
+
+func TestOpenAPISpecEndpoint(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, string(handler.OpenAPISpec()), rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "/api/v1/openapi.json")
+}
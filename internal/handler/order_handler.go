@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/goccy/go-json"
+
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/responder"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+)
+
+var (
+	ErrNoOrderLines      = errors.New("an order needs at least one line")
+	ErrTooManyOrderLines = errors.New("number of order lines exceeds maximum allowed value")
+	ErrUnknownCatalog    = errors.New("unknown catalog")
+)
+
+const (
+	// DefaultCatalog names the packet sizes managed through /api/v1/packet/size, used by order lines without a catalog.
+	DefaultCatalog = "default"
+	// MaxOrderLines bounds the lines of an order.
+	MaxOrderLines = 100
+)
+
+// OrderLine is a line of POST /api/v1/orders/calculate: a quantity of a SKU packed with the sizes of a catalog.
+// The SKU is only echoed back.
+type OrderLine struct {
+	SKU      string `json:"sku"`
+	Catalog  string `json:"catalog"`
+	Quantity int    `json:"quantity"`
+}
+
+// OrderRequest is the body of POST /api/v1/orders/calculate. FillPolicy is read by packer.ParseFillPolicy and applies
+// to every line, min-overshoot when empty.
+type OrderRequest struct {
+	FillPolicy string      `json:"fill_policy"`
+	Lines      []OrderLine `json:"lines"`
+}
+
+// WithCatalogs adds named catalogs, each with its own packet sizes, for order lines. The packer given to New is the
+// DefaultCatalog and cannot be replaced.
+func (h *Handler) WithCatalogs(catalogs map[string]packer.Packer) *Handler {
+	h.catalogs = make(map[string]packer.Packer, len(catalogs))
+	for name, catalogPacker := range catalogs {
+		if name != DefaultCatalog {
+			h.catalogs[name] = catalogPacker
+		}
+	}
+
+	return h
+}
+
+// catalogPacker returns the packer of a catalog.
+func (h *Handler) catalogPacker(catalog string) (packer.Packer, bool) {
+	if catalog == DefaultCatalog {
+		return h.packer, true
+	}
+	catalogPacker, ok := h.catalogs[catalog]
+
+	return catalogPacker, ok
+}
+
+// orderCost charges an order like a single calculation of the quantities of all its lines, capped at the largest
+// calculation, so that an order accepted line by line never costs more than the burst fits.
+func (h *Handler) orderCost(r *http.Request) float64 {
+	return middleware.BodyCost(int(h.itemsPerCostUnit.Load()), func(body []byte) (int, error) {
+		var order OrderRequest
+		if err := json.Unmarshal(body, &order); err != nil {
+			return 0, err
+		}
+
+		items := 0
+		for _, line := range order.Lines {
+			items = min(items+min(max(line.Quantity, 0), h.maxItems), h.maxItems)
+		}
+
+		return items, nil
+	})(r)
+}
+
+func (h *Handler) handleOrderCalculation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.handleError(w, ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var order OrderRequest
+	if err := decodeBody(w, r, &order); err != nil {
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	catalogPackers, err := h.validateOrder(order.Lines)
+	if err != nil {
+		h.logger.Warn("Invalid order", "lines", len(order.Lines), "err", err)
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	policy, err := packer.ParseFillPolicy(order.FillPolicy)
+	if err != nil {
+		h.logger.Warn("Invalid fill policy", "fill_policy", order.FillPolicy, "err", err)
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	lines := make([]types.OrderLineResult, 0, len(order.Lines))
+	for index, line := range order.Lines {
+		result, calculateErr := h.calculateInCatalog(r, line.Catalog, catalogPackers[index], line.Quantity)
+		if calculateErr == nil {
+			calculateErr = policy.Check(line.Quantity, result.TotalShipped)
+		}
+		if calculateErr != nil {
+			h.handleError(w, fmt.Errorf("lines[%d]: %w", index, calculateErr),
+				h.calculationErrorStatus(w, line.Quantity, calculateErr))
+
+			return
+		}
+
+		lines = append(lines, types.OrderLineResult{SKU: line.SKU, Catalog: line.Catalog, Result: result})
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "", types.NewOrderResult(lines))
+}
+
+// validateOrder checks every line before any is calculated and returns the packer of each line. Lines without a
+// catalog are set to the DefaultCatalog.
+func (h *Handler) validateOrder(lines []OrderLine) ([]packer.Packer, error) {
+	if len(lines) == 0 {
+		return nil, ErrNoOrderLines
+	}
+	if len(lines) > MaxOrderLines {
+		return nil, fmt.Errorf("%w of %d", ErrTooManyOrderLines, MaxOrderLines)
+	}
+
+	catalogPackers := make([]packer.Packer, len(lines))
+	for index := range lines {
+		line := &lines[index]
+		if line.Catalog == "" {
+			line.Catalog = DefaultCatalog
+		}
+
+		catalogPacker, ok := h.catalogPacker(line.Catalog)
+		if !ok {
+			return nil, fmt.Errorf("lines[%d]: %w %q", index, ErrUnknownCatalog, line.Catalog)
+		}
+		if err := validation.ValidateItems(line.Quantity, h.maxItems); err != nil {
+			return nil, fmt.Errorf("lines[%d]: %w", index, err)
+		}
+		catalogPackers[index] = catalogPacker
+	}
+
+	return catalogPackers, nil
+}
@@ -0,0 +1,168 @@
+package handler_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+func newOrderMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+
+	bolts, err := packer.NewWithConfig(packer.Config{DefaultSizes: []types.PacketSize{23, 31, 53}})
+	require.NoError(t, err)
+
+	newCache := cache.NewInMemoryCache()
+	t.Cleanup(newCache.Close)
+
+	mux := http.NewServeMux()
+	handler.New(slog.New(slog.DiscardHandler), packer.New(), newCache).
+		WithLimits(100_000, handler.MaxAllowedSizes).
+		WithCatalogs(map[string]packer.Packer{"bolts": bolts}).
+		RegisterRoutes(mux)
+
+	return mux
+}
+
+func calculateOrder(t *testing.T, mux http.Handler, body string) types.OrderResult {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/orders/calculate", body))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Empty(t, rec.Header().Get("Deprecation"), "orders have no v2 successor")
+
+	var response types.Response[types.OrderResult]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+
+	return response.Data
+}
+
+func TestOrderCalculation(t *testing.T) {
+	t.Parallel()
+
+	mux := newOrderMux(t)
+
+	order := calculateOrder(t, mux, `{"lines":[
+		{"sku":"washer","quantity":12001},
+		{"sku":"bolt","catalog":"bolts","quantity":500},
+		{"sku":"nut","catalog":"default","quantity":251}
+	]}`)
+	require.Len(t, order.Lines, 3)
+
+	require.Equal(t, "washer", order.Lines[0].SKU)
+	require.Equal(t, handler.DefaultCatalog, order.Lines[0].Catalog)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}},
+		order.Lines[0].Result.Lines)
+
+	require.Equal(t, "bolts", order.Lines[1].Catalog)
+	require.Equal(t, []types.PackingLine{{Size: 53, Quantity: 9}, {Size: 23, Quantity: 1}}, order.Lines[1].Result.Lines)
+
+	require.Equal(t, []types.PackingLine{{Size: 500, Quantity: 1}}, order.Lines[2].Result.Lines)
+
+	require.Equal(t, types.OrderTotals{
+		ItemsRequested: 12001 + 500 + 251,
+		TotalShipped:   12250 + 500 + 500,
+		Overshoot:      249 + 0 + 249,
+		PackCount:      4 + 10 + 1,
+	}, order.Totals)
+}
+
+func TestOrderCalculation_CachesPerCatalog(t *testing.T) {
+	t.Parallel()
+
+	mux := newOrderMux(t)
+
+	order := calculateOrder(t, mux, `{"lines":[{"quantity":500},{"catalog":"bolts","quantity":500}]}`)
+	require.False(t, order.Lines[0].Result.Cached)
+	require.False(t, order.Lines[1].Result.Cached, "catalogs do not share results for the same items")
+
+	order = calculateOrder(t, mux, `{"lines":[{"catalog":"bolts","quantity":500},{"quantity":500},{"quantity":12001}]}`)
+	require.True(t, order.Lines[0].Result.Cached)
+	require.True(t, order.Lines[1].Result.Cached)
+	require.False(t, order.Lines[2].Result.Cached)
+
+	require.True(t, calculate(t, mux, "/api/v1/packet/calculate?items=12001").Cached,
+		"order lines of the default catalog share the cache of single calculations")
+}
+
+func TestOrderCalculation_FillPolicy(t *testing.T) {
+	t.Parallel()
+
+	mux := newOrderMux(t)
+
+	order := calculateOrder(t, mux, `{"fill_policy":"exact","lines":[{"quantity":500},{"catalog":"bolts","quantity":500}]}`)
+	require.Zero(t, order.Totals.Overshoot)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/orders/calculate",
+		`{"fill_policy":"max-overshoot=100","lines":[{"quantity":500},{"quantity":12001}]}`))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "lines[1]: "+packer.ErrNoFeasiblePacking.Error())
+}
+
+func TestOrderCalculation_Invalid(t *testing.T) {
+	t.Parallel()
+
+	mux := newOrderMux(t)
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedError string
+	}{
+		{
+			name:          "no lines",
+			body:          `{"lines":[]}`,
+			expectedError: handler.ErrNoOrderLines.Error(),
+		},
+		{
+			name:          "unknown catalog",
+			body:          `{"lines":[{"quantity":1},{"catalog":"screws","quantity":1}]}`,
+			expectedError: `lines[1]: unknown catalog "screws"`,
+		},
+		{
+			name:          "invalid quantity",
+			body:          `{"lines":[{"quantity":1},{"catalog":"bolts","quantity":0}]}`,
+			expectedError: "lines[1]: ",
+		},
+		{
+			name:          "quantity above max items",
+			body:          `{"lines":[{"quantity":100001}]}`,
+			expectedError: "lines[0]: ",
+		},
+		{
+			name:          "invalid fill policy",
+			body:          `{"fill_policy":"cheapest","lines":[{"quantity":1}]}`,
+			expectedError: packer.ErrInvalidFillPolicy.Error(),
+		},
+		{
+			name:          "malformed",
+			body:          `{"lines":`,
+			expectedError: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/orders/calculate", tt.body))
+			require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+			var response types.Response[json.RawMessage]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			require.Contains(t, response.Err, tt.expectedError)
+		})
+	}
+}
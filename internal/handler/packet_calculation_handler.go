@@ -2,20 +2,31 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/dsha256/packer/internal/packer"
 	"github.com/dsha256/packer/internal/responder"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/admission"
 	"github.com/dsha256/packer/pkg/cache"
 	"github.com/dsha256/packer/pkg/safeconv"
 )
 
 var (
-	ErrInvalidItems  = errors.New("items should be positive integer")
-	ErrItemsTooLarge = errors.New("items exceed maximum allowed value of 1 billion")
+	ErrInvalidItems  = validation.ErrInvalidItems
+	ErrItemsTooLarge = validation.ErrItemsTooLarge
+	ErrTooManySizes  = validation.ErrTooManySizes
+	ErrServerBusy    = errors.New("server is busy, retry later")
 )
 
-const MaxAllowedItems = 1_000_000_000
+const (
+	MaxAllowedItems = 1_000_000_000
+	// MaxAllowedSizes bounds the packet sizes accepted by PUT /api/v1/packet/size.
+	MaxAllowedSizes = 100
+)
 
 func (h *Handler) handlePacketsCalculation(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -34,57 +45,169 @@ func (h *Handler) handleGetOptimalPackets(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	items := r.Form.Get("items")
-	itemsInt := safeconv.ParseInt(items)
-	if itemsInt < 1 {
-		h.logger.Warn("Invalid incoming items", "err", ErrInvalidItems)
-		h.handleError(w, ErrInvalidItems, http.StatusBadRequest)
+	itemsInt := safeconv.ParseInt(r.Form.Get("items"))
+	if err := validation.ValidateItems(itemsInt, h.maxItems); err != nil {
+		h.logger.Warn("Invalid incoming items", "items", itemsInt, "err", err)
+		h.handleError(w, err, http.StatusBadRequest)
 
 		return
 	}
 
-	if itemsInt > MaxAllowedItems {
-		h.logger.Warn("Items exceed maximum allowed", "items", itemsInt, "max", MaxAllowedItems)
-		h.handleError(w, ErrItemsTooLarge, http.StatusBadRequest)
+	policy, err := packer.ParseFillPolicy(r.Form.Get("fill_policy"))
+	if err != nil {
+		h.logger.Warn("Invalid fill policy", "fill_policy", r.Form.Get("fill_policy"), "err", err)
+		h.handleError(w, err, http.StatusBadRequest)
 
 		return
 	}
 
-	cachedPackets, err := h.cache.Get(r.Context(), items)
+	result, err := h.calculateWithPolicy(r, itemsInt, policy)
+	if err != nil {
+		h.handleError(w, err, h.calculationErrorStatus(w, itemsInt, err))
+
+		return
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "", map[string]any{
+		"optimal_packets": result.Packets(),
+		"result":          result,
+	})
+}
+
+// calculate returns the cached result for items and the current packet sizes, or solves and caches it.
+func (h *Handler) calculate(r *http.Request, items int) (types.PackingResult, error) {
+	return h.calculateInCatalog(r, DefaultCatalog, h.packer, items)
+}
+
+// calculateWithPolicy is calculate for results the fill policy accepts. The policy never changes the packing, so
+// results are cached regardless of it.
+func (h *Handler) calculateWithPolicy(r *http.Request, items int, policy packer.FillPolicy) (types.PackingResult, error) {
+	result, err := h.calculate(r, items)
+	if err != nil {
+		return types.PackingResult{}, err
+	}
+	if err = policy.Check(items, result.TotalShipped); err != nil {
+		return types.PackingResult{}, err
+	}
+
+	return result, nil
+}
+
+// calculateInCatalog is calculate for the packet sizes of a catalog. Results are cached per catalog.
+func (h *Handler) calculateInCatalog(
+	r *http.Request,
+	catalog string,
+	catalogPacker packer.Packer,
+	items int,
+) (types.PackingResult, error) {
+	version, err := catalogPacker.PacketSizesVersion(r.Context())
 	if err != nil {
-		if errors.Is(err, cache.ErrNoKey) {
-			h.logger.Info("Items is not cached", "err", err)
-		} else {
-			h.logger.Error("Failed to get items", "err", err)
-			h.handleError(w, err, http.StatusInternalServerError)
+		return types.PackingResult{}, err
+	}
+
+	cached, err := h.cache.Get(r.Context(), calculationCacheKey(catalog, version, items))
+	switch {
+	case err == nil:
+		if result, ok := cached.(types.PackingResult); ok {
+			result.Cached = true
 
-			return
+			return result, nil
 		}
+	case errors.Is(err, cache.ErrNoKey):
+		h.logger.Info("Items is not cached", "err", err)
+	default:
+		h.logger.Error("Failed to get items", "err", err)
+
+		return types.PackingResult{}, err
 	}
-	if err == nil {
-		responder.WriteSuccess(w, http.StatusOK, "", map[string]any{
-			"optimal_packets": cachedPackets,
-		})
 
-		return
+	release, err := h.admit(r, catalogPacker, items)
+	if err != nil {
+		return types.PackingResult{}, admissionError{err: err}
 	}
+	defer release()
 
-	packets, err := h.packer.GetOptimalPackets(r.Context(), itemsInt)
+	result, err := catalogPacker.GetOptimalPackets(r.Context(), items)
 	if err != nil {
 		h.logger.Error("Failed to get optimal packets", "err", err)
-		h.handleError(w, err, http.StatusInternalServerError)
 
-		return
+		return types.PackingResult{}, err
 	}
 
-	if err = h.cache.Set(r.Context(), items, packets, 1*time.Hour); err != nil {
+	// The sizes may have changed since the version was read; the result is cached under the version it used.
+	key := calculationCacheKey(catalog, result.SizeSetVersion, items)
+	if err = h.cache.Set(r.Context(), key, result, time.Duration(h.cacheTTL.Load())); err != nil {
 		h.logger.Error("Failed to set items to cache", "err", err)
-		h.handleError(w, err, http.StatusInternalServerError)
 
-		return
+		return types.PackingResult{}, err
 	}
 
-	responder.WriteSuccess(w, http.StatusOK, "", map[string]any{
-		"optimal_packets": packets,
-	})
+	return result, nil
+}
+
+// admissionError marks calculations that were not admitted, which are answered with 503, or 422 when they can never be.
+type admissionError struct {
+	err error
+}
+
+func (e admissionError) Error() string {
+	return e.err.Error()
+}
+
+func (e admissionError) Unwrap() error {
+	return e.err
+}
+
+// calculationErrorStatus returns the status of a failed calculation: 422 when the fill policy rejects the packing, the
+// sizes make the search too large or it weighs more than the whole admission capacity, 503 when it was otherwise not
+// admitted, with Retry-After when the server is only busy, and 500 otherwise.
+func (h *Handler) calculationErrorStatus(w http.ResponseWriter, items int, err error) int {
+	if errors.Is(err, packer.ErrNoFeasiblePacking) || errors.Is(err, packer.ErrTableTooLarge) ||
+		errors.Is(err, admission.ErrExceedsCapacity) {
+		h.logger.Info("Calculation cannot be solved", "items", items, "err", err)
+
+		return http.StatusUnprocessableEntity
+	}
+	if !errors.As(err, new(admissionError)) {
+		return http.StatusInternalServerError
+	}
+
+	h.logger.Warn("Calculation was not admitted", "items", items, "err", err)
+	if errors.Is(err, ErrServerBusy) {
+		w.Header().Set("Retry-After", "1")
+	}
+
+	return http.StatusServiceUnavailable
+}
+
+// calculationCacheKey keys results by catalog and packet sizes version, so that replacing the sizes never serves a
+// result calculated with the previous ones.
+func calculationCacheKey(catalog string, sizesVersion uint64, items int) string {
+	return fmt.Sprintf("%s:%d:%d", catalog, sizesVersion, items)
+}
+
+// admit reserves solver capacity for a calculation, weighed by packer.SolverWork for the sizes and their constraints.
+func (h *Handler) admit(r *http.Request, catalogPacker packer.Packer, items int) (func(), error) {
+	if h.admission == nil {
+		return func() {}, nil
+	}
+
+	sizes, err := catalogPacker.ListPacketSizes(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := catalogPacker.ListPacketConstraints(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := h.admission.Acquire(r.Context(), packer.SolverWork(items, sizes, types.PacketSizeDetails{
+		Constraints: constraints,
+	}))
+	if errors.Is(err, admission.ErrQueueFull) || errors.Is(err, admission.ErrWaitTimeout) {
+		return nil, fmt.Errorf("%w: %w", ErrServerBusy, err)
+	}
+
+	return release, err
 }
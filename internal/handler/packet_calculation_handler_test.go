@@ -0,0 +1,188 @@
+package handler_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/admission"
+)
+
+func calculate(t *testing.T, mux http.Handler, target string) types.PackingResult {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response types.Response[struct {
+		OptimalPackets map[types.PacketSize]types.PacketQuantity `json:"optimal_packets"`
+		Result         types.PackingResult                       `json:"result"`
+	}]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, response.Data.Result.Packets(), response.Data.OptimalPackets)
+
+	return response.Data.Result
+}
+
+func TestCalculate_CachesPerSizeSet(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	result := calculate(t, mux, "/api/v1/packet/calculate?items=12001")
+	require.False(t, result.Cached)
+	require.Equal(t, uint64(1), result.SizeSetVersion)
+	require.Equal(t, 249, result.Overshoot)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}}, result.Lines)
+
+	result = calculate(t, mux, "/api/v1/packet/calculate?items=12001")
+	require.True(t, result.Cached)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/packet/size", bytes.NewBufferString(`{"sizes":[23,31,53]}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	result = calculate(t, mux, "/api/v1/packet/calculate?items=12001")
+	require.False(t, result.Cached, "results of the previous sizes are not served")
+	require.Equal(t, uint64(2), result.SizeSetVersion)
+	require.Equal(t, 0, result.Overshoot)
+	require.Equal(t, 12001, result.TotalShipped)
+}
+
+func TestCalculate_FillPolicy(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	result := calculate(t, mux, "/api/v1/packet/calculate?items=12001&fill_policy=max-overshoot%3D249")
+	require.Equal(t, 249, result.Overshoot)
+	require.True(t, calculate(t, mux, "/api/v1/packet/calculate?items=12001").Cached,
+		"the policy does not change the cached packing")
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedError  error
+		expectedStatus int
+	}{
+		{
+			name:           "exact",
+			target:         "/api/v1/packet/calculate?items=12001&fill_policy=exact",
+			expectedError:  packer.ErrNoFeasiblePacking,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "overshoot above the bound",
+			target:         "/api/v1/packet/calculate?items=12001&fill_policy=max-overshoot%3D2%25",
+			expectedError:  packer.ErrNoFeasiblePacking,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "invalid policy",
+			target:         "/api/v1/packet/calculate?items=12001&fill_policy=max-overshoot",
+			expectedError:  packer.ErrInvalidFillPolicy,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			var response types.Response[json.RawMessage]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			require.Contains(t, response.Err, tt.expectedError.Error())
+		})
+	}
+}
+
+func TestCalculate_EchoesDefinitions(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size", `{"sizes":[250,500,1000,2000,5000],"definitions":[
+		{"size":5000,"id":"box-xl","label":"Pallet box","weight":52.5,"cost":4},
+		{"size":250,"id":"box-s","weight":2.5,"dimensions":{"length":0.3,"width":0.2,"height":0.2}}
+	]}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	for _, cached := range []bool{false, true} {
+		result := calculate(t, mux, "/api/v1/packet/calculate?items=12001")
+		require.Equal(t, cached, result.Cached)
+		require.Equal(t, []types.PackingLine{
+			{Size: 5000, Quantity: 2, Definition: &types.PacketDefinition{Size: 5000, ID: "box-xl", Label: "Pallet box", Weight: 52.5, Cost: 4}},
+			{Size: 2000, Quantity: 1},
+			{
+				Size:       250,
+				Quantity:   1,
+				Definition: &types.PacketDefinition{Size: 250, ID: "box-s", Weight: 2.5, Dimensions: &types.Dimensions{Length: 0.3, Width: 0.2, Height: 0.2}},
+			},
+		}, result.Lines)
+		require.InDelta(t, 2*52.5+2.5, result.TotalWeight, 1e-9)
+		require.InDelta(t, 0.012, result.TotalVolume, 1e-9)
+		require.InDelta(t, 8, result.TotalCost, 1e-9)
+	}
+
+	order := calculateOrder(t, mux, `{"lines":[{"quantity":12001},{"quantity":250}]}`)
+	require.InDelta(t, 2*52.5+2.5+2.5, order.Totals.TotalWeight, 1e-9)
+}
+
+func TestCalculate_SearchTooLarge(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size", `{"sizes":[250,3000000000]}`))
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), validation.ErrSizeTooLarge.Error())
+
+	// A batch of 1000 packets of the largest size lies beyond the search bound.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/packet/size",
+		`{"sizes":[250,1000000000],"constraints":[{"size":1000000000,"min_quantity":1000}]}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=1", nil))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), packer.ErrTableTooLarge.Error())
+}
+
+func TestCalculate_ExceedsAdmissionCapacity(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, func(h *handler.Handler) {
+		h.WithAdmissionController(admission.New(admission.Config{Capacity: 10_000}))
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=5000", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/packet/calculate?items=6000", nil))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Empty(t, rec.Header().Get("Retry-After"), "retrying never helps")
+	require.Contains(t, rec.Body.String(), admission.ErrExceedsCapacity.Error())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":6000}`))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"code":"exceeds_capacity"`)
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/goccy/go-json"
 
+	"github.com/dsha256/packer/internal/packer"
 	"github.com/dsha256/packer/internal/responder"
 	"github.com/dsha256/packer/internal/types"
 	"github.com/dsha256/packer/internal/validation"
@@ -22,20 +24,33 @@ func (h *Handler) handlePacketSizes(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleListPacketSizes(w http.ResponseWriter, r *http.Request) {
-	packetSizes, err := h.packer.ListPacketSizes(r.Context())
+	sizeSet, err := h.packetSizeSet(r.Context())
 	if err != nil {
 		h.handleError(w, err, http.StatusInternalServerError)
 
 		return
 	}
 
-	responder.WriteSuccess(w, http.StatusOK, "", map[string][]types.PacketSize{
-		"packet_sizes": packetSizes,
+	responder.WriteSuccess(w, http.StatusOK, "", packetSizesResponse{
+		PacketSizes:       sizeSet.Sizes,
+		PacketConstraints: sizeSet.Constraints,
+		PacketDefinitions: sizeSet.Definitions,
 	})
 }
 
+// packetSizesResponse is the data of GET /api/v1/packet/size.
+type packetSizesResponse struct {
+	PacketSizes       []types.PacketSize       `json:"packet_sizes"`
+	PacketConstraints []types.PacketConstraint `json:"packet_constraints,omitempty"`
+	PacketDefinitions []types.PacketDefinition `json:"packet_definitions,omitempty"`
+}
+
 type PutPacketSizesRequest struct {
 	Sizes []types.PacketSize `json:"sizes"`
+	// Constraints restrict the quantity of some of the sizes. They are replaced together with the sizes.
+	Constraints []types.PacketConstraint `json:"constraints"`
+	// Definitions describe the packs of some of the sizes. They are replaced together with the sizes.
+	Definitions []types.PacketDefinition `json:"definitions"`
 }
 
 func (h *Handler) handlePutPacketSizes(w http.ResponseWriter, r *http.Request) {
@@ -46,13 +61,35 @@ func (h *Handler) handlePutPacketSizes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validation.ValidatePacketSizes(sizes.Sizes); err != nil {
+	if err := validation.ValidatePacketSizes(sizes.Sizes, sizes.Constraints...); err != nil {
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validation.ValidatePacketDefinitions(sizes.Sizes, sizes.Definitions); err != nil {
 		h.handleError(w, err, http.StatusBadRequest)
 
 		return
 	}
 
-	if err := h.packer.SetPacketSizes(r.Context(), sizes.Sizes); err != nil {
+	if err := validation.ValidatePacketSizesCount(sizes.Sizes, h.maxSizes); err != nil {
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.packer.SetPacketSizes(r.Context(), sizes.Sizes, types.PacketSizeDetails{
+		Constraints: sizes.Constraints,
+		Definitions: sizes.Definitions,
+	}); err != nil {
+		h.logger.Error("Failed to set packet sizes", "err", err)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, packer.ErrNoPacketSizes) {
+			statusCode = http.StatusBadRequest
+		}
+		h.handleError(w, err, statusCode)
+
 		return
 	}
 
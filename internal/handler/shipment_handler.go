@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/goccy/go-json"
+
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/responder"
+	"github.com/dsha256/packer/internal/shipment"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+)
+
+var (
+	ErrShipmentPacks      = errors.New("a shipment plan needs either items or lines, not both")
+	ErrShipmentFillPolicy = errors.New("a shipment plan's fill policy applies to items, not lines")
+)
+
+// ShipmentPlanRequest is the body of POST /api/v1/shipment/plan. The packs are either calculated for Items with the
+// default packet sizes and FillPolicy, or given as Lines, e.g. from an earlier calculation. Without Packs, the weight
+// and dimensions of each pack come from the definition of its size on the lines.
+type ShipmentPlanRequest struct {
+	Lines      []types.PackingLine `json:"lines"`
+	Packs      []shipment.PackSpec `json:"packs"`
+	Mode       shipment.Mode       `json:"mode"`
+	FillPolicy string              `json:"fill_policy"`
+	Container  shipment.Container  `json:"container"`
+	Items      int                 `json:"items"`
+}
+
+// ShipmentPlanResult is the data of POST /api/v1/shipment/plan. Packing is the calculation of the requested items,
+// omitted when the request gave lines.
+type ShipmentPlanResult struct {
+	Packing *types.PackingResult `json:"packing,omitempty"`
+	Plan    shipment.Plan        `json:"plan"`
+}
+
+// shipmentPlanCost charges a plan of items like their calculation, and a plan of lines by their packs, capped at the
+// packs a plan takes.
+func (h *Handler) shipmentPlanCost(r *http.Request) float64 {
+	return middleware.BodyCost(int(h.itemsPerCostUnit.Load()), func(body []byte) (int, error) {
+		var request ShipmentPlanRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return 0, err
+		}
+		if request.Items != 0 {
+			return request.Items, nil
+		}
+
+		packs := 0
+		for _, line := range request.Lines {
+			packs = min(packs+min(max(int(line.Quantity), 0), shipment.MaxPacks), shipment.MaxPacks)
+		}
+
+		return packs, nil
+	})(r)
+}
+
+func (h *Handler) handleShipmentPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.handleError(w, ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var request ShipmentPlanRequest
+	if err := decodeBody(w, r, &request); err != nil {
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+	if (request.Items == 0) == (len(request.Lines) == 0) {
+		h.handleError(w, ErrShipmentPacks, http.StatusBadRequest)
+
+		return
+	}
+	if request.FillPolicy != "" && request.Items == 0 {
+		h.handleError(w, ErrShipmentFillPolicy, http.StatusBadRequest)
+
+		return
+	}
+
+	var result ShipmentPlanResult
+	lines := request.Lines
+	if request.Items != 0 {
+		if err := validation.ValidateItems(request.Items, h.maxItems); err != nil {
+			h.logger.Warn("Invalid incoming items", "items", request.Items, "err", err)
+			h.handleError(w, err, http.StatusBadRequest)
+
+			return
+		}
+
+		policy, err := packer.ParseFillPolicy(request.FillPolicy)
+		if err != nil {
+			h.logger.Warn("Invalid fill policy", "fill_policy", request.FillPolicy, "err", err)
+			h.handleError(w, err, http.StatusBadRequest)
+
+			return
+		}
+
+		packing, err := h.calculateWithPolicy(r, request.Items, policy)
+		if err != nil {
+			h.handleError(w, err, h.calculationErrorStatus(w, request.Items, err))
+
+			return
+		}
+		result.Packing = &packing
+		lines = packing.Lines
+	}
+
+	packs := request.Packs
+	if len(packs) == 0 {
+		packs = packSpecs(lines)
+	}
+
+	plan, err := shipment.NewPlan(lines, packs, request.Container, request.Mode)
+	if err != nil {
+		h.logger.Warn("Invalid shipment plan", "lines", len(lines), "packs", len(packs), "err", err)
+		h.handleError(w, err, http.StatusBadRequest)
+
+		return
+	}
+	result.Plan = plan
+
+	responder.WriteSuccess(w, http.StatusOK, "", result)
+}
+
+// packSpecs returns the pack specs of the sizes of lines that have a definition.
+func packSpecs(lines []types.PackingLine) []shipment.PackSpec {
+	specs := make([]shipment.PackSpec, 0, len(lines))
+	for _, line := range lines {
+		if line.Definition == nil || slices.ContainsFunc(specs, func(spec shipment.PackSpec) bool {
+			return spec.Size == line.Size
+		}) {
+			continue
+		}
+
+		spec := shipment.PackSpec{Size: line.Size, Weight: line.Definition.Weight}
+		if line.Definition.Dimensions != nil {
+			spec.Dimensions = *line.Definition.Dimensions
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/shipment"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+const shipmentPacks = `"packs":[
+	{"size":5000,"weight":52,"dimensions":{"length":1.2,"width":0.8,"height":0.5}},
+	{"size":2000,"weight":21,"dimensions":{"length":0.6,"width":0.4,"height":0.5}},
+	{"size":250,"weight":3,"dimensions":{"length":0.3,"width":0.2,"height":0.2}}
+]`
+
+func newShipmentMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+
+	newCache := cache.NewInMemoryCache()
+	t.Cleanup(newCache.Close)
+
+	mux := http.NewServeMux()
+	handler.New(slog.New(slog.DiscardHandler), packer.New(), newCache).
+		WithLimits(100_000, handler.MaxAllowedSizes).
+		RegisterRoutes(mux)
+
+	return mux
+}
+
+func TestShipmentPlan(t *testing.T) {
+	t.Parallel()
+
+	mux := newShipmentMux(t)
+
+	tests := []struct {
+		name            string
+		body            string
+		expected        [][]types.PackingLine
+		expectedPacking bool
+	}{
+		{
+			name: "calculates the packs for items",
+			body: `{"items":12001,` + shipmentPacks + `,"container":{"max_weight":100}}`,
+			expected: [][]types.PackingLine{
+				{{Size: 5000, Quantity: 1}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}},
+				{{Size: 5000, Quantity: 1}},
+			},
+			expectedPacking: true,
+		},
+		{
+			name: "plans the given lines",
+			body: `{"lines":[{"size":2000,"quantity":3},{"size":250,"quantity":2}],` + shipmentPacks +
+				`,"container":{"max_packs":2},"mode":"first-fit-decreasing"}`,
+			expected: [][]types.PackingLine{
+				{{Size: 2000, Quantity: 2}},
+				{{Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}},
+				{{Size: 250, Quantity: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/shipment/plan", tt.body))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			require.Empty(t, rec.Header().Get("Deprecation"), "shipment plans have no v2 successor")
+
+			var response types.Response[handler.ShipmentPlanResult]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+
+			require.Equal(t, tt.expectedPacking, response.Data.Packing != nil)
+			containers := make([][]types.PackingLine, 0, len(response.Data.Plan.Containers))
+			for _, load := range response.Data.Plan.Containers {
+				containers = append(containers, load.Lines)
+			}
+			require.Equal(t, tt.expected, containers)
+			require.True(t, response.Data.Plan.Optimal)
+		})
+	}
+}
+
+func TestShipmentPlan_PacksFromDefinitions(t *testing.T) {
+	t.Parallel()
+
+	mux := newShipmentMux(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[250,5000],"definitions":[
+		{"size":5000,"weight":52},
+		{"size":250,"weight":3}
+	]}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/shipment/plan", `{"items":10001,"container":{"max_weight":100}}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response types.Response[handler.ShipmentPlanResult]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.InDelta(t, response.Data.Packing.TotalWeight, response.Data.Plan.TotalWeight, 1e-9)
+	require.Len(t, response.Data.Plan.Containers, 2)
+}
+
+func TestShipmentPlan_FillPolicy(t *testing.T) {
+	t.Parallel()
+
+	mux := newShipmentMux(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/shipment/plan",
+		`{"items":12001,"fill_policy":"max-overshoot=248",`+shipmentPacks+`,"container":{"max_packs":2}}`))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), packer.ErrNoFeasiblePacking.Error())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/shipment/plan",
+		`{"items":12001,"fill_policy":"max-overshoot=249",`+shipmentPacks+`,"container":{"max_packs":2}}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestShipmentPlan_Errors(t *testing.T) {
+	t.Parallel()
+
+	mux := newShipmentMux(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "neither items nor lines", body: `{` + shipmentPacks + `,"container":{"max_packs":2}}`, expected: handler.ErrShipmentPacks.Error()},
+		{
+			name:     "both items and lines",
+			body:     `{"items":1,"lines":[{"size":250,"quantity":1}],` + shipmentPacks + `,"container":{"max_packs":2}}`,
+			expected: handler.ErrShipmentPacks.Error(),
+		},
+		{
+			name:     "fill policy with lines",
+			body:     `{"fill_policy":"exact","lines":[{"size":250,"quantity":1}],` + shipmentPacks + `,"container":{"max_packs":2}}`,
+			expected: handler.ErrShipmentFillPolicy.Error(),
+		},
+		{
+			name:     "invalid fill policy",
+			body:     `{"items":1,"fill_policy":"cheapest",` + shipmentPacks + `,"container":{"max_packs":2}}`,
+			expected: packer.ErrInvalidFillPolicy.Error(),
+		},
+		{name: "invalid items", body: `{"items":-1,` + shipmentPacks + `,"container":{"max_packs":2}}`, expected: "items should be positive integer"},
+		{name: "missing pack spec", body: `{"items":1,"packs":[],"container":{"max_packs":2}}`, expected: shipment.ErrMissingPackSpec.Error()},
+		{name: "invalid container", body: `{"items":1,` + shipmentPacks + `,"container":{}}`, expected: shipment.ErrInvalidContainer.Error()},
+		{name: "pack too heavy", body: `{"items":5000,` + shipmentPacks + `,"container":{"max_weight":50}}`, expected: shipment.ErrPackTooLarge.Error()},
+		{name: "unknown field", body: `{"items":1,"pallet":{}}`, expected: handler.ErrInvalidBody.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/shipment/plan", tt.body))
+			require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+			var response types.Response[any]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			require.Contains(t, response.Err, tt.expected)
+		})
+	}
+}
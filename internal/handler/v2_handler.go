@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/goccy/go-json"
+
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/responder"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+	"github.com/dsha256/packer/pkg/admission"
+)
+
+var (
+	ErrInvalidBody = errors.New("invalid request body")
+	ErrNotFound    = errors.New("route not found")
+)
+
+// maxRequestBodyBytes bounds the JSON bodies accepted by the v2 routes.
+const maxRequestBodyBytes = 1 << 20
+
+// CalculationRequest is the body of POST /api/v2/calculations.
+type CalculationRequest struct {
+	// FillPolicy is read by packer.ParseFillPolicy, min-overshoot when empty.
+	FillPolicy string `json:"fill_policy"`
+	Items      int    `json:"items"`
+}
+
+// PacketSizesV2 is the body of PUT /api/v2/packet-sizes and the response of both packet size routes.
+// Version is ignored on PUT.
+type PacketSizesV2 struct {
+	Sizes       []types.PacketSize       `json:"sizes"`
+	Constraints []types.PacketConstraint `json:"constraints,omitempty"`
+	Definitions []types.PacketDefinition `json:"definitions,omitempty"`
+	Version     uint64                   `json:"version"`
+}
+
+// errorCodes maps known errors to the stable codes of the v2 API. Errors not listed get a code from their status.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{validation.ErrInvalidItems, "invalid_items"},
+	{validation.ErrItemsTooLarge, "items_too_large"},
+	{validation.ErrNonPositiveSize, "invalid_size"},
+	{validation.ErrSizeTooLarge, "size_too_large"},
+	{validation.ErrDuplicatedSizes, "duplicate_sizes"},
+	{validation.ErrTooManySizes, "too_many_sizes"},
+	{validation.ErrInvalidConstraint, "invalid_constraint"},
+	{validation.ErrInvalidDefinition, "invalid_definition"},
+	{packer.ErrNoPacketSizes, "no_packet_sizes"},
+	{packer.ErrInvalidFillPolicy, "invalid_fill_policy"},
+	{packer.ErrNoFeasiblePacking, "no_feasible_packing"},
+	{ErrInvalidBody, "invalid_body"},
+	{ErrServerBusy, "server_busy"},
+	{admission.ErrExceedsCapacity, "exceeds_capacity"},
+	{middleware.ErrRateLimited, "rate_limited"},
+	{middleware.ErrCostExceedsBurst, "cost_exceeds_burst"},
+	{middleware.ErrForbidden, "forbidden"},
+}
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthenticated",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusMethodNotAllowed:    "method_not_allowed",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusTooManyRequests:     "rate_limited",
+	http.StatusInternalServerError: "internal",
+	http.StatusServiceUnavailable:  "unavailable",
+}
+
+// ErrorCode returns the v2 error code of err answered with status.
+func ErrorCode(err error, status int) string {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+
+	return "error"
+}
+
+// writeErrorV2 writes the v2 error envelope.
+func writeErrorV2(w http.ResponseWriter, status int, err error) {
+	responder.WriteJSON(w, status, types.ErrorResponseV2{
+		Error: types.APIError{
+			Code:    ErrorCode(err, status),
+			Message: err.Error(),
+			Status:  status,
+		},
+	})
+}
+
+// v2 makes the shared middleware answer with v2 errors.
+func (h *Handler) v2(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, responder.WithErrorWriter(r, writeErrorV2))
+	})
+}
+
+func (h *Handler) handleErrorV2(w http.ResponseWriter, err error, status int) {
+	h.logger.Error("Error handling request", "error", err)
+	writeErrorV2(w, status, err)
+}
+
+func (h *Handler) calculationCostV2(r *http.Request) float64 {
+	return middleware.BodyItemsCost("items", int(h.itemsPerCostUnit.Load()))(r)
+}
+
+// decodeBody decodes a JSON body of at most maxRequestBodyBytes, rejecting unknown fields.
+func decodeBody(w http.ResponseWriter, r *http.Request, target any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBody, err)
+	}
+
+	return nil
+}
+
+func (h *Handler) handleCalculationsV2(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.handleErrorV2(w, ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var request CalculationRequest
+	if err := decodeBody(w, r, &request); err != nil {
+		h.handleErrorV2(w, err, http.StatusBadRequest)
+
+		return
+	}
+	if err := validation.ValidateItems(request.Items, h.maxItems); err != nil {
+		h.logger.Warn("Invalid incoming items", "items", request.Items, "err", err)
+		h.handleErrorV2(w, err, http.StatusBadRequest)
+
+		return
+	}
+	policy, err := packer.ParseFillPolicy(request.FillPolicy)
+	if err != nil {
+		h.logger.Warn("Invalid fill policy", "fill_policy", request.FillPolicy, "err", err)
+		h.handleErrorV2(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := h.calculateWithPolicy(r, request.Items, policy)
+	if err != nil {
+		h.handleErrorV2(w, err, h.calculationErrorStatus(w, request.Items, err))
+
+		return
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "", result)
+}
+
+func (h *Handler) handlePacketSizesV2(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var request PacketSizesV2
+		if err := decodeBody(w, r, &request); err != nil {
+			h.handleErrorV2(w, err, http.StatusBadRequest)
+
+			return
+		}
+		if err := validation.ValidatePacketSizesCount(request.Sizes, h.maxSizes); err != nil {
+			h.handleErrorV2(w, err, http.StatusBadRequest)
+
+			return
+		}
+		if err := h.packer.SetPacketSizes(r.Context(), request.Sizes, types.PacketSizeDetails{
+			Constraints: request.Constraints,
+			Definitions: request.Definitions,
+		}); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, packer.ErrNoPacketSizes) || errors.Is(err, validation.ErrNonPositiveSize) ||
+				errors.Is(err, validation.ErrSizeTooLarge) || errors.Is(err, validation.ErrDuplicatedSizes) || errors.Is(err, validation.ErrInvalidConstraint) ||
+				errors.Is(err, validation.ErrInvalidDefinition) {
+				status = http.StatusBadRequest
+			}
+			h.handleErrorV2(w, err, status)
+
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		h.handleErrorV2(w, ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	sizeSet, err := h.packetSizeSet(r.Context())
+	if err != nil {
+		h.handleErrorV2(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	responder.WriteSuccess(w, http.StatusOK, "", sizeSet)
+}
+
+// packetSizeSet reads the sizes, their constraints and their definitions together with their version, reading again if they were
+// replaced in between.
+func (h *Handler) packetSizeSet(ctx context.Context) (PacketSizesV2, error) {
+	for {
+		version, err := h.packer.PacketSizesVersion(ctx)
+		if err != nil {
+			return PacketSizesV2{}, err
+		}
+		sizes, err := h.packer.ListPacketSizes(ctx)
+		if err != nil {
+			return PacketSizesV2{}, err
+		}
+		constraints, err := h.packer.ListPacketConstraints(ctx)
+		if err != nil {
+			return PacketSizesV2{}, err
+		}
+		definitions, err := h.packer.ListPacketDefinitions(ctx)
+		if err != nil {
+			return PacketSizesV2{}, err
+		}
+		current, err := h.packer.PacketSizesVersion(ctx)
+		if err != nil {
+			return PacketSizesV2{}, err
+		}
+
+		if current == version {
+			return PacketSizesV2{Sizes: sizes, Constraints: constraints, Definitions: definitions, Version: version}, nil
+		}
+	}
+}
+
+func (h *Handler) handleNotFoundV2(w http.ResponseWriter, _ *http.Request) {
+	h.handleErrorV2(w, ErrNotFound, http.StatusNotFound)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dsha256/packer/internal/middleware"
+)
+
+var (
+	// DefaultV1DeprecatedAt is when the v1 routes were deprecated in favour of v2.
+	DefaultV1DeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	// DefaultV1Sunset is when the v1 routes are planned to be removed.
+	DefaultV1Sunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Deprecation announces that a route set is deprecated and when it goes away.
+type Deprecation struct {
+	At     time.Time
+	Sunset time.Time
+}
+
+// WithV1Deprecation changes the dates announced on the v1 routes.
+func (h *Handler) WithV1Deprecation(deprecation Deprecation) *Handler {
+	h.v1Deprecation = deprecation
+
+	return h
+}
+
+// registerV1Routes registers the original routes. They answer with the {data, err, msg} envelope and, except for the
+// order calculation and the shipment plan that have no v2 successor yet, announce their deprecation and v2 successor
+// on every response.
+func (h *Handler) registerV1Routes(mux *http.ServeMux) {
+	mux.Handle("/api/v1/packet/calculate", h.deprecated("/api/v2/calculations", h.wrapProtectedHandler(
+		h.handlePacketsCalculation,
+		h.calculationCost,
+		middleware.RequireRole(middleware.RoleReader),
+	)))
+	mux.Handle("/api/v1/packet/size", h.deprecated("/api/v2/packet-sizes", h.wrapProtectedHandler(
+		h.handlePacketSizes,
+		middleware.UnitCost,
+		middleware.AdminForWrites,
+	)))
+	mux.Handle("/api/v1/orders/calculate", h.wrapProtectedHandler(
+		h.handleOrderCalculation,
+		h.orderCost,
+		middleware.RequireRole(middleware.RoleReader),
+	))
+	mux.Handle("/api/v1/shipment/plan", h.wrapProtectedHandler(
+		h.handleShipmentPlan,
+		h.shipmentPlanCost,
+		middleware.RequireRole(middleware.RoleReader),
+	))
+	mux.Handle("/api/v1/health", h.deprecated("/readyz", h.wrapHandler(h.handleHealth)))
+	mux.Handle("/api/v1/openapi.json", h.wrapHandler(h.handleOpenAPISpec))
+	mux.Handle("/api/v1/docs", h.wrapHandler(h.handleDocs))
+}
+
+// registerV2Routes registers the v2 routes. They answer with structured results and structured errors, including
+// the errors written by the shared middleware.
+func (h *Handler) registerV2Routes(mux *http.ServeMux) {
+	mux.Handle("/api/v2/calculations", h.v2(h.wrapProtectedHandler(
+		h.handleCalculationsV2,
+		h.calculationCostV2,
+		middleware.RequireRole(middleware.RoleReader),
+	)))
+	mux.Handle("/api/v2/packet-sizes", h.v2(h.wrapProtectedHandler(
+		h.handlePacketSizesV2,
+		middleware.UnitCost,
+		middleware.AdminForWrites,
+	)))
+	mux.Handle("/api/v2/", h.v2(h.wrapHandler(h.handleNotFoundV2)))
+}
+
+// deprecated sets the Deprecation (RFC 9745) and Sunset (RFC 8594) headers and links the successor route.
+func (h *Handler) deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", h.v1Deprecation.At.Unix()))
+		w.Header().Set("Sunset", h.v1Deprecation.Sunset.UTC().Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,234 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/types"
+)
+
+func TestV1Routes_AnnounceDeprecation(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, nil)
+
+	tests := []struct {
+		method    string
+		target    string
+		successor string
+	}{
+		{method: http.MethodGet, target: "/api/v1/packet/calculate?items=1", successor: "/api/v2/calculations"},
+		{method: http.MethodGet, target: "/api/v1/packet/calculate?items=0", successor: "/api/v2/calculations"},
+		{method: http.MethodGet, target: "/api/v1/packet/size", successor: "/api/v2/packet-sizes"},
+		{method: http.MethodGet, target: "/api/v1/health", successor: "/readyz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			require.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+			require.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+			require.Equal(t, `<`+tt.successor+`>; rel="successor-version"`, rec.Header().Get("Link"))
+		})
+	}
+}
+
+func TestV1Routes_ConfiguredDeprecation(t *testing.T) {
+	t.Parallel()
+
+	mux := newContractMux(t, func(h *handler.Handler) {
+		h.WithV1Deprecation(handler.Deprecation{
+			At:     time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Sunset: time.Date(2031, time.January, 1, 0, 0, 0, 0, time.UTC),
+		})
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/packet/size", nil))
+	require.Equal(t, "@1893456000", rec.Header().Get("Deprecation"))
+	require.Equal(t, "Wed, 01 Jan 2031 00:00:00 GMT", rec.Header().Get("Sunset"))
+}
+
+func TestV2Routes(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	newContractMux(t, nil).ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":12001}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Empty(t, rec.Header().Get("Deprecation"))
+	require.Empty(t, rec.Header().Get("Sunset"))
+
+	var response struct {
+		Data types.PackingResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, 12001, response.Data.ItemsRequested)
+	require.Equal(t, 12250, response.Data.TotalShipped)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}}, response.Data.Lines)
+
+	rec = httptest.NewRecorder()
+	newContractMux(t, nil).ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[53,23,31]}`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.JSONEq(t, `{"data":{"sizes":[23,31,53],"version":2}}`, rec.Body.String())
+}
+
+func TestV2Routes_StructuredErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		configure      func(h *handler.Handler)
+		newRequest     func() *http.Request
+		name           string
+		expectedCode   string
+		expectedStatus int
+	}{
+		{
+			name: "invalid items",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":-1}`)
+			},
+			expectedCode:   "invalid_items",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "items too large",
+			configure: func(h *handler.Handler) {
+				h.WithLimits(100, handler.MaxAllowedSizes)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":101}`)
+			},
+			expectedCode:   "items_too_large",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "malformed body",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":`)
+			},
+			expectedCode:   "invalid_body",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid fill policy",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":1,"fill_policy":"closest"}`)
+			},
+			expectedCode:   "invalid_fill_policy",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "no feasible packing",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":251,"fill_policy":"exact"}`)
+			},
+			expectedCode:   "no_feasible_packing",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "query instead of body",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v2/calculations?items=1", nil)
+			},
+			expectedCode:   "method_not_allowed",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "no sizes",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[]}`)
+			},
+			expectedCode:   "no_packet_sizes",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "non-positive size",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[0]}`)
+			},
+			expectedCode:   "invalid_size",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "size too large",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[250,1000000001]}`)
+			},
+			expectedCode:   "size_too_large",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "too many sizes",
+			configure: func(h *handler.Handler) {
+				h.WithLimits(handler.MaxAllowedItems, 2)
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[1,2,3]}`)
+			},
+			expectedCode:   "too_many_sizes",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid constraint",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[250],"constraints":[{"size":250,"step":-1}]}`)
+			},
+			expectedCode:   "invalid_constraint",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid definition",
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPut, "/api/v2/packet-sizes", `{"sizes":[250],"definitions":[{"size":500,"weight":1}]}`)
+			},
+			expectedCode:   "invalid_definition",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unauthenticated",
+			configure: func(h *handler.Handler) {
+				h.WithAuthenticator(middleware.NewAuthenticator(middleware.AuthConfig{}))
+			},
+			newRequest: func() *http.Request {
+				return newJSONRequest(http.MethodPost, "/api/v2/calculations", `{"items":1}`)
+			},
+			expectedCode:   "unauthenticated",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown route",
+			newRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/v2/unknown", nil)
+			},
+			expectedCode:   "not_found",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			newContractMux(t, tt.configure).ServeHTTP(rec, tt.newRequest())
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+
+			var response types.ErrorResponseV2
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			require.Equal(t, tt.expectedCode, response.Error.Code)
+			require.Equal(t, tt.expectedStatus, response.Error.Status)
+			require.NotEmpty(t, response.Error.Message)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+var (
+	ErrCacheRoundTrip = errors.New("cache returned a different value than stored")
+	ErrNoPacketSizes  = errors.New("no packet sizes are loaded")
+	ErrSolverSelfTest = errors.New("solver returned an unexpected result for the canned input")
+)
+
+const (
+	cacheCheckKey       = "health:ping"
+	cacheCheckTTL       = time.Minute
+	solverSelfTestItems = 12001
+)
+
+// CacheChecker verifies that the cache accepts writes and returns what was written.
+func CacheChecker(c cache.Cache) Checker {
+	return NewChecker("cache", func(ctx context.Context) error {
+		value := time.Now().UnixNano()
+		if err := c.Set(ctx, cacheCheckKey, value, cacheCheckTTL); err != nil {
+			return err
+		}
+
+		got, err := c.Get(ctx, cacheCheckKey)
+		if err != nil {
+			return err
+		}
+		if got != value {
+			return ErrCacheRoundTrip
+		}
+
+		return nil
+	})
+}
+
+// PacketSizesChecker verifies that the packer has packet sizes to calculate with.
+func PacketSizesChecker(p packer.Packer) Checker {
+	return NewChecker("packet_sizes", func(ctx context.Context) error {
+		sizes, err := p.ListPacketSizes(ctx)
+		if err != nil {
+			return err
+		}
+		if len(sizes) == 0 {
+			return ErrNoPacketSizes
+		}
+
+		return nil
+	})
+}
+
+// SolverChecker runs the solver on a canned input with a known answer.
+func SolverChecker() Checker {
+	expected := map[types.PacketSize]types.PacketQuantity{5000: 2, 2000: 1, 250: 1}
+
+	return NewChecker("solver", func(_ context.Context) error {
+		result, err := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+			PacketSizes: []types.PacketSize{250, 500, 1000, 2000, 5000},
+			Items:       solverSelfTestItems,
+		})
+		if err != nil {
+			return err
+		}
+		if !maps.Equal(result, expected) {
+			return ErrSolverSelfTest
+		}
+
+		return nil
+	})
+}
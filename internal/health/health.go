@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("service is shutting down")
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultCheckTimeout = 2 * time.Second
+)
+
+// Checker checks a single dependency of the service.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	check func(ctx context.Context) error
+	name  string
+}
+
+func (checker *checkerFunc) Name() string {
+	return checker.name
+}
+
+func (checker *checkerFunc) Check(ctx context.Context) error {
+	return checker.check(ctx)
+}
+
+// NewChecker creates a Checker from a function.
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return &checkerFunc{
+		check: check,
+		name:  name,
+	}
+}
+
+// ComponentStatus is the outcome of a single check.
+type ComponentStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the outcome of all readiness checks.
+type Report struct {
+	Status     string            `json:"status"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// Ready reports whether every component is up.
+func (report *Report) Ready() bool {
+	return report.Status == StatusUp
+}
+
+// Prober runs the readiness checks. It reports not ready once shutdown has started so load balancers drain traffic.
+type Prober struct {
+	checkers     []Checker
+	checkTimeout time.Duration
+	shuttingDown atomic.Bool
+	mu           sync.RWMutex
+}
+
+// NewProber creates a Prober with the given checkers.
+func NewProber(checkers ...Checker) *Prober {
+	return &Prober{
+		checkers:     checkers,
+		checkTimeout: defaultCheckTimeout,
+	}
+}
+
+// WithCheckTimeout sets how long a single check may take before it is reported down.
+func (prober *Prober) WithCheckTimeout(timeout time.Duration) *Prober {
+	prober.checkTimeout = timeout
+
+	return prober
+}
+
+// AddCheckers registers more checkers.
+func (prober *Prober) AddCheckers(checkers ...Checker) {
+	prober.mu.Lock()
+	defer prober.mu.Unlock()
+
+	prober.checkers = append(prober.checkers, checkers...)
+}
+
+// MarkShuttingDown makes every following readiness check fail.
+func (prober *Prober) MarkShuttingDown() {
+	prober.shuttingDown.Store(true)
+}
+
+// Readiness runs all checks concurrently and reports their status and latency.
+func (prober *Prober) Readiness(ctx context.Context) Report {
+	prober.mu.RLock()
+	checkers := prober.checkers
+	prober.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusUp,
+		Components: make([]ComponentStatus, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for index, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Components[index] = prober.run(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	if prober.shuttingDown.Load() {
+		report.Components = append(report.Components, ComponentStatus{
+			Name:   "shutdown",
+			Status: StatusDown,
+			Error:  ErrShuttingDown.Error(),
+		})
+	}
+
+	for _, component := range report.Components {
+		if component.Status != StatusUp {
+			report.Status = StatusDown
+
+			break
+		}
+	}
+
+	return report
+}
+
+func (prober *Prober) run(ctx context.Context, checker Checker) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, prober.checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	status := ComponentStatus{
+		Name:      checker.Name(),
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+
+	return status
+}
@@ -0,0 +1,103 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/health"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/pkg/cache"
+)
+
+var errBroken = errors.New("broken")
+
+func TestProber_Readiness(t *testing.T) {
+	t.Parallel()
+
+	newCache := cache.NewInMemoryCache()
+	defer newCache.Close()
+
+	prober := health.NewProber(
+		health.CacheChecker(newCache),
+		health.PacketSizesChecker(packer.New()),
+		health.SolverChecker(),
+	)
+
+	report := prober.Readiness(context.Background())
+	require.True(t, report.Ready())
+	require.Len(t, report.Components, 3)
+	for _, component := range report.Components {
+		require.Equal(t, health.StatusUp, component.Status, component.Name)
+		require.Empty(t, component.Error)
+	}
+}
+
+func TestProber_FailingChecker(t *testing.T) {
+	t.Parallel()
+
+	prober := health.NewProber(
+		health.NewChecker("ok", func(_ context.Context) error { return nil }),
+		health.NewChecker("broken", func(_ context.Context) error { return errBroken }),
+	)
+
+	report := prober.Readiness(context.Background())
+	require.False(t, report.Ready())
+	require.Equal(t, health.StatusUp, report.Components[0].Status)
+	require.Equal(t, health.StatusDown, report.Components[1].Status)
+	require.Equal(t, errBroken.Error(), report.Components[1].Error)
+}
+
+func TestProber_CheckTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	prober := health.NewProber(health.NewChecker("slow", func(_ context.Context) error {
+		<-release
+
+		return nil
+	})).WithCheckTimeout(20 * time.Millisecond)
+
+	report := prober.Readiness(context.Background())
+	require.False(t, report.Ready())
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Components[0].Error)
+}
+
+func TestProber_MarkShuttingDown(t *testing.T) {
+	t.Parallel()
+
+	prober := health.NewProber()
+	report := prober.Readiness(context.Background())
+	require.True(t, report.Ready())
+
+	prober.MarkShuttingDown()
+
+	report = prober.Readiness(context.Background())
+	require.False(t, report.Ready())
+	require.Equal(t, health.ErrShuttingDown.Error(), report.Components[0].Error)
+}
+
+// noSizesPacker lists no packet sizes, which the packer itself no longer accepts.
+type noSizesPacker struct {
+	packer.Packer
+}
+
+func (noSizesPacker) ListPacketSizes(context.Context) ([]types.PacketSize, error) {
+	return nil, nil
+}
+
+func TestPacketSizesChecker_NoSizes(t *testing.T) {
+	t.Parallel()
+
+	newPacker := packer.New()
+	require.ErrorIs(t, newPacker.SetPacketSizes(context.Background(), nil, types.PacketSizeDetails{}), packer.ErrNoPacketSizes)
+
+	err := health.PacketSizesChecker(noSizesPacker{Packer: newPacker}).Check(context.Background())
+	require.ErrorIs(t, err, health.ErrNoPacketSizes)
+}
@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/dsha256/packer/internal/responder"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("insufficient role for this operation")
+	ErrInvalidToken    = errors.New("invalid bearer token")
+	ErrTokenExpired    = errors.New("bearer token has expired")
+)
+
+// Role is the access level granted to an authenticated client.
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleAdmin  Role = "admin"
+)
+
+// Allows reports whether the role grants at least the required access. Admins may do everything readers can.
+func (role Role) Allows(required Role) bool {
+	switch required {
+	case RoleReader:
+		return role == RoleReader || role == RoleAdmin
+	case RoleAdmin:
+		return role == RoleAdmin
+	default:
+		return false
+	}
+}
+
+// RoleFunc returns the role required to serve a request.
+type RoleFunc func(r *http.Request) Role
+
+// RequireRole requires the same role for every request.
+func RequireRole(role Role) RoleFunc {
+	return func(_ *http.Request) Role {
+		return role
+	}
+}
+
+// AdminForWrites lets readers use safe methods and requires the admin role for everything else.
+func AdminForWrites(r *http.Request) Role {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleReader
+	default:
+		return RoleAdmin
+	}
+}
+
+// Principal is the identity an authenticated request acts as.
+type Principal struct {
+	Subject string
+	Role    Role
+}
+
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the principal stored by AuthMiddleware.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+
+	return principal, ok
+}
+
+// AuthConfig holds the configuration for the authenticator.
+type AuthConfig struct {
+	// APIKeys maps the SHA-256 hex digest of a static API key to the principal it authenticates.
+	APIKeys map[string]Principal
+	// JWTSecret is the HMAC key used to verify HS256 bearer tokens. Bearer tokens are rejected when empty.
+	JWTSecret   []byte
+	JWTIssuer   string
+	JWTAudience string
+	JWTLeeway   time.Duration
+}
+
+// Authenticator resolves static API keys and HMAC-signed JWT bearer tokens to principals.
+type Authenticator struct {
+	config AuthConfig
+}
+
+// NewAuthenticator creates a new Authenticator.
+func NewAuthenticator(config AuthConfig) *Authenticator {
+	return &Authenticator{
+		config: config,
+	}
+}
+
+// Authenticate returns the principal of the request or ErrUnauthenticated.
+func (authenticator *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return authenticator.AuthenticateCredentials(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials resolves an API key or, when it is empty, an "Authorization: Bearer" value.
+// It allows transports other than HTTP to share the same credentials.
+func (authenticator *Authenticator) AuthenticateCredentials(apiKey, authorization string) (Principal, error) {
+	if apiKey != "" {
+		return authenticator.authenticateAPIKey(apiKey)
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return authenticator.authenticateToken(strings.TrimSpace(token), time.Now())
+	}
+
+	return Principal{}, ErrUnauthenticated
+}
+
+func (authenticator *Authenticator) authenticateAPIKey(apiKey string) (Principal, error) {
+	digest := sha256.Sum256([]byte(apiKey))
+	hexDigest := hex.EncodeToString(digest[:])
+	for storedDigest, principal := range authenticator.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(storedDigest), []byte(hexDigest)) == 1 {
+			return principal, nil
+		}
+	}
+
+	return Principal{}, ErrUnauthenticated
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Role      Role     `json:"role"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// audience accepts both the string and the array form of the "aud" claim.
+type audience []string
+
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*aud = many
+
+	return nil
+}
+
+func (authenticator *Authenticator) authenticateToken(token string, now time.Time) (Principal, error) {
+	if len(authenticator.config.JWTSecret) == 0 {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Principal{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, authenticator.config.JWTSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	return authenticator.validateClaims(&claims, now)
+}
+
+func (authenticator *Authenticator) validateClaims(claims *jwtClaims, now time.Time) (Principal, error) {
+	leeway := authenticator.config.JWTLeeway
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return Principal{}, ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return Principal{}, ErrInvalidToken
+	}
+	if authenticator.config.JWTIssuer != "" && claims.Issuer != authenticator.config.JWTIssuer {
+		return Principal{}, ErrInvalidToken
+	}
+	if authenticator.config.JWTAudience != "" && !slices.Contains(claims.Audience, authenticator.config.JWTAudience) {
+		return Principal{}, ErrInvalidToken
+	}
+	if claims.Role != RoleReader && claims.Role != RoleAdmin {
+		return Principal{}, ErrInvalidToken
+	}
+
+	return Principal{
+		Subject: claims.Subject,
+		Role:    claims.Role,
+	}, nil
+}
+
+func decodeSegment(segment string, target any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, target)
+}
+
+// AuthMiddleware rejects unauthenticated requests with 401 and requests lacking the required role with 403.
+// The authenticated principal is available to the next handler through PrincipalFromContext.
+func AuthMiddleware(authenticator *Authenticator, requiredRole RoleFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="packer"`)
+			responder.WriteRequestError(w, r, http.StatusUnauthorized, err)
+
+			return
+		}
+
+		if !principal.Role.Allows(requiredRole(r)) {
+			responder.WriteRequestError(w, r, http.StatusForbidden, ErrForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal)))
+	})
+}
@@ -0,0 +1,165 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/middleware"
+)
+
+var testJWTSecret = []byte("test-secret") //nolint:gochecknoglobals // Shared test fixture.
+
+func signToken(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newAuthServer(t *testing.T) http.Handler {
+	t.Helper()
+
+	readerDigest := sha256.Sum256([]byte("reader-key"))
+	adminDigest := sha256.Sum256([]byte("admin-key"))
+	authenticator := middleware.NewAuthenticator(middleware.AuthConfig{
+		APIKeys: map[string]middleware.Principal{
+			hex.EncodeToString(readerDigest[:]): {Subject: "reader", Role: middleware.RoleReader},
+			hex.EncodeToString(adminDigest[:]):  {Subject: "admin", Role: middleware.RoleAdmin},
+		},
+		JWTSecret: testJWTSecret,
+		JWTIssuer: "packer-tests",
+	})
+
+	return middleware.AuthMiddleware(authenticator, middleware.AdminForWrites, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := middleware.PrincipalFromContext(r.Context())
+		require.True(t, ok)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(principal.Subject))
+	}))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tests := []struct {
+		headers      map[string]string
+		name         string
+		method       string
+		expectedBody string
+		expectedCode int
+	}{
+		{
+			name:         "no credentials",
+			method:       http.MethodGet,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown api key",
+			method:       http.MethodGet,
+			headers:      map[string]string{middleware.APIKeyHeader: "nope"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "reader api key reads",
+			method:       http.MethodGet,
+			headers:      map[string]string{middleware.APIKeyHeader: "reader-key"},
+			expectedCode: http.StatusOK,
+			expectedBody: "reader",
+		},
+		{
+			name:         "reader api key cannot write",
+			method:       http.MethodPut,
+			headers:      map[string]string{middleware.APIKeyHeader: "reader-key"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "admin api key writes",
+			method:       http.MethodPut,
+			headers:      map[string]string{middleware.APIKeyHeader: "admin-key"},
+			expectedCode: http.StatusOK,
+			expectedBody: "admin",
+		},
+		{
+			name:   "admin bearer token writes",
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, testJWTSecret, map[string]any{
+				"sub": "svc", "iss": "packer-tests", "role": "admin", "exp": now.Add(time.Minute).Unix(),
+			})},
+			expectedCode: http.StatusOK,
+			expectedBody: "svc",
+		},
+		{
+			name:   "reader bearer token cannot write",
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, testJWTSecret, map[string]any{
+				"sub": "svc", "iss": "packer-tests", "role": "reader", "exp": now.Add(time.Minute).Unix(),
+			})},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "expired bearer token",
+			method: http.MethodGet,
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, testJWTSecret, map[string]any{
+				"sub": "svc", "iss": "packer-tests", "role": "admin", "exp": now.Add(-time.Minute).Unix(),
+			})},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong signing key",
+			method: http.MethodGet,
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, []byte("other"), map[string]any{
+				"sub": "svc", "iss": "packer-tests", "role": "admin", "exp": now.Add(time.Minute).Unix(),
+			})},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong issuer",
+			method: http.MethodGet,
+			headers: map[string]string{"Authorization": "Bearer " + signToken(t, testJWTSecret, map[string]any{
+				"sub": "svc", "iss": "someone-else", "role": "admin", "exp": now.Add(time.Minute).Unix(),
+			})},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	handler := newAuthServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, "/api/v1/packet/size", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+			if tt.expectedCode == http.StatusUnauthorized {
+				require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+				require.Contains(t, rec.Body.String(), `"err"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/dsha256/packer/internal/responder"
+)
+
+var (
+	ErrRateLimited      = errors.New("rate limit exceeded, retry later")
+	ErrCostExceedsBurst = errors.New("request costs more than the rate limit burst, it can never be served")
+)
+
+const (
+	// APIKeyHeader is the request header carrying the client's API key.
+	APIKeyHeader = "X-API-Key"
+
+	defaultIdleTimeout = 10 * time.Minute
+
+	// maxCostBodyBytes bounds the request body read by BodyCost.
+	maxCostBodyBytes = 1 << 20
+)
+
+// CostFunc returns the number of tokens a request consumes.
+type CostFunc func(r *http.Request) float64
+
+// UnitCost charges every request a single token.
+func UnitCost(_ *http.Request) float64 {
+	return 1
+}
+
+// QueryItemsCost charges one token plus one more token per itemsPerUnit of the integer query parameter,
+// so that requests asking for more work drain the budget faster.
+func QueryItemsCost(param string, itemsPerUnit int) CostFunc {
+	return func(r *http.Request) float64 {
+		if itemsPerUnit < 1 {
+			return 1
+		}
+
+		items, err := strconv.Atoi(r.URL.Query().Get(param))
+		if err != nil || items < 1 {
+			return 1
+		}
+
+		return 1 + float64(items/itemsPerUnit)
+	}
+}
+
+// BodyItemsCost is QueryItemsCost for an integer field of a JSON request body.
+func BodyItemsCost(field string, itemsPerUnit int) CostFunc {
+	return BodyCost(itemsPerUnit, func(body []byte) (int, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return 0, err
+		}
+
+		return strconv.Atoi(string(fields[field]))
+	})
+}
+
+// BodyCost charges one token plus one more token per itemsPerUnit of the items that countItems reads from the
+// request body. The body is restored for the next handler; bodies over 1 MiB and bodies countItems rejects cost a
+// single token and are left for the handler to reject.
+func BodyCost(itemsPerUnit int, countItems func(body []byte) (int, error)) CostFunc {
+	return func(r *http.Request) float64 {
+		if itemsPerUnit < 1 || r.Body == nil {
+			return 1
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCostBodyBytes+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil || len(body) > maxCostBodyBytes {
+			return 1
+		}
+
+		items, err := countItems(body)
+		if err != nil || items < 1 {
+			return 1
+		}
+
+		return 1 + float64(items/itemsPerUnit)
+	}
+}
+
+// ClientLimit is a token-bucket budget: tokens refill at RequestsPerSecond up to Burst.
+type ClientLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RateLimitConfig holds the configuration for the rate limiter.
+type RateLimitConfig struct {
+	// Clients maps the SHA-256 hex digest of an API key to its dedicated budget.
+	Clients           map[string]ClientLimit
+	Default           ClientLimit
+	IdleTimeout       time.Duration
+	TrustForwardedFor bool
+}
+
+type tokenBucket struct {
+	lastRefill time.Time
+	limit      ClientLimit
+	tokens     float64
+	mu         sync.Mutex
+}
+
+// take consumes cost tokens if available, otherwise it returns ErrRateLimited and how long the caller should wait, or
+// ErrCostExceedsBurst when waiting never helps. limit is the client's current budget, which changes when the
+// configuration is updated.
+func (bucket *tokenBucket) take(now time.Time, cost float64, limit ClientLimit) (time.Duration, error) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.limit = limit
+
+	burst := float64(bucket.limit.Burst)
+	elapsed := now.Sub(bucket.lastRefill).Seconds()
+	bucket.tokens = math.Min(burst, bucket.tokens+elapsed*bucket.limit.RequestsPerSecond)
+	bucket.lastRefill = now
+
+	if cost > burst {
+		return 0, ErrCostExceedsBurst
+	}
+	if bucket.tokens >= cost {
+		bucket.tokens -= cost
+
+		return 0, nil
+	}
+
+	if bucket.limit.RequestsPerSecond <= 0 {
+		return time.Duration(math.MaxInt64), ErrRateLimited
+	}
+
+	wait := (cost - bucket.tokens) / bucket.limit.RequestsPerSecond
+
+	return time.Duration(wait * float64(time.Second)), ErrRateLimited
+}
+
+func (bucket *tokenBucket) idleSince(now time.Time) time.Duration {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	return now.Sub(bucket.lastRefill)
+}
+
+// RateLimiter keeps a token bucket per client, keyed by API key or client IP.
+type RateLimiter struct {
+	buckets     *sync.Map
+	stopCleanup chan struct{}
+	config      atomic.Pointer[RateLimitConfig]
+	cleanupWg   sync.WaitGroup
+	idleTimeout time.Duration
+}
+
+// NewRateLimiter creates a RateLimiter and starts evicting buckets of idle clients.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultIdleTimeout
+	}
+
+	limiter := &RateLimiter{
+		buckets:     &sync.Map{},
+		stopCleanup: make(chan struct{}),
+		idleTimeout: config.IdleTimeout,
+	}
+	limiter.config.Store(&config)
+
+	limiter.cleanupWg.Add(1)
+	go limiter.cleanupIdleBuckets()
+
+	return limiter
+}
+
+// Allow consumes cost tokens from the bucket of the client that sent r.
+// When the budget is exhausted it returns ErrRateLimited and the time after which the request may succeed, and when
+// the cost exceeds the client's burst ErrCostExceedsBurst.
+func (limiter *RateLimiter) Allow(r *http.Request, cost float64) (time.Duration, error) {
+	key, limit := limiter.identify(r)
+	now := time.Now()
+
+	value, _ := limiter.buckets.LoadOrStore(key, &tokenBucket{
+		lastRefill: now,
+		limit:      limit,
+		tokens:     float64(limit.Burst),
+	})
+
+	bucket, ok := value.(*tokenBucket)
+	if !ok {
+		return 0, nil
+	}
+
+	return bucket.take(now, cost, limit)
+}
+
+// UpdateConfig replaces the client budgets without resetting the buckets of known clients.
+// The idle timeout is fixed when the limiter is created.
+func (limiter *RateLimiter) UpdateConfig(config RateLimitConfig) {
+	config.IdleTimeout = limiter.idleTimeout
+	limiter.config.Store(&config)
+}
+
+// Close stops the idle bucket cleanup.
+func (limiter *RateLimiter) Close() {
+	close(limiter.stopCleanup)
+	limiter.cleanupWg.Wait()
+}
+
+func (limiter *RateLimiter) identify(r *http.Request) (string, ClientLimit) {
+	config := limiter.config.Load()
+	if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+		digest := sha256.Sum256([]byte(apiKey))
+		hexDigest := hex.EncodeToString(digest[:])
+		if limit, ok := config.Clients[hexDigest]; ok {
+			return "key:" + hexDigest, limit
+		}
+	}
+
+	return "ip:" + clientIP(r, config.TrustForwardedFor), config.Default
+}
+
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+
+			return strings.TrimSpace(first)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (limiter *RateLimiter) cleanupIdleBuckets() {
+	defer limiter.cleanupWg.Done()
+
+	ticker := time.NewTicker(limiter.idleTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			limiter.buckets.Range(func(key, value any) bool {
+				bucket, ok := value.(*tokenBucket)
+				if !ok || bucket.idleSince(now) > limiter.idleTimeout {
+					limiter.buckets.Delete(key)
+				}
+
+				return true
+			})
+		case <-limiter.stopCleanup:
+			return
+		}
+	}
+}
+
+// RateLimitMiddleware rejects requests with 429 Too Many Requests once the client's budget is exhausted, and with
+// 413 Content Too Large when they cost more than the whole budget.
+func RateLimitMiddleware(limiter *RateLimiter, cost CostFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		retryAfter, err := limiter.Allow(r, cost(r))
+		if errors.Is(err, ErrCostExceedsBurst) {
+			responder.WriteRequestError(w, r, http.StatusRequestEntityTooLarge, err)
+
+			return
+		}
+		if err != nil {
+			seconds := int64(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+			responder.WriteRequestError(w, r, http.StatusTooManyRequests, err)
+
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/middleware"
+)
+
+func newRateLimitedServer(t *testing.T, config middleware.RateLimitConfig, cost middleware.CostFunc) http.Handler {
+	t.Helper()
+
+	limiter := middleware.NewRateLimiter(config)
+	t.Cleanup(limiter.Close)
+
+	return middleware.RateLimitMiddleware(limiter, cost, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func doRequest(handler http.Handler, target, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRateLimitMiddleware_ExhaustsBurstPerIP(t *testing.T) {
+	t.Parallel()
+
+	handler := newRateLimitedServer(t, middleware.RateLimitConfig{
+		Default: middleware.ClientLimit{RequestsPerSecond: 0.5, Burst: 2},
+	}, middleware.UnitCost)
+
+	for range 2 {
+		rec := doRequest(handler, "/", "10.0.0.1:1234", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	rec := doRequest(handler, "/", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), middleware.ErrRateLimited.Error())
+
+	rec = doRequest(handler, "/", "10.0.0.2:1234", "")
+	require.Equal(t, http.StatusOK, rec.Code, "other clients keep their own budget")
+}
+
+func TestRateLimitMiddleware_WeightsByItems(t *testing.T) {
+	t.Parallel()
+
+	handler := newRateLimitedServer(t, middleware.RateLimitConfig{
+		Default: middleware.ClientLimit{RequestsPerSecond: 0.1, Burst: 10},
+	}, middleware.QueryItemsCost("items", 1000))
+
+	rec := doRequest(handler, "/?items=5000", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(handler, "/?items=5000", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	rec = doRequest(handler, "/?items=10", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(handler, "/?items=10000", "10.0.0.2:1234", "")
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "a cost above the burst can never be served")
+	require.Empty(t, rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), middleware.ErrCostExceedsBurst.Error())
+
+	rec = doRequest(handler, "/?items=9000", "10.0.0.2:1234", "")
+	require.Equal(t, http.StatusOK, rec.Code, "a cost of the whole burst is served, and rejected requests cost nothing")
+}
+
+func TestBodyItemsCost(t *testing.T) {
+	t.Parallel()
+
+	cost := middleware.BodyItemsCost("items", 1000)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCost float64
+	}{
+		{name: "items", body: `{"items": 5000}`, expectedCost: 6},
+		{name: "small items", body: `{"items": 10}`, expectedCost: 1},
+		{name: "missing field", body: `{"count": 5000}`, expectedCost: 1},
+		{name: "malformed body", body: `{"items": `, expectedCost: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			require.InDelta(t, tt.expectedCost, cost(req), 0)
+
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.body, string(body), "the body is restored for the handler")
+		})
+	}
+}
+
+func TestRateLimitMiddleware_APIKeyClients(t *testing.T) {
+	t.Parallel()
+
+	digest := sha256.Sum256([]byte("secret-key"))
+	handler := newRateLimitedServer(t, middleware.RateLimitConfig{
+		Clients: map[string]middleware.ClientLimit{
+			hex.EncodeToString(digest[:]): {RequestsPerSecond: 1, Burst: 3},
+		},
+		Default: middleware.ClientLimit{RequestsPerSecond: 0.1, Burst: 1},
+	}, middleware.UnitCost)
+
+	for range 3 {
+		rec := doRequest(handler, "/", "10.0.0.1:1234", "secret-key")
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	rec := doRequest(handler, "/", "10.0.0.1:1234", "secret-key")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	rec = doRequest(handler, "/", "10.0.0.1:1234", "unknown-key")
+	require.Equal(t, http.StatusOK, rec.Code, "unknown keys fall back to the IP budget")
+	rec = doRequest(handler, "/", "10.0.0.1:1234", "another-unknown-key")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestRateLimiter_UpdateConfig(t *testing.T) {
+	t.Parallel()
+
+	limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+		Default: middleware.ClientLimit{RequestsPerSecond: 0.01, Burst: 3},
+	})
+	t.Cleanup(limiter.Close)
+	handler := middleware.RateLimitMiddleware(limiter, middleware.UnitCost, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	require.Equal(t, http.StatusOK, doRequest(handler, "/", "10.0.0.1:1234", "").Code)
+
+	limiter.UpdateConfig(middleware.RateLimitConfig{
+		Default: middleware.ClientLimit{RequestsPerSecond: 0.01, Burst: 1},
+	})
+
+	rec := doRequest(handler, "/", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusOK, rec.Code, "the remaining tokens are capped at the new burst")
+	rec = doRequest(handler, "/", "10.0.0.1:1234", "")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
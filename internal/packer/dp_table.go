@@ -0,0 +1,221 @@
+package packer
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dsha256/packer/internal/types"
+)
+
+// unreachable marks totals that no combination of sizes ships.
+const unreachable = math.MaxUint32
+
+// dpState is the dynamic programming table of CalculateOptimalPacketsForItemsV1 in a form that reads a packing back in
+// O(number of sizes).
+//
+// V1 records, for every total, the smallest size whose predecessor total needs the fewest packets. Following these
+// records from a total visits the sizes in ascending order, so a packing is a run of each size at most. The table
+// stores the length of that run per total and jumps over whole runs when it reads a packing back.
+//
+// Entries are never changed once computed, so a dpState can be extended into a larger one while other goroutines keep
+// reading the totals it already had.
+type dpState struct {
+	sizes []types.PacketSize
+	// packs is, per total, the fewest packets that ship it, or unreachable.
+	packs []uint32
+	// run is, per total, the number of packets of the size in sizeIndex that the packing of the total starts with.
+	run []uint32
+	// sizeIndex is, per total, the index in sizes of the smallest size the packing of the total uses.
+	sizeIndex []uint16
+}
+
+// newDPState returns the table of total 0 for sizes, which must be normalized.
+func newDPState(sizes []types.PacketSize) (*dpState, error) {
+	if len(sizes) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: %d", ErrTableTooManySizes, len(sizes))
+	}
+
+	return &dpState{sizes: sizes, packs: []uint32{0}, run: []uint32{0}, sizeIndex: []uint16{0}}, nil
+}
+
+// extended returns a table covering the totals up to maxSum. The entries already computed are shared, and appending
+// only writes beyond them, so the receiver stays valid for its readers.
+func (state *dpState) extended(maxSum int) (*dpState, error) {
+	if maxSum >= unreachable {
+		return nil, fmt.Errorf("%w: %d", ErrTableTooLarge, maxSum)
+	}
+
+	from := len(state.packs)
+	if maxSum < from {
+		return state, nil
+	}
+
+	grow := maxSum + 1 - from
+	next := &dpState{
+		sizes:     state.sizes,
+		packs:     append(state.packs, make([]uint32, grow)...),
+		run:       append(state.run, make([]uint32, grow)...),
+		sizeIndex: append(state.sizeIndex, make([]uint16, grow)...),
+	}
+
+	for s := from; s <= maxSum; s++ {
+		next.packs[s] = unreachable
+		for index, size := range next.sizes {
+			previous := s - int(size)
+			if previous < 0 || next.packs[previous] == unreachable || next.packs[previous]+1 >= next.packs[s] {
+				continue
+			}
+
+			next.packs[s] = next.packs[previous] + 1
+			next.sizeIndex[s] = uint16(index) //nolint:gosec // newDPState checks the number of sizes.
+			next.run[s] = 1
+			if previous > 0 && next.sizeIndex[previous] == next.sizeIndex[s] {
+				next.run[s] = next.run[previous] + 1
+			}
+		}
+	}
+
+	return next, nil
+}
+
+// maxSize returns the largest packet size.
+func (state *dpState) maxSize() int {
+	return int(state.sizes[len(state.sizes)-1])
+}
+
+// bestTotal returns the smallest reachable total of at least items. The table must cover items + the largest size.
+func (state *dpState) bestTotal(items int) (int, error) {
+	// A multiple of the largest size always lies in [items, items + largest size), so a total is found.
+	for s := items; s <= items+state.maxSize(); s++ {
+		if state.packs[s] != unreachable {
+			return s, nil
+		}
+	}
+
+	return 0, ErrNoPacking
+}
+
+// memoryBytes returns the memory held by the table, including the capacity reserved for growing.
+func (state *dpState) memoryBytes() int64 {
+	const (
+		packsBytes     = 4
+		runBytes       = 4
+		sizeIndexBytes = 2
+		sizeBytes      = 8
+	)
+
+	return int64(cap(state.packs))*packsBytes + int64(cap(state.run))*runBytes +
+		int64(cap(state.sizeIndex))*sizeIndexBytes + int64(len(state.sizes))*sizeBytes
+}
+
+// packing reads back the packing of a reachable total.
+func (state *dpState) packing(total int) Result {
+	result := make(Result)
+	for total > 0 {
+		size := state.sizes[state.sizeIndex[total]]
+		quantity := state.run[total]
+		result[size] = types.PacketQuantity(quantity)
+		total -= int(size) * int(quantity)
+	}
+
+	return result
+}
+
+// DPTable keeps the dynamic programming of CalculateOptimalPacketsForItemsV1 for one set of packet sizes between
+// calculations. A calculation that needs totals beyond the table extends it, at least doubling it, so that a stream
+// of growing calculations costs amortized O(number of sizes) per item instead of a full solve each. Calculations
+// within the table only read it back, concurrently and without locking.
+type DPTable struct {
+	state    atomic.Pointer[dpState]
+	growLock sync.Mutex
+	maxItems int
+}
+
+// NewDPTable returns an empty table for sizes that answers calculations of up to maxItems items, or ErrTableTooLarge
+// when it could grow beyond MaxTableBytes. Sizes may be unsorted and contain duplicates.
+func NewDPTable(sizes []types.PacketSize, maxItems int) (*DPTable, error) {
+	packetSizes, err := normalizeParams(&CalculateOptimalPacketsForItemsParams{Items: maxItems, PacketSizes: sizes})
+	if err != nil {
+		return nil, err
+	}
+	maxSum := maxItems + int(packetSizes[len(packetSizes)-1])
+	if maxSum >= unreachable {
+		return nil, fmt.Errorf("%w: %d", ErrTableTooLarge, maxSum)
+	}
+	if err = checkTableBytes(maxSum, 0); err != nil {
+		return nil, err
+	}
+
+	state, err := newDPState(packetSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &DPTable{maxItems: maxItems}
+	table.state.Store(state)
+
+	return table, nil
+}
+
+// Solve returns the packing of items, extending the table when needed, or false when items is negative or above
+// the table bound.
+func (table *DPTable) Solve(items int) (Result, bool, error) {
+	if items < 0 || items > table.maxItems {
+		return nil, false, nil
+	}
+
+	state := table.state.Load()
+	maxSum := items + state.maxSize()
+	if maxSum >= len(state.packs) {
+		var err error
+		if state, err = table.grow(maxSum); err != nil {
+			return nil, false, err
+		}
+	}
+
+	total, err := state.bestTotal(items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return state.packing(total), true, nil
+}
+
+// grow extends the table to cover maxSum. Concurrent calculations that need to grow wait for each other, the ones
+// within the table do not.
+func (table *DPTable) grow(maxSum int) (*dpState, error) {
+	table.growLock.Lock()
+	defer table.growLock.Unlock()
+
+	state := table.state.Load()
+	if maxSum < len(state.packs) {
+		return state, nil
+	}
+
+	// Doubling keeps the cost of a stream of growing calculations amortized; the bound caps it.
+	limit := table.maxItems + state.maxSize()
+	state, err := state.extended(min(max(maxSum, 2*len(state.packs)), limit))
+	if err != nil {
+		return nil, err
+	}
+	table.state.Store(state)
+
+	return state, nil
+}
+
+// MaxItems returns the largest item count the table answers.
+func (table *DPTable) MaxItems() int {
+	return table.maxItems
+}
+
+// Totals returns the number of totals the table holds so far.
+func (table *DPTable) Totals() int {
+	return len(table.state.Load().packs)
+}
+
+// MemoryBytes returns the memory held by the table.
+func (table *DPTable) MemoryBytes() int64 {
+	return table.state.Load().memoryBytes()
+}
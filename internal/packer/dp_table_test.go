@@ -0,0 +1,154 @@
+package packer_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+)
+
+func TestDPTable_MatchesV1(t *testing.T) {
+	t.Parallel()
+
+	sizes := []types.PacketSize{23, 31, 53}
+	table, err := packer.NewDPTable(sizes, 50_000)
+	require.NoError(t, err)
+	require.Equal(t, 1, table.Totals(), "the table starts empty")
+
+	random := rand.New(rand.NewPCG(44, 2024)) //nolint:gosec // Reproducible test inputs.
+	for range 300 {
+		items := random.IntN(50_001)
+		expected, err := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+			Items:       items,
+			PacketSizes: sizes,
+		})
+		require.NoError(t, err)
+
+		actual, ok, err := table.Solve(items)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, expected, actual, "items %d", items)
+	}
+
+	require.LessOrEqual(t, table.Totals(), 50_000+53+1, "the table does not grow beyond its bound")
+
+	_, ok, err := table.Solve(50_001)
+	require.NoError(t, err)
+	require.False(t, ok, "items above the bound")
+}
+
+func TestDPTable_Grows(t *testing.T) {
+	t.Parallel()
+
+	table, err := packer.NewDPTable([]types.PacketSize{250, 500, 1000, 2000, 5000}, 1_000_000)
+	require.NoError(t, err)
+
+	_, ok, err := table.Solve(10_000)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 15_001, table.Totals(), "the first calculation covers items + the largest size")
+	memory := table.MemoryBytes()
+	require.Positive(t, memory)
+
+	_, _, err = table.Solve(5_000)
+	require.NoError(t, err)
+	require.Equal(t, 15_001, table.Totals(), "smaller calculations read the table")
+
+	_, _, err = table.Solve(10_001)
+	require.NoError(t, err)
+	require.Equal(t, 30_003, table.Totals(), "the table at least doubles")
+	require.Greater(t, table.MemoryBytes(), memory)
+
+	_, _, err = table.Solve(1_000_000)
+	require.NoError(t, err)
+	require.Equal(t, 1_005_001, table.Totals())
+}
+
+func TestDPTable_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	sizes := []types.PacketSize{250, 500, 1000, 2000, 5000}
+	table, err := packer.NewDPTable(sizes, 200_000)
+	require.NoError(t, err)
+
+	type solved struct {
+		err    error
+		result packer.Result
+		items  int
+	}
+	results := make(chan solved, 8*20)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Go(func() {
+			for step := range 20 {
+				items := 1 + (step*8+worker)*1_237
+				result, _, err := table.Solve(items)
+				results <- solved{err: err, result: result, items: items}
+			}
+		})
+	}
+	wg.Wait()
+	close(results)
+
+	for solved := range results {
+		require.NoError(t, solved.err)
+		expected, err := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+			Items:       solved.items,
+			PacketSizes: sizes,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, solved.result, "items %d", solved.items)
+	}
+}
+
+func TestPacker_DPTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{DPTableMaxItems: 100_000})
+	require.NoError(t, err)
+	reporter, ok := newPacker.(packer.DPTableReporter)
+	require.True(t, ok)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 12001)
+	require.NoError(t, err)
+	require.Equal(t, "v1", result.Strategy)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}}, result.Lines)
+
+	stats := reporter.DPTableStats()
+	require.Equal(t, 100_000, stats.MaxItems)
+	require.Equal(t, 17_002, stats.Totals)
+	require.Positive(t, stats.MemoryBytes)
+
+	result, err = newPacker.GetOptimalPackets(ctx, 500_000)
+	require.NoError(t, err)
+	require.Equal(t, 500_000, result.TotalShipped, "items above the bound are solved")
+	require.Equal(t, 17_002, reporter.DPTableStats().Totals)
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, []types.PacketSize{23, 31, 53}, types.PacketSizeDetails{}))
+	require.Equal(t, 1, reporter.DPTableStats().Totals, "the table of the previous sizes is discarded")
+
+	result, err = newPacker.GetOptimalPackets(ctx, 500)
+	require.NoError(t, err)
+	require.Equal(t, []types.PackingLine{{Size: 53, Quantity: 9}, {Size: 23, Quantity: 1}}, result.Lines)
+}
+
+func TestPacker_DPTableOnlyForV1(t *testing.T) {
+	t.Parallel()
+
+	newPacker, err := packer.NewWithConfig(packer.Config{Strategy: packer.StrategyV2, DPTableMaxItems: 100_000})
+	require.NoError(t, err)
+
+	_, err = newPacker.GetOptimalPackets(context.Background(), 12001)
+	require.NoError(t, err)
+
+	reporter, ok := newPacker.(packer.DPTableReporter)
+	require.True(t, ok)
+	require.Equal(t, packer.DPTableStats{}, reporter.DPTableStats())
+}
@@ -0,0 +1,101 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidFillPolicy = errors.New("invalid fill policy, use exact, min-overshoot, max-overshoot=N or max-overshoot=N%")
+	ErrNoFeasiblePacking = errors.New("no feasible packing for the fill policy")
+)
+
+// FillMode is how much a packing may ship beyond the requested items.
+type FillMode string
+
+const (
+	// FillMinOvershoot accepts the packing with the least overshoot, whatever it is. It is the default.
+	FillMinOvershoot FillMode = "min-overshoot"
+	// FillExact only accepts packings that ship exactly the requested items.
+	FillExact FillMode = "exact"
+	// FillMaxOvershoot accepts the packing with the least overshoot when it is within a bound.
+	FillMaxOvershoot FillMode = "max-overshoot"
+)
+
+// FillPolicy bounds the overshoot of a packing. The zero value is FillMinOvershoot.
+//
+// The solvers always find the packing with the least overshoot, so a policy never changes the packing: it either
+// accepts it or reports ErrNoFeasiblePacking.
+type FillPolicy struct {
+	Mode FillMode
+	// MaxOvershoot is the bound of FillMaxOvershoot, in items or, with Percent, in percent of the requested items.
+	MaxOvershoot int
+	Percent      bool
+}
+
+// ParseFillPolicy parses exact, min-overshoot, max-overshoot=N or max-overshoot=N%. An empty text is
+// FillMinOvershoot.
+func ParseFillPolicy(text string) (FillPolicy, error) {
+	switch mode, bound, hasBound := strings.Cut(text, "="); {
+	case text == "" || text == string(FillMinOvershoot):
+		return FillPolicy{Mode: FillMinOvershoot}, nil
+	case text == string(FillExact):
+		return FillPolicy{Mode: FillExact}, nil
+	case mode == string(FillMaxOvershoot) && hasBound:
+		number, percent := strings.CutSuffix(bound, "%")
+		maxOvershoot, err := strconv.Atoi(number)
+		if err != nil || maxOvershoot < 0 {
+			return FillPolicy{}, fmt.Errorf("%w, got %q", ErrInvalidFillPolicy, text)
+		}
+
+		return FillPolicy{Mode: FillMaxOvershoot, MaxOvershoot: maxOvershoot, Percent: percent}, nil
+	default:
+		return FillPolicy{}, fmt.Errorf("%w, got %q", ErrInvalidFillPolicy, text)
+	}
+}
+
+// String returns the policy in the form ParseFillPolicy reads.
+func (policy FillPolicy) String() string {
+	switch {
+	case policy.Mode == FillMaxOvershoot && policy.Percent:
+		return fmt.Sprintf("%s=%d%%", FillMaxOvershoot, policy.MaxOvershoot)
+	case policy.Mode == FillMaxOvershoot:
+		return fmt.Sprintf("%s=%d", FillMaxOvershoot, policy.MaxOvershoot)
+	case policy.Mode == "":
+		return string(FillMinOvershoot)
+	default:
+		return string(policy.Mode)
+	}
+}
+
+// AllowedOvershoot returns the largest overshoot the policy accepts for items, or false when it accepts any.
+// Percentages are rounded down.
+func (policy FillPolicy) AllowedOvershoot(items int) (int, bool) {
+	switch {
+	case policy.Mode == FillExact:
+		return 0, true
+	case policy.Mode != FillMaxOvershoot:
+		return 0, false
+	case !policy.Percent:
+		return policy.MaxOvershoot, true
+	case policy.MaxOvershoot > 0 && items > math.MaxInt/policy.MaxOvershoot:
+		return 0, false
+	default:
+		return items * policy.MaxOvershoot / 100, true
+	}
+}
+
+// Check returns ErrNoFeasiblePacking when the packing with the least overshoot for items, which ships totalShipped,
+// is not accepted by the policy.
+func (policy FillPolicy) Check(items, totalShipped int) error {
+	allowed, bounded := policy.AllowedOvershoot(items)
+	if !bounded || totalShipped-items <= allowed {
+		return nil
+	}
+
+	return fmt.Errorf("%w %s: %d items cannot be shipped with an overshoot of at most %d, the least is %d",
+		ErrNoFeasiblePacking, policy, items, allowed, totalShipped-items)
+}
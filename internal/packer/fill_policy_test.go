@@ -0,0 +1,95 @@
+package packer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/packer"
+)
+
+func TestParseFillPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expected packer.FillPolicy
+		text     string
+		valid    bool
+	}{
+		{text: "", expected: packer.FillPolicy{Mode: packer.FillMinOvershoot}, valid: true},
+		{text: "min-overshoot", expected: packer.FillPolicy{Mode: packer.FillMinOvershoot}, valid: true},
+		{text: "exact", expected: packer.FillPolicy{Mode: packer.FillExact}, valid: true},
+		{text: "max-overshoot=0", expected: packer.FillPolicy{Mode: packer.FillMaxOvershoot}, valid: true},
+		{text: "max-overshoot=250", expected: packer.FillPolicy{Mode: packer.FillMaxOvershoot, MaxOvershoot: 250}, valid: true},
+		{
+			text:     "max-overshoot=10%",
+			expected: packer.FillPolicy{Mode: packer.FillMaxOvershoot, MaxOvershoot: 10, Percent: true},
+			valid:    true,
+		},
+		{text: "max-overshoot"},
+		{text: "max-overshoot="},
+		{text: "max-overshoot=-1"},
+		{text: "max-overshoot=1.5%"},
+		{text: "max-overshoot=10%%"},
+		{text: "exact=1"},
+		{text: "closest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := packer.ParseFillPolicy(tt.text)
+			if !tt.valid {
+				require.ErrorIs(t, err, packer.ErrInvalidFillPolicy)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, policy)
+
+			roundTrip, err := packer.ParseFillPolicy(policy.String())
+			require.NoError(t, err)
+			require.Equal(t, policy, roundTrip)
+		})
+	}
+}
+
+func TestFillPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		policy       string
+		items        int
+		totalShipped int
+		feasible     bool
+	}{
+		{name: "min-overshoot accepts any overshoot", policy: "min-overshoot", items: 1, totalShipped: 250, feasible: true},
+		{name: "zero value accepts any overshoot", policy: "", items: 1, totalShipped: 5000, feasible: true},
+		{name: "exact", policy: "exact", items: 750, totalShipped: 750, feasible: true},
+		{name: "exact with overshoot", policy: "exact", items: 751, totalShipped: 1000},
+		{name: "within items", policy: "max-overshoot=249", items: 12001, totalShipped: 12250, feasible: true},
+		{name: "beyond items", policy: "max-overshoot=248", items: 12001, totalShipped: 12250},
+		{name: "within percent", policy: "max-overshoot=10%", items: 2260, totalShipped: 2486, feasible: true},
+		{name: "percent rounds down", policy: "max-overshoot=10%", items: 2269, totalShipped: 2496},
+		{name: "percent above 100", policy: "max-overshoot=1000%", items: 1, totalShipped: 11, feasible: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := packer.ParseFillPolicy(tt.policy)
+			require.NoError(t, err)
+
+			err = policy.Check(tt.items, tt.totalShipped)
+			if tt.feasible {
+				require.NoError(t, err)
+
+				return
+			}
+			require.ErrorIs(t, err, packer.ErrNoFeasiblePacking)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsha256/packer/internal/types"
+)
+
+var (
+	ErrTableTooLarge     = errors.New("table bound plus the largest packet size exceeds the table range")
+	ErrTableTooManySizes = errors.New("too many packet sizes for a table")
+)
+
+// MaxTableBytes bounds the memory of a lookup or DP table. A table holds an entry per total up to its bound plus the
+// largest size, so a large size alone can ask for gigabytes; such sizes are answered by the solver instead.
+const MaxTableBytes = 256 << 20
+
+// checkTableBytes returns ErrTableTooLarge when a table over the totals up to maxSum, plus extraBytes, would not fit
+// MaxTableBytes.
+func checkTableBytes(maxSum int, extraBytes int64) error {
+	const totalBytes = 10 // packs, run and sizeIndex.
+
+	if bytes := int64(maxSum+1)*totalBytes + extraBytes; bytes > MaxTableBytes {
+		return fmt.Errorf("%w: %d bytes exceed %d", ErrTableTooLarge, bytes, MaxTableBytes)
+	}
+
+	return nil
+}
+
+// overshootBytes is the size of a LookupTable.overshoot entry.
+const overshootBytes = 4
+
+// LookupTable holds the packing of every item count up to a bound for one set of packet sizes, so that a
+// calculation costs O(number of sizes) instead of a solver run. The packings are the ones
+// CalculateOptimalPacketsForItemsV1 returns.
+type LookupTable struct {
+	state *dpState
+	// overshoot is, per item count, how many more items the best packing ships.
+	overshoot []uint32
+	maxItems  int
+}
+
+// NewLookupTable solves every item count from 0 to maxItems for the sizes. It takes O((maxItems + largest size) *
+// number of sizes) time and returns ErrTableTooLarge, before allocating, when the table would exceed MaxTableBytes.
+// Sizes may be unsorted and contain duplicates.
+func NewLookupTable(sizes []types.PacketSize, maxItems int) (*LookupTable, error) {
+	packetSizes, err := normalizeParams(&CalculateOptimalPacketsForItemsParams{Items: maxItems, PacketSizes: sizes})
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := newDPState(packetSizes)
+	if err != nil {
+		return nil, err
+	}
+	maxSum := maxItems + state.maxSize()
+	if err = checkTableBytes(maxSum, int64(maxItems+1)*overshootBytes); err != nil {
+		return nil, err
+	}
+	if state, err = state.extended(maxSum); err != nil {
+		return nil, err
+	}
+
+	table := &LookupTable{overshoot: make([]uint32, maxItems+1), maxItems: maxItems}
+
+	// A multiple of the largest size always lies in [items, items + largest size), so every count has a total.
+	nextTotal := maxSum
+	for s := maxSum; s >= 0; s-- {
+		if state.packs[s] != unreachable {
+			nextTotal = s
+		}
+		if s <= maxItems {
+			table.overshoot[s] = uint32(nextTotal - s) //nolint:gosec // Below maxSum, which fits in uint32.
+		}
+	}
+
+	// Only the packings are read back, the pack counts are not needed anymore.
+	state.packs = nil
+	table.state = state
+
+	return table, nil
+}
+
+// Lookup returns the packing of items, or false when items is negative or above the table bound.
+func (table *LookupTable) Lookup(items int) (Result, bool) {
+	if items < 0 || items > table.maxItems {
+		return nil, false
+	}
+
+	return table.state.packing(items + int(table.overshoot[items])), true
+}
+
+// MaxItems returns the largest item count the table answers.
+func (table *LookupTable) MaxItems() int {
+	return table.maxItems
+}
+
+// MemoryBytes returns the memory held by the table.
+func (table *LookupTable) MemoryBytes() int64 {
+	return int64(len(table.overshoot))*overshootBytes + table.state.memoryBytes()
+}
@@ -0,0 +1,184 @@
+package packer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+)
+
+func TestLookupTable_MatchesV1(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sizes    []types.PacketSize
+		maxItems int
+	}{
+		{name: "product of ten", sizes: []types.PacketSize{250, 500, 1000, 2000, 5000}, maxItems: 20_000},
+		{name: "primes", sizes: []types.PacketSize{23, 31, 53}, maxItems: 5_000},
+		{name: "unsorted with duplicates", sizes: []types.PacketSize{9, 6, 20, 6}, maxItems: 500},
+		{name: "single size", sizes: []types.PacketSize{7}, maxItems: 100},
+		{name: "size one", sizes: []types.PacketSize{1, 3, 4}, maxItems: 1_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			table, err := packer.NewLookupTable(tt.sizes, tt.maxItems)
+			require.NoError(t, err)
+			require.Equal(t, tt.maxItems, table.MaxItems())
+
+			for items := 0; items <= tt.maxItems; items++ {
+				expected, err := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+					Items:       items,
+					PacketSizes: tt.sizes,
+				})
+				require.NoError(t, err)
+
+				actual, ok := table.Lookup(items)
+				require.True(t, ok)
+				require.Equal(t, expected, actual, "items %d", items)
+			}
+
+			_, ok := table.Lookup(tt.maxItems + 1)
+			require.False(t, ok, "items above the bound")
+			_, ok = table.Lookup(-1)
+			require.False(t, ok, "negative items")
+		})
+	}
+}
+
+func TestLookupTable_MemoryBytes(t *testing.T) {
+	t.Parallel()
+
+	table, err := packer.NewLookupTable([]types.PacketSize{250, 500, 1000, 2000, 5000}, 100_000)
+	require.NoError(t, err)
+
+	// 4 bytes per item count, 6 per total up to 105,000 and 8 per size.
+	require.InDelta(t, 4*100_001+6*105_001+8*5, table.MemoryBytes(), 6*105_001/10, "append may reserve some capacity")
+}
+
+func TestNewLookupTable_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := packer.NewLookupTable(nil, 10)
+	require.ErrorIs(t, err, packer.ErrNoPacketSizes)
+
+	_, err = packer.NewLookupTable([]types.PacketSize{0, 5}, 10)
+	require.ErrorIs(t, err, validation.ErrNonPositiveSize)
+
+	_, err = packer.NewLookupTable([]types.PacketSize{5}, -1)
+	require.ErrorIs(t, err, packer.ErrNegativeItems)
+
+	_, err = packer.NewLookupTable([]types.PacketSize{5}, 1<<32)
+	require.ErrorIs(t, err, packer.ErrTableTooLarge)
+
+	_, err = packer.NewLookupTable([]types.PacketSize{100_000_000}, 100_000)
+	require.ErrorIs(t, err, packer.ErrTableTooLarge, "a large size exceeds MaxTableBytes")
+}
+
+func TestPacker_LookupTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 20_000})
+	require.NoError(t, err)
+
+	reporter, ok := newPacker.(packer.LookupTableReporter)
+	require.True(t, ok)
+	stats := reporter.LookupTableStats()
+	require.True(t, stats.Ready)
+	require.Equal(t, 20_000, stats.MaxItems)
+	require.Positive(t, stats.MemoryBytes)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 12001)
+	require.NoError(t, err)
+	require.Equal(t, "lookup", result.Strategy)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}}, result.Lines)
+
+	result, err = newPacker.GetOptimalPackets(ctx, 20_001)
+	require.NoError(t, err)
+	require.Equal(t, "v1", result.Strategy, "items above the bound are solved")
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, []types.PacketSize{23, 31, 53}, types.PacketSizeDetails{}))
+	stats = reporter.LookupTableStats()
+	require.True(t, stats.Ready)
+	require.Equal(t, uint64(2), stats.SizeSetVersion)
+
+	result, err = newPacker.GetOptimalPackets(ctx, 500)
+	require.NoError(t, err)
+	require.Equal(t, "lookup", result.Strategy)
+	require.Equal(t, uint64(2), result.SizeSetVersion)
+	require.Equal(t, []types.PackingLine{{Size: 53, Quantity: 9}, {Size: 23, Quantity: 1}}, result.Lines)
+}
+
+func TestPacker_LookupTableInBackground(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 1_000, LookupTableInBackground: true})
+	require.NoError(t, err)
+	reporter, ok := newPacker.(packer.LookupTableReporter)
+	require.True(t, ok)
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, []types.PacketSize{23, 31, 53}, types.PacketSizeDetails{}))
+	require.Eventually(t, func() bool {
+		stats := reporter.LookupTableStats()
+
+		return stats.Ready && stats.SizeSetVersion == 2
+	}, 5*time.Second, time.Millisecond)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 500)
+	require.NoError(t, err)
+	require.Equal(t, "lookup", result.Strategy)
+	require.Equal(t, uint64(2), result.SizeSetVersion)
+}
+
+func TestPacker_LookupTableSupersedesBuilds(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 1_000, LookupTableInBackground: true})
+	require.NoError(t, err)
+	reporter, ok := newPacker.(packer.LookupTableReporter)
+	require.True(t, ok)
+
+	for size := range types.PacketSize(20) {
+		require.NoError(t, newPacker.SetPacketSizes(ctx, []types.PacketSize{size + 1, 53}, types.PacketSizeDetails{}))
+	}
+	require.Eventually(t, func() bool {
+		stats := reporter.LookupTableStats()
+
+		return stats.Ready && stats.SizeSetVersion == 21
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestPacker_LookupTableOverBudget(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 1_000})
+	require.NoError(t, err)
+	reporter, ok := newPacker.(packer.LookupTableReporter)
+	require.True(t, ok)
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, []types.PacketSize{250, 100_000_000}, types.PacketSizeDetails{}))
+	stats := reporter.LookupTableStats()
+	require.False(t, stats.Ready, "the table of a size over the memory budget is not built")
+	require.Equal(t, uint64(2), stats.SizeSetVersion)
+}
+
+func TestPacker_WithoutLookupTable(t *testing.T) {
+	t.Parallel()
+
+	reporter, ok := packer.New().(packer.LookupTableReporter)
+	require.True(t, ok)
+	require.Equal(t, packer.LookupTableStats{SizeSetVersion: 1}, reporter.LookupTableStats())
+}
@@ -0,0 +1,62 @@
+package packer
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/dsha256/packer/internal/types"
+)
+
+// packBatch is a batch of packets of one size that the solvers add to a packing as a whole: quantity packets of size,
+// holding weight items.
+type packBatch struct {
+	size     types.PacketSize
+	quantity types.PacketQuantity
+	weight   int
+}
+
+// packBatches returns the batches the solvers combine for sizes, which must be normalized, ordered by weight.
+//
+// An unconstrained size is a batch of one packet. A size that ships at least m steps of k packets, where m is
+// PacketConstraint.MinSteps and k PacketConstraint.StepOrOne, is the batches of m, m+1, ..., 2m-1 steps: any number
+// of steps of at least m is a sum of those, and sums of those are never fewer than m steps, so combining batches
+// freely honors the constraint. Without constraints the batches are the sizes, in the same order.
+//
+// The second value bounds how far above the items the solvers search: multiples of the smallest batch of any size
+// reach every interval of its weight, so a packing lies below items + the largest of those weights.
+func packBatches(items int, sizes []types.PacketSize, constraints []types.PacketConstraint) ([]packBatch, int, error) {
+	constraintOf := make(map[types.PacketSize]types.PacketConstraint, len(constraints))
+	for _, constraint := range constraints {
+		constraintOf[constraint.Size] = constraint
+	}
+
+	batches := make([]packBatch, 0, len(sizes))
+	searchAbove := 0
+	for _, size := range sizes {
+		constraint := constraintOf[size]
+		step, minSteps := int(constraint.StepOrOne()), int(constraint.MinSteps())
+		for steps := minSteps; steps < 2*minSteps; steps++ {
+			quantity := steps * step
+			if int(size) > math.MaxInt/quantity {
+				return nil, 0, fmt.Errorf("%w: %d packets of %d", ErrItemsOverflow, quantity, size)
+			}
+			batches = append(batches, packBatch{
+				size:     size,
+				quantity: types.PacketQuantity(quantity),
+				weight:   quantity * int(size),
+			})
+		}
+		searchAbove = max(searchAbove, minSteps*step*int(size))
+	}
+	if items > math.MaxInt-searchAbove {
+		return nil, 0, fmt.Errorf("%w: %d + %d", ErrItemsOverflow, items, searchAbove)
+	}
+
+	slices.SortStableFunc(batches, func(a, b packBatch) int {
+		return cmp.Compare(a.weight, b.weight)
+	})
+
+	return batches, searchAbove, nil
+}
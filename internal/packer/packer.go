@@ -8,6 +8,13 @@ import (
 
 type Packer interface {
 	ListPacketSizes(ctx context.Context) ([]types.PacketSize, error)
-	SetPacketSizes(ctx context.Context, sizes []types.PacketSize) error
-	GetOptimalPackets(ctx context.Context, items int) (map[types.PacketSize]types.PacketQuantity, error)
+	// PacketSizesVersion identifies the current packet sizes. It changes whenever they are replaced.
+	PacketSizesVersion(ctx context.Context) (uint64, error)
+	// ListPacketConstraints returns the constraints of the current packet sizes, see types.PacketConstraint.
+	ListPacketConstraints(ctx context.Context) ([]types.PacketConstraint, error)
+	// ListPacketDefinitions returns the definitions of the current packet sizes, see types.PacketDefinition.
+	ListPacketDefinitions(ctx context.Context) ([]types.PacketDefinition, error)
+	// SetPacketSizes replaces the packet sizes, their constraints and their definitions together.
+	SetPacketSizes(ctx context.Context, sizes []types.PacketSize, details types.PacketSizeDetails) error
+	GetOptimalPackets(ctx context.Context, items int) (types.PackingResult, error)
 }
@@ -2,42 +2,395 @@ package packer
 
 import (
 	"context"
-	"sort"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
 )
 
+var ErrUnknownStrategy = errors.New("unknown strategy, use v1, v2 or parallel")
+
+// Strategy selects the solver used by GetOptimalPackets.
+type Strategy string
+
+const (
+	// StrategyV1 solves with dynamic programming, see CalculateOptimalPacketsForItemsV1.
+	StrategyV1 Strategy = "v1"
+	// StrategyV2 solves with a min-heap search, see CalculateOptimalPacketsForItemsV2.
+	StrategyV2 Strategy = "v2"
+	// StrategyParallel solves with the dynamic programming of v1 spread over goroutines, see
+	// CalculateOptimalPacketsForItemsParallel.
+	StrategyParallel Strategy = "parallel"
+
+	// lookupStrategy is reported for calculations answered by the lookup table.
+	lookupStrategy = "lookup"
+)
+
+// Config holds the initial packet sizes and the solver strategy of a Packer.
+type Config struct {
+	Strategy     Strategy
+	DefaultSizes []types.PacketSize
+	// Workers is the number of goroutines of the parallel strategy, GOMAXPROCS when zero.
+	Workers int
+	// LookupTableMaxItems builds a LookupTable up to this item count whenever the sizes are set. Calculations up to
+	// it are answered from the table, larger ones by the solver. Zero disables the table.
+	LookupTableMaxItems int
+	// DPTableMaxItems keeps the dynamic programming table of the v1 strategy between calculations of up to this item
+	// count, see DPTable. Zero disables it; the v2 strategy does not use it.
+	DPTableMaxItems int
+	// LookupTableInBackground builds the table without blocking SetPacketSizes; the solver answers until it is ready.
+	LookupTableInBackground bool
+}
+
+// LookupTableStats describes the lookup table of the current packet sizes.
+type LookupTableStats struct {
+	MaxItems       int    `json:"max_items"`
+	MemoryBytes    int64  `json:"memory_bytes"`
+	SizeSetVersion uint64 `json:"size_set_version"`
+	Ready          bool   `json:"ready"`
+}
+
+// LookupTableReporter is implemented by packers that can answer from a LookupTable.
+type LookupTableReporter interface {
+	LookupTableStats() LookupTableStats
+}
+
+// DPTableStats describes the DPTable of the current packet sizes.
+type DPTableStats struct {
+	MaxItems    int   `json:"max_items"`
+	Totals      int   `json:"totals"`
+	MemoryBytes int64 `json:"memory_bytes"`
+}
+
+// DPTableReporter is implemented by packers that can solve with a DPTable.
+type DPTableReporter interface {
+	DPTableStats() DPTableStats
+}
+
+// DefaultConfig returns the configuration used by New.
+func DefaultConfig() Config {
+	return Config{
+		Strategy:     StrategyV1,
+		DefaultSizes: []types.PacketSize{250, 500, 1000, 2000, 5000},
+	}
+}
+
 type packer struct {
-	packetSizes     []types.PacketSize
-	packetSizesLock sync.Mutex
+	solve             func(params *CalculateOptimalPacketsForItemsParams) (Result, error)
+	lookupTable       *LookupTable
+	dpTable           *DPTable
+	strategy          Strategy
+	packetSizes       []types.PacketSize
+	packetConstraints []types.PacketConstraint
+	packetDefinitions []types.PacketDefinition
+	// lookupTableMaxItems, dpTableMaxItems and lookupTableInBackground are fixed at construction.
+	lookupTableMaxItems     int
+	dpTableMaxItems         int
+	packetSizesVersion      uint64
+	packetSizesLock         sync.RWMutex
+	lookupTableInBackground bool
+	// pendingBuild is the newest lookup table to build in the background, and building whether a goroutine builds
+	// them; both are guarded by buildLock.
+	pendingBuild *lookupTableBuild
+	building     bool
+	buildLock    sync.Mutex
+}
+
+// lookupTableBuild is a lookup table to build for a version of the packet sizes.
+type lookupTableBuild struct {
+	sizes   []types.PacketSize
+	version uint64
 }
 
 func New() Packer {
-	return &packer{
-		packetSizes: []types.PacketSize{250, 500, 1000, 2000, 5000},
+	newPacker, _ := NewWithConfig(DefaultConfig())
+
+	return newPacker
+}
+
+// NewWithConfig creates a Packer with the given default sizes and strategy.
+// Empty fields fall back to DefaultConfig.
+func NewWithConfig(cfg Config) (Packer, error) {
+	defaults := DefaultConfig()
+	if cfg.Strategy == "" {
+		cfg.Strategy = defaults.Strategy
+	}
+	if len(cfg.DefaultSizes) == 0 {
+		cfg.DefaultSizes = defaults.DefaultSizes
 	}
+
+	newPacker := &packer{
+		strategy:                cfg.Strategy,
+		packetSizesVersion:      1,
+		lookupTableMaxItems:     cfg.LookupTableMaxItems,
+		lookupTableInBackground: cfg.LookupTableInBackground,
+	}
+	if cfg.Strategy == StrategyV1 {
+		newPacker.dpTableMaxItems = cfg.DPTableMaxItems
+	}
+	switch cfg.Strategy {
+	case StrategyV1:
+		newPacker.solve = CalculateOptimalPacketsForItemsV1
+	case StrategyV2:
+		newPacker.solve = CalculateOptimalPacketsForItemsV2
+	case StrategyParallel:
+		newPacker.solve = func(params *CalculateOptimalPacketsForItemsParams) (Result, error) {
+			withWorkers := *params
+			withWorkers.Workers = cfg.Workers
+
+			return CalculateOptimalPacketsForItemsParallel(&withWorkers)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, cfg.Strategy)
+	}
+
+	if err := validation.ValidatePacketSizes(cfg.DefaultSizes); err != nil {
+		return nil, err
+	}
+	newPacker.packetSizes = slices.Sorted(slices.Values(cfg.DefaultSizes))
+	newPacker.dpTable = newPacker.newDPTable(newPacker.packetSizes)
+	newPacker.refreshLookupTable(newPacker.packetSizes, newPacker.packetSizesVersion)
+
+	return newPacker, nil
 }
 
 func (s *packer) ListPacketSizes(_ context.Context) ([]types.PacketSize, error) {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
 	return s.packetSizes, nil
 }
 
-func (s *packer) SetPacketSizes(_ context.Context, sizes []types.PacketSize) error {
-	sort.Slice(sizes, func(i, j int) bool {
-		return sizes[i] < sizes[j]
-	})
+func (s *packer) ListPacketConstraints(_ context.Context) ([]types.PacketConstraint, error) {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
+	return s.packetConstraints, nil
+}
+
+func (s *packer) ListPacketDefinitions(_ context.Context) ([]types.PacketDefinition, error) {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
+	return s.packetDefinitions, nil
+}
+
+func (s *packer) PacketSizesVersion(_ context.Context) (uint64, error) {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
+	return s.packetSizesVersion, nil
+}
+
+// SetPacketSizes replaces the packet sizes, their constraints and their definitions. The sizes must be positive and
+// unique, and the details valid for them; the caller's slices are copied. The lookup and DP tables only serve
+// unconstrained sizes.
+func (s *packer) SetPacketSizes(_ context.Context, sizes []types.PacketSize, details types.PacketSizeDetails) error {
+	if len(sizes) == 0 {
+		return ErrNoPacketSizes
+	}
+	if err := validation.ValidatePacketSizes(sizes, details.Constraints...); err != nil {
+		return err
+	}
+	if err := validation.ValidatePacketDefinitions(sizes, details.Definitions); err != nil {
+		return err
+	}
+	sizes = slices.Sorted(slices.Values(sizes))
+	constraints := slices.Clone(details.Constraints)
+	definitions := slices.Clone(details.Definitions)
+	for index, definition := range definitions {
+		if definition.Dimensions != nil {
+			dimensions := *definition.Dimensions
+			definitions[index].Dimensions = &dimensions
+		}
+	}
+	var dpTable *DPTable
+	if len(constraints) == 0 {
+		dpTable = s.newDPTable(sizes)
+	}
 
 	s.packetSizesLock.Lock()
 	s.packetSizes = sizes
+	s.packetConstraints = constraints
+	s.packetDefinitions = definitions
+	s.packetSizesVersion++
+	s.lookupTable = nil
+	s.dpTable = dpTable
+	version := s.packetSizesVersion
 	s.packetSizesLock.Unlock()
 
+	if len(constraints) == 0 {
+		s.refreshLookupTable(sizes, version)
+	}
+
 	return nil
 }
 
-func (s *packer) GetOptimalPackets(_ context.Context, items int) (map[types.PacketSize]types.PacketQuantity, error) {
-	return CalculateOptimalPacketsForItemsV1(&CalculateOptimalPacketsForItemsParams{
-		Items:       items,
-		PacketSizes: s.packetSizes,
-	}), nil
+func (s *packer) GetOptimalPackets(_ context.Context, items int) (types.PackingResult, error) {
+	s.packetSizesLock.RLock()
+	packetSizes, constraints, version := s.packetSizes, s.packetConstraints, s.packetSizesVersion
+	definitions := s.packetDefinitions
+	table, dpTable := s.lookupTable, s.dpTable
+	s.packetSizesLock.RUnlock()
+
+	start := time.Now()
+	strategy := string(s.strategy)
+	packets, found := Result(nil), false
+	if table != nil {
+		packets, found = table.Lookup(items)
+		if found {
+			strategy = lookupStrategy
+		}
+	}
+	if !found && dpTable != nil {
+		var err error
+		if packets, found, err = dpTable.Solve(items); err != nil {
+			return types.PackingResult{}, err
+		}
+	}
+	if !found {
+		var err error
+		packets, err = s.solve(&CalculateOptimalPacketsForItemsParams{
+			Items:       items,
+			PacketSizes: packetSizes,
+			Constraints: constraints,
+		})
+		if err != nil {
+			return types.PackingResult{}, err
+		}
+	}
+
+	result := types.NewPackingResult(items, packets)
+	result.Strategy = strategy
+	result.SizeSetVersion = version
+	result.ApplyDefinitions(definitions)
+	result.ComputeTime = time.Since(start)
+
+	return result, nil
+}
+
+func (s *packer) LookupTableStats() LookupTableStats {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
+	stats := LookupTableStats{MaxItems: s.lookupTableMaxItems, SizeSetVersion: s.packetSizesVersion}
+	if s.lookupTable != nil {
+		stats.MemoryBytes = s.lookupTable.MemoryBytes()
+		stats.Ready = true
+	}
+
+	return stats
+}
+
+func (s *packer) DPTableStats() DPTableStats {
+	s.packetSizesLock.RLock()
+	dpTable := s.dpTable
+	s.packetSizesLock.RUnlock()
+
+	stats := DPTableStats{MaxItems: s.dpTableMaxItems}
+	if dpTable != nil {
+		stats.Totals = dpTable.Totals()
+		stats.MemoryBytes = dpTable.MemoryBytes()
+	}
+
+	return stats
+}
+
+// newDPTable returns an empty DPTable for sizes, or nil when it is disabled. The table only grows with calculations.
+func (s *packer) newDPTable(sizes []types.PacketSize) *DPTable {
+	if s.dpTableMaxItems <= 0 {
+		return nil
+	}
+
+	dpTable, err := NewDPTable(sizes, s.dpTableMaxItems)
+	if err != nil {
+		slog.Warn("Failed to create DP table, calculations use the solver", "error", err)
+
+		return nil
+	}
+
+	return dpTable
+}
+
+// refreshLookupTable builds the lookup table of sizes, in the background when configured, and installs it unless the
+// sizes were replaced in the meantime. A single goroutine builds in the background, always the newest sizes, so a
+// burst of updates does not build a table for each of them.
+func (s *packer) refreshLookupTable(sizes []types.PacketSize, version uint64) {
+	if s.lookupTableMaxItems <= 0 {
+		return
+	}
+
+	if !s.lookupTableInBackground {
+		s.buildLookupTable(lookupTableBuild{sizes: sizes, version: version})
+
+		return
+	}
+
+	s.buildLock.Lock()
+	defer s.buildLock.Unlock()
+	s.pendingBuild = &lookupTableBuild{sizes: sizes, version: version}
+	if !s.building {
+		s.building = true
+		go s.buildPendingLookupTables()
+	}
+}
+
+// buildPendingLookupTables builds the pending lookup table until no newer one is requested.
+func (s *packer) buildPendingLookupTables() {
+	for {
+		s.buildLock.Lock()
+		build := s.pendingBuild
+		s.pendingBuild = nil
+		if build == nil {
+			s.building = false
+			s.buildLock.Unlock()
+
+			return
+		}
+		s.buildLock.Unlock()
+
+		s.buildLookupTable(*build)
+	}
+}
+
+// buildLookupTable builds and installs the lookup table of a version of the sizes, skipping versions already
+// replaced.
+func (s *packer) buildLookupTable(build lookupTableBuild) {
+	if s.isStale(build.version) {
+		return
+	}
+
+	start := time.Now()
+	table, err := NewLookupTable(build.sizes, s.lookupTableMaxItems)
+	if err != nil {
+		slog.Warn("Failed to build lookup table, calculations use the solver", "version", build.version, "error", err)
+
+		return
+	}
+
+	s.packetSizesLock.Lock()
+	defer s.packetSizesLock.Unlock()
+	if s.packetSizesVersion != build.version {
+		return
+	}
+	s.lookupTable = table
+	slog.Info("Lookup table built",
+		"version", build.version,
+		"max_items", table.MaxItems(),
+		"memory_bytes", table.MemoryBytes(),
+		"duration", time.Since(start),
+	)
+}
+
+// isStale reports whether the sizes of version were replaced.
+func (s *packer) isStale(version uint64) bool {
+	s.packetSizesLock.RLock()
+	defer s.packetSizesLock.RUnlock()
+
+	return s.packetSizesVersion != version
 }
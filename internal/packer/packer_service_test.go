@@ -0,0 +1,194 @@
+package packer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expectedErr   error
+		name          string
+		cfg           packer.Config
+		expectedSizes []types.PacketSize
+	}{
+		{
+			name:          "defaults",
+			expectedSizes: []types.PacketSize{250, 500, 1000, 2000, 5000},
+		},
+		{
+			name:          "custom sizes are sorted",
+			cfg:           packer.Config{Strategy: packer.StrategyV2, DefaultSizes: []types.PacketSize{53, 23, 31}},
+			expectedSizes: []types.PacketSize{23, 31, 53},
+		},
+		{
+			name:        "unknown strategy",
+			cfg:         packer.Config{Strategy: "v3"},
+			expectedErr: packer.ErrUnknownStrategy,
+		},
+		{
+			name:        "invalid sizes",
+			cfg:         packer.Config{DefaultSizes: []types.PacketSize{23, 23}},
+			expectedErr: validation.ErrDuplicatedSizes,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			newPacker, err := packer.NewWithConfig(tt.cfg)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			sizes, err := newPacker.ListPacketSizes(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedSizes, sizes)
+
+			result, err := newPacker.GetOptimalPackets(context.Background(), 500_000)
+			require.NoError(t, err)
+			require.NotEmpty(t, result.Lines)
+		})
+	}
+}
+
+func TestNewWithConfig_Strategies(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range []packer.Strategy{packer.StrategyV1, packer.StrategyV2, packer.StrategyParallel} {
+		newPacker, err := packer.NewWithConfig(packer.Config{Strategy: strategy})
+		require.NoError(t, err)
+
+		result, err := newPacker.GetOptimalPackets(context.Background(), 12001)
+		require.NoError(t, err)
+		require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 2}, {Size: 2000, Quantity: 1}, {Size: 250, Quantity: 1}},
+			result.Lines, strategy)
+		require.Equal(t, 12001, result.ItemsRequested)
+		require.Equal(t, 12250, result.TotalShipped)
+		require.Equal(t, 249, result.Overshoot)
+		require.Equal(t, 4, result.PackCount)
+		require.Equal(t, string(strategy), result.Strategy)
+		require.Equal(t, uint64(1), result.SizeSetVersion)
+		require.Positive(t, result.ComputeTime)
+		require.Equal(t, map[types.PacketSize]types.PacketQuantity{5000: 2, 2000: 1, 250: 1}, result.Packets())
+	}
+}
+
+func TestPacker_SetPacketSizesChangesVersion(t *testing.T) {
+	t.Parallel()
+
+	newPacker := packer.New()
+	ctx := context.Background()
+
+	sizes := []types.PacketSize{53, 23, 31}
+	require.NoError(t, newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{}))
+	require.Equal(t, []types.PacketSize{53, 23, 31}, sizes, "the caller's sizes are not sorted in place")
+
+	version, err := newPacker.PacketSizesVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 500_000)
+	require.NoError(t, err)
+	require.Equal(t, version, result.SizeSetVersion)
+	require.Equal(t, []types.PackingLine{{Size: 53, Quantity: 9429}, {Size: 31, Quantity: 7}, {Size: 23, Quantity: 2}}, result.Lines)
+
+	require.ErrorIs(t, newPacker.SetPacketSizes(ctx, nil, types.PacketSizeDetails{}), packer.ErrNoPacketSizes)
+	version, err = newPacker.PacketSizesVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version, "rejected sizes keep the version")
+}
+
+func TestPacker_SetPacketSizesWithConstraints(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 100_000, DPTableMaxItems: 100_000})
+	require.NoError(t, err)
+
+	sizes := []types.PacketSize{250, 500, 1000, 2000, 5000}
+	constraints := []types.PacketConstraint{{Size: 5000, Step: 2}}
+	require.NoError(t, newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{Constraints: constraints}))
+
+	listed, err := newPacker.ListPacketConstraints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, constraints, listed)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 5001)
+	require.NoError(t, err)
+	require.Equal(t, "v1", result.Strategy, "the lookup table only serves unconstrained sizes")
+	require.Equal(t, []types.PackingLine{{Size: 2000, Quantity: 2}, {Size: 1000, Quantity: 1}, {Size: 250, Quantity: 1}}, result.Lines)
+	reporter, ok := newPacker.(packer.DPTableReporter)
+	require.True(t, ok)
+	require.Equal(t, packer.DPTableStats{MaxItems: 100_000}, reporter.DPTableStats(), "neither does the DP table")
+
+	err = newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{
+		Constraints: []types.PacketConstraint{{Size: 750, Step: 2}},
+	})
+	require.ErrorIs(t, err, validation.ErrInvalidConstraint)
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{}))
+	listed, err = newPacker.ListPacketConstraints(ctx)
+	require.NoError(t, err)
+	require.Empty(t, listed, "replacing the sizes replaces their constraints")
+
+	result, err = newPacker.GetOptimalPackets(ctx, 5001)
+	require.NoError(t, err)
+	require.Equal(t, []types.PackingLine{{Size: 5000, Quantity: 1}, {Size: 250, Quantity: 1}}, result.Lines)
+}
+
+func TestPacker_SetPacketSizesWithDefinitions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newPacker, err := packer.NewWithConfig(packer.Config{LookupTableMaxItems: 100_000})
+	require.NoError(t, err)
+
+	sizes := []types.PacketSize{250, 500, 1000, 2000, 5000}
+	definitions := []types.PacketDefinition{
+		{Size: 5000, ID: "box-xl", Label: "Pallet box", Weight: 52.5, Cost: 4, Dimensions: &types.Dimensions{Length: 1.2, Width: 0.8, Height: 0.5}},
+		{Size: 250, ID: "box-s", Weight: 2.5},
+	}
+	require.NoError(t, newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{Definitions: definitions}))
+
+	listed, err := newPacker.ListPacketDefinitions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, definitions, listed)
+
+	result, err := newPacker.GetOptimalPackets(ctx, 12001)
+	require.NoError(t, err)
+	require.Equal(t, "lookup", result.Strategy, "definitions never change the packing")
+	require.Len(t, result.Lines, 3)
+	require.Equal(t, &definitions[0], result.Lines[0].Definition)
+	require.Nil(t, result.Lines[1].Definition, "size 2000 has no definition")
+	require.Equal(t, &definitions[1], result.Lines[2].Definition)
+	require.InDelta(t, 2*52.5+2.5, result.TotalWeight, 1e-9)
+	require.InDelta(t, 2*0.48, result.TotalVolume, 1e-9)
+	require.InDelta(t, 2*4.0, result.TotalCost, 1e-9)
+
+	err = newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{
+		Definitions: []types.PacketDefinition{{Size: 500, ID: "box-s"}, {Size: 1000, ID: "box-s"}},
+	})
+	require.ErrorIs(t, err, validation.ErrInvalidDefinition)
+
+	require.NoError(t, newPacker.SetPacketSizes(ctx, sizes, types.PacketSizeDetails{}))
+	listed, err = newPacker.ListPacketDefinitions(ctx)
+	require.NoError(t, err)
+	require.Empty(t, listed, "replacing the sizes replaces their definitions")
+
+	result, err = newPacker.GetOptimalPackets(ctx, 12001)
+	require.NoError(t, err)
+	require.Zero(t, result.TotalWeight)
+}
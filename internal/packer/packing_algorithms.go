@@ -2,81 +2,209 @@ package packer
 
 import (
 	"container/heap"
+	"errors"
+	"fmt"
 	"math"
+	"slices"
 
 	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
 )
 
+var (
+	ErrNoPacketSizes  = errors.New("at least one packet size is required")
+	ErrNegativeItems  = errors.New("items must not be negative")
+	ErrItemsOverflow  = errors.New("items plus the largest packet size overflow")
+	ErrNoPacking      = errors.New("no combination of packet sizes covers the items")
+	errUnexpectedHeap = errors.New("unexpected min-heap element")
+)
+
+// MaxSearchTotal bounds the largest total the solvers search, items + how far above them a packing may lie. V1 and
+// the parallel solver allocate a table entry per total, so it bounds their memory; it is above the items the API
+// accepts plus any reasonable size.
+const MaxSearchTotal = 1<<31 - 1
+
 type CalculateOptimalPacketsForItemsParams struct {
 	PacketSizes []types.PacketSize
+	// Constraints restrict the quantity of some sizes, see types.PacketConstraint. Constraints of sizes that are not
+	// in PacketSizes are ignored.
+	Constraints []types.PacketConstraint
 	Items       int
+	// Workers is the number of goroutines of CalculateOptimalPacketsForItemsParallel, GOMAXPROCS when zero.
+	// The other solvers ignore it.
+	Workers int
+}
+
+// Result holds the quantity of each packet size in a packing. Sizes that are not used are absent.
+type Result map[types.PacketSize]types.PacketQuantity
+
+// TotalItems returns the number of items the packing ships.
+func (result Result) TotalItems() int {
+	total := 0
+	for size, quantity := range result {
+		total += int(size) * int(quantity)
+	}
+
+	return total
 }
 
-func CalculateOptimalPacketsForItemsV1(params *CalculateOptimalPacketsForItemsParams) map[types.PacketSize]types.PacketQuantity {
-	result := make(map[types.PacketSize]types.PacketQuantity)
+// Packs returns the number of packets in the packing.
+func (result Result) Packs() int {
+	packs := 0
+	for _, quantity := range result {
+		packs += int(quantity)
+	}
+
+	return packs
+}
 
-	sizes := make([]int, len(params.PacketSizes))
-	for i, ps := range params.PacketSizes {
-		sizes[i] = int(ps)
+// SolverWork estimates the work and memory the solvers need for a calculation. Both grow with the totals searched,
+// items + how far above them a packing may lie, and the work with the batches the sizes expand into per total; see
+// packBatches. Without constraints that is items + the largest size. Calculations the solvers would reject for their
+// size weigh math.MaxInt64.
+func SolverWork(items int, sizes []types.PacketSize, details types.PacketSizeDetails) int64 {
+	if len(sizes) == 0 {
+		return int64(items)
 	}
 
-	maxSize := sizes[len(sizes)-1]
-	maxSum := params.Items + maxSize
+	sorted := slices.Compact(slices.Sorted(slices.Values(sizes)))
+	batches, searchAbove, err := packBatches(items, sorted, details.Constraints)
+	if err != nil || items > math.MaxInt-searchAbove {
+		return math.MaxInt64
+	}
+
+	totals := int64(items + searchAbove)
+	if totals > math.MaxInt64/int64(len(batches)) {
+		return math.MaxInt64
+	}
+
+	return totals * int64(len(batches)) / int64(len(sorted))
+}
+
+// normalizeParams validates the calculation and returns its sizes sorted ascending without duplicates.
+// The caller's slice is not modified.
+func normalizeParams(params *CalculateOptimalPacketsForItemsParams) ([]types.PacketSize, error) {
+	if params.Items < 0 {
+		return nil, fmt.Errorf("%w, got %d", ErrNegativeItems, params.Items)
+	}
+	if len(params.PacketSizes) == 0 {
+		return nil, ErrNoPacketSizes
+	}
+
+	sizes := slices.Clone(params.PacketSizes)
+	slices.Sort(sizes)
+	sizes = slices.Compact(sizes)
+
+	if sizes[0] < 1 {
+		return nil, fmt.Errorf("%w, got %d", validation.ErrNonPositiveSize, sizes[0])
+	}
+	maxSize := int(sizes[len(sizes)-1])
+	if params.Items > math.MaxInt-maxSize {
+		return nil, fmt.Errorf("%w: %d + %d", ErrItemsOverflow, params.Items, maxSize)
+	}
+	if _, err := searchTotal(params.Items, maxSize); err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// searchTotal returns items + searchAbove, the largest total a solver searches, or ErrTableTooLarge above
+// MaxSearchTotal.
+func searchTotal(items, searchAbove int) (int, error) {
+	if items > MaxSearchTotal-searchAbove {
+		return 0, fmt.Errorf("%w: %d + %d exceeds %d", ErrTableTooLarge, items, searchAbove, MaxSearchTotal)
+	}
+
+	return items + searchAbove, nil
+}
+
+// CalculateOptimalPacketsForItemsV1 ships the fewest items possible, then uses the fewest packets for that total,
+// by dynamic programming over every total up to items + the largest size. Sizes may be unsorted and contain
+// duplicates; zero items need no packets. Constrained sizes are solved as batches of packets, see packBatches.
+func CalculateOptimalPacketsForItemsV1(params *CalculateOptimalPacketsForItemsParams) (Result, error) {
+	packetSizes, err := normalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Result)
+	if params.Items == 0 {
+		return result, nil
+	}
+
+	batches, searchAbove, err := packBatches(params.Items, packetSizes, params.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	maxSum, err := searchTotal(params.Items, searchAbove)
+	if err != nil {
+		return nil, err
+	}
 
 	dpPacks := make([]int, maxSum+1)
-	prevPacket := make([]int, maxSum+1)
+	prevBatch := make([]int, maxSum+1)
 	for i := range dpPacks {
-		dpPacks[i] = math.MaxInt32
+		dpPacks[i] = math.MaxInt
 	}
 	dpPacks[0] = 0
 
 	for s := 1; s <= maxSum; s++ {
-		for _, sz := range sizes {
-			if s >= sz && dpPacks[s-sz] != math.MaxInt32 {
-				if cand := dpPacks[s-sz] + 1; cand < dpPacks[s] {
+		for index, batch := range batches {
+			if s >= batch.weight && dpPacks[s-batch.weight] != math.MaxInt {
+				if cand := dpPacks[s-batch.weight] + int(batch.quantity); cand < dpPacks[s] {
 					dpPacks[s] = cand
-					prevPacket[s] = sz
+					prevBatch[s] = index
 				}
 			}
 		}
 	}
 
+	// Multiples of a batch of the largest size always lie in [items, items + its weight), so a total is found.
 	bestSum := -1
 	for s := params.Items; s <= maxSum; s++ {
-		if dpPacks[s] < math.MaxInt32 {
+		if dpPacks[s] < math.MaxInt {
 			bestSum = s
 
 			break
 		}
 	}
 	if bestSum < 0 {
-		result[types.PacketSize(sizes[0])] = 1
-
-		return result
+		return nil, ErrNoPacking
 	}
 
-	countMap := make(map[int]int)
-	for cur := bestSum; cur > 0; cur -= prevPacket[cur] {
-		countMap[prevPacket[cur]]++
-	}
-
-	for _, sz := range sizes {
-		if cnt, ok := countMap[sz]; ok && cnt > 0 {
-			result[types.PacketSize(sz)] = types.PacketQuantity(cnt)
-		}
+	for cur := bestSum; cur > 0; cur -= batches[prevBatch[cur]].weight {
+		batch := batches[prevBatch[cur]]
+		result[batch.size] += batch.quantity
 	}
 
-	return result
+	return result, nil
 }
 
-func CalculateOptimalPacketsForItemsV2(params *CalculateOptimalPacketsForItemsParams) map[types.PacketSize]types.PacketQuantity {
+// CalculateOptimalPacketsForItemsV2 finds a packing as good as CalculateOptimalPacketsForItemsV1's, the same total
+// and number of packets, with a Dijkstra search that visits totals in ascending order and stops at the first one
+// covering the items. Among packings that tie, it may pick different packets than V1.
+func CalculateOptimalPacketsForItemsV2(params *CalculateOptimalPacketsForItemsParams) (Result, error) {
+	sizes, err := normalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
 	items := params.Items
-	sizes := params.PacketSizes
+	if items == 0 {
+		return make(Result), nil
+	}
+
+	batches, searchAbove, err := packBatches(items, sizes, params.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = searchTotal(items, searchAbove); err != nil {
+		return nil, err
+	}
 
 	minNumPacks := make(map[int]int)
 	predecessor := make(map[int]struct {
 		prevT int
-		s     types.PacketSize
+		batch packBatch
 	})
 
 	minHeap := &MinHeap{}
@@ -88,7 +216,7 @@ func CalculateOptimalPacketsForItemsV2(params *CalculateOptimalPacketsForItemsPa
 		popped := heap.Pop(minHeap)
 		heapElement, ok := popped.(HeapElement)
 		if !ok {
-			return make(map[types.PacketSize]types.PacketQuantity)
+			return nil, fmt.Errorf("%w: %T", errUnexpectedHeap, popped)
 		}
 		total := heapElement.total
 		numPacks := heapElement.numPacks
@@ -98,31 +226,30 @@ func CalculateOptimalPacketsForItemsV2(params *CalculateOptimalPacketsForItemsPa
 		}
 
 		if total >= items {
-			result := make(map[types.PacketSize]types.PacketQuantity)
+			result := make(Result)
 			currentTotal := total
 			for currentTotal != 0 {
 				pred := predecessor[currentTotal]
-				s := pred.s
-				result[s]++
+				result[pred.batch.size] += pred.batch.quantity
 				currentTotal = pred.prevT
 			}
 
-			return result
+			return result, nil
 		}
 
-		for _, size := range sizes {
-			newTotal := total + int(size)
-			newNumPacks := numPacks + 1
+		for _, batch := range batches {
+			newTotal := total + batch.weight
+			newNumPacks := numPacks + int(batch.quantity)
 			if _, ok := minNumPacks[newTotal]; !ok || newNumPacks < minNumPacks[newTotal] {
 				minNumPacks[newTotal] = newNumPacks
 				predecessor[newTotal] = struct {
 					prevT int
-					s     types.PacketSize
-				}{total, size}
+					batch packBatch
+				}{total, batch}
 				heap.Push(minHeap, HeapElement{newTotal, newNumPacks})
 			}
 		}
 	}
 
-	return make(map[types.PacketSize]types.PacketQuantity)
+	return nil, ErrNoPacking
 }
@@ -8,7 +8,7 @@ import (
 )
 
 //nolint:dupl // Clearer in this case.
-func benchmarkCalculateOptimalPacketsForItemsWithProductOfTenSizes(b *testing.B, calculateFunc func(params *packer.CalculateOptimalPacketsForItemsParams) map[types.PacketSize]types.PacketQuantity) {
+func benchmarkCalculateOptimalPacketsForItemsWithProductOfTenSizes(b *testing.B, calculateFunc func(params *packer.CalculateOptimalPacketsForItemsParams) (packer.Result, error)) {
 	b.Helper()
 
 	testCases := []struct {
@@ -65,7 +65,7 @@ func benchmarkCalculateOptimalPacketsForItemsWithProductOfTenSizes(b *testing.B,
 }
 
 //nolint:dupl // Clearer in this case.
-func benchmarkCalculateOptimalPacketsForItemsWithPrimeSizes(b *testing.B, calculateFunc func(params *packer.CalculateOptimalPacketsForItemsParams) map[types.PacketSize]types.PacketQuantity) {
+func benchmarkCalculateOptimalPacketsForItemsWithPrimeSizes(b *testing.B, calculateFunc func(params *packer.CalculateOptimalPacketsForItemsParams) (packer.Result, error)) {
 	b.Helper()
 
 	testCases := []struct {
@@ -136,3 +136,65 @@ func Benchmark_CalculateOptimalPacketsForItemsV2_PrimeSizes(b *testing.B) {
 func Benchmark_CalculateOptimalPacketsForItemsV1_PrimeSizes(b *testing.B) {
 	benchmarkCalculateOptimalPacketsForItemsWithPrimeSizes(b, packer.CalculateOptimalPacketsForItemsV1)
 }
+
+func Benchmark_LookupTable(b *testing.B) {
+	sizes := []types.PacketSize{251, 503, 997, 2003, 4999}
+
+	b.Run("Build_100k", func(b *testing.B) {
+		for b.Loop() {
+			packer.NewLookupTable(sizes, 100_000)
+		}
+	})
+
+	table, err := packer.NewLookupTable(sizes, 100_000)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Lookup_~100k_Items", func(b *testing.B) {
+		for b.Loop() {
+			table.Lookup(100_000 - 123)
+		}
+	})
+}
+
+// Benchmark_GrowingStream compares solving each calculation of a stream of mixed, mostly growing item counts from
+// scratch with V1 against sharing one DPTable, which only computes the totals it has not seen yet.
+func Benchmark_GrowingStream(b *testing.B) {
+	sizes := []types.PacketSize{251, 503, 997, 2003, 4999}
+	items := func(i int) int {
+		return 50_123 + (i*9_973)%1_000_000
+	}
+
+	b.Run("V1", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+				Items:       items(i),
+				PacketSizes: sizes,
+			})
+			i++
+		}
+	})
+
+	b.Run("DPTable", func(b *testing.B) {
+		table, err := packer.NewDPTable(sizes, 1_050_123)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		i := 0
+		for b.Loop() {
+			table.Solve(items(i))
+			i++
+		}
+	})
+}
+
+func Benchmark_CalculateOptimalPacketsForItemsParallel_ProductOfTenSizes(b *testing.B) {
+	benchmarkCalculateOptimalPacketsForItemsWithProductOfTenSizes(b, packer.CalculateOptimalPacketsForItemsParallel)
+}
+
+func Benchmark_CalculateOptimalPacketsForItemsParallel_PrimeSizes(b *testing.B) {
+	benchmarkCalculateOptimalPacketsForItemsWithPrimeSizes(b, packer.CalculateOptimalPacketsForItemsParallel)
+}
@@ -0,0 +1,190 @@
+package packer_test
+
+import (
+	"encoding/binary"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+)
+
+const (
+	fuzzMaxItems    = 20_000
+	fuzzMaxSize     = 5_000
+	fuzzMaxSizes    = 5
+	oracleMaxItems  = 64
+	oracleMaxSize   = 64
+	oracleMaxSizes  = 4
+	propertyRuns    = 500
+	propertySeedOne = 39
+	propertySeedTwo = 2024
+)
+
+// FuzzCalculateOptimalPackets checks the solver properties on arbitrary inputs. The checked-in corpus lives in
+// testdata/fuzz/FuzzCalculateOptimalPackets; run `go test -fuzz=FuzzCalculateOptimalPackets ./internal/packer`
+// to search for new failures.
+func FuzzCalculateOptimalPackets(f *testing.F) {
+	f.Add(uint16(12001), encodeSizes(250, 500, 1000, 2000, 5000))
+	f.Add(uint16(43), encodeSizes(6, 9, 20))
+	f.Add(uint16(1), encodeSizes(5000))
+
+	f.Fuzz(func(t *testing.T, rawItems uint16, rawSizes []byte) {
+		items, sizes := decodeInput(rawItems, rawSizes)
+		checkPackingProperties(t, items, sizes)
+	})
+}
+
+// TestCalculateOptimalPackets_Properties runs the fuzz properties on deterministic random inputs, so that they are
+// exercised by every test run, including inputs small enough for the brute-force oracle.
+func TestCalculateOptimalPackets_Properties(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewPCG(propertySeedOne, propertySeedTwo)) //nolint:gosec // Reproducible test inputs.
+	for range propertyRuns {
+		maxItems, maxSize, maxSizes := fuzzMaxItems, fuzzMaxSize, fuzzMaxSizes
+		if random.IntN(2) == 0 {
+			maxItems, maxSize, maxSizes = oracleMaxItems, oracleMaxSize, oracleMaxSizes
+		}
+
+		sizes := make([]types.PacketSize, 1+random.IntN(maxSizes))
+		for i := range sizes {
+			sizes[i] = types.PacketSize(1 + random.IntN(maxSize))
+		}
+
+		checkPackingProperties(t, random.IntN(maxItems+1), sizes)
+	}
+}
+
+// checkPackingProperties asserts that Parallel returns the packing of V1, that V1 and V2 agree on the total shipped
+// and the pack count, that every item is shipped in configured sizes only, and, for small inputs, that no combination
+// ships fewer items or, for the same total, uses fewer packs. The sizes may be unsorted and contain duplicates, as the
+// solvers normalize them.
+func checkPackingProperties(t *testing.T, items int, sizes []types.PacketSize) {
+	t.Helper()
+
+	params := &packer.CalculateOptimalPacketsForItemsParams{Items: items, PacketSizes: sizes}
+	v1, err := packer.CalculateOptimalPacketsForItemsV1(params)
+	if err != nil {
+		t.Fatalf("items %d, sizes %v: V1 failed: %v", items, sizes, err)
+	}
+	v2, err := packer.CalculateOptimalPacketsForItemsV2(params)
+	if err != nil {
+		t.Fatalf("items %d, sizes %v: V2 failed: %v", items, sizes, err)
+	}
+	parallel, err := packer.CalculateOptimalPacketsForItemsParallel(params)
+	if err != nil {
+		t.Fatalf("items %d, sizes %v: Parallel failed: %v", items, sizes, err)
+	}
+	if !maps.Equal(v1, parallel) {
+		t.Fatalf("items %d, sizes %v: V1 packs %v, Parallel packs %v", items, sizes, v1, parallel)
+	}
+
+	total1, packs1 := summarize(t, v1, sizes)
+	total2, packs2 := summarize(t, v2, sizes)
+
+	if total1 != total2 || packs1 != packs2 {
+		t.Fatalf("items %d, sizes %v: V1 ships %d in %d packs (%v), V2 ships %d in %d packs (%v)",
+			items, sizes, total1, packs1, v1, total2, packs2, v2)
+	}
+	if total1 < items {
+		t.Fatalf("items %d, sizes %v: ships only %d items (%v)", items, sizes, total1, v1)
+	}
+
+	if items > oracleMaxItems || len(sizes) > oracleMaxSizes || slices.Max(sizes) > oracleMaxSize {
+		return
+	}
+
+	bestTotal, bestPacks := bruteForce(items, normalizeSizes(slices.Clone(sizes)))
+	if total1 != bestTotal || packs1 != bestPacks {
+		t.Fatalf("items %d, sizes %v: ships %d in %d packs (%v), the best is %d in %d packs",
+			items, sizes, total1, packs1, v1, bestTotal, bestPacks)
+	}
+}
+
+// summarize returns the items shipped and the number of packs, failing on sizes that are not configured.
+func summarize(t *testing.T, result packer.Result, sizes []types.PacketSize) (int, int) {
+	t.Helper()
+
+	var total, packs int
+	for size, quantity := range result {
+		if !slices.Contains(sizes, size) {
+			t.Fatalf("sizes %v: result %v uses the unconfigured size %d", sizes, result, size)
+		}
+		if quantity <= 0 {
+			t.Fatalf("sizes %v: result %v has a non-positive quantity for size %d", sizes, result, size)
+		}
+		total += int(size) * int(quantity)
+		packs += int(quantity)
+	}
+
+	if total != result.TotalItems() || packs != result.Packs() {
+		t.Fatalf("result %v reports %d items in %d packs, want %d in %d", result, result.TotalItems(), result.Packs(), total, packs)
+	}
+
+	return total, packs
+}
+
+// bruteForce enumerates every combination that ships fewer than items + the largest size, which always contains
+// the optimum, and returns the smallest total of at least items and the fewest packs for it.
+func bruteForce(items int, sizes []types.PacketSize) (int, int) {
+	if items == 0 {
+		return 0, 0
+	}
+
+	limit := items + int(slices.Max(sizes))
+	bestTotal, bestPacks := limit, 0
+
+	var enumerate func(index, total, packs int)
+	enumerate = func(index, total, packs int) {
+		if index == len(sizes) {
+			if total >= items && (total < bestTotal || (total == bestTotal && packs < bestPacks)) {
+				bestTotal, bestPacks = total, packs
+			}
+
+			return
+		}
+
+		for count := 0; total+count*int(sizes[index]) < limit; count++ {
+			enumerate(index+1, total+count*int(sizes[index]), packs+count)
+		}
+	}
+	enumerate(0, 0, 0)
+
+	return bestTotal, bestPacks
+}
+
+// decodeInput maps raw fuzz input to a valid calculation: 0 to fuzzMaxItems items and up to fuzzMaxSizes positive
+// sizes, one per two bytes, in input order and possibly repeated.
+func decodeInput(rawItems uint16, rawSizes []byte) (int, []types.PacketSize) {
+	items := int(rawItems) % (fuzzMaxItems + 1)
+
+	sizes := make([]types.PacketSize, 0, fuzzMaxSizes)
+	for i := 0; i+1 < len(rawSizes) && len(sizes) < fuzzMaxSizes; i += 2 {
+		sizes = append(sizes, types.PacketSize(1+int(binary.BigEndian.Uint16(rawSizes[i:]))%fuzzMaxSize))
+	}
+
+	if len(sizes) == 0 {
+		sizes = append(sizes, 1)
+	}
+
+	return items, sizes
+}
+
+// encodeSizes is the inverse of the size decoding in decodeInput.
+func encodeSizes(sizes ...int) []byte {
+	raw := make([]byte, 0, 2*len(sizes))
+	for _, size := range sizes {
+		raw = binary.BigEndian.AppendUint16(raw, uint16(size-1)) //nolint:gosec // Sizes are at most fuzzMaxSize.
+	}
+
+	return raw
+}
+
+func normalizeSizes(sizes []types.PacketSize) []types.PacketSize {
+	slices.Sort(sizes)
+
+	return slices.Compact(sizes)
+}
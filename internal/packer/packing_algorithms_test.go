@@ -1,11 +1,17 @@
 package packer_test
 
 import (
+	"math"
+	"math/rand/v2"
 	"reflect"
+	"slices"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/dsha256/packer/internal/packer"
 	"github.com/dsha256/packer/internal/types"
+	"github.com/dsha256/packer/internal/validation"
 )
 
 func Test_CalculateOptimalPacketsForItems(t *testing.T) {
@@ -76,11 +82,14 @@ func Test_CalculateOptimalPacketsForItems(t *testing.T) {
 		t.Run(testCase.Name, func(t *testing.T) {
 			t.Parallel()
 
-			result := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
+			result, err := packer.CalculateOptimalPacketsForItemsV1(&packer.CalculateOptimalPacketsForItemsParams{
 				Items:       testCase.Items,
 				PacketSizes: testCase.PacketSizes,
 			})
-			if !reflect.DeepEqual(result, testCase.ExpectedOptimalPacks) {
+			if err != nil {
+				t.Fatalf("CalculateOptimalPacketsForItemsV1: %v", err)
+			}
+			if !reflect.DeepEqual(map[types.PacketSize]types.PacketQuantity(result), testCase.ExpectedOptimalPacks) {
 				t.Errorf("CalculateOptimalPacketsForItemsV1: Expected: %v \nGot: %v", testCase.ExpectedOptimalPacks, result)
 			}
 		})
@@ -90,13 +99,259 @@ func Test_CalculateOptimalPacketsForItems(t *testing.T) {
 		t.Run(testCase.Name, func(t *testing.T) {
 			t.Parallel()
 
-			result := packer.CalculateOptimalPacketsForItemsV2(&packer.CalculateOptimalPacketsForItemsParams{
+			result, err := packer.CalculateOptimalPacketsForItemsV2(&packer.CalculateOptimalPacketsForItemsParams{
 				Items:       testCase.Items,
 				PacketSizes: testCase.PacketSizes,
 			})
-			if !reflect.DeepEqual(result, testCase.ExpectedOptimalPacks) {
+			if err != nil {
+				t.Fatalf("CalculateOptimalPacketsForItemsV2: %v", err)
+			}
+			if !reflect.DeepEqual(map[types.PacketSize]types.PacketQuantity(result), testCase.ExpectedOptimalPacks) {
 				t.Errorf("CalculateOptimalPacketsForItemsV2: Expected: %v \nGot: %v", testCase.ExpectedOptimalPacks, result)
 			}
 		})
 	}
 }
+
+func Test_CalculateOptimalPacketsForItems_Inputs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expectedErr    error
+		expectedResult packer.Result
+		name           string
+		sizes          []types.PacketSize
+		constraints    []types.PacketConstraint
+		items          int
+	}{
+		{
+			name:           "unsorted sizes",
+			items:          12001,
+			sizes:          []types.PacketSize{5000, 250, 2000, 500, 1000},
+			expectedResult: packer.Result{5000: 2, 2000: 1, 250: 1},
+		},
+		{
+			name:           "duplicate sizes",
+			items:          501,
+			sizes:          []types.PacketSize{500, 250, 500, 250},
+			expectedResult: packer.Result{500: 1, 250: 1},
+		},
+		{
+			name:           "zero items",
+			items:          0,
+			sizes:          []types.PacketSize{250, 500},
+			expectedResult: packer.Result{},
+		},
+		{name: "no sizes", items: 10, expectedErr: packer.ErrNoPacketSizes},
+		{name: "non-positive size", items: 10, sizes: []types.PacketSize{250, 0}, expectedErr: validation.ErrNonPositiveSize},
+		{name: "negative items", items: -1, sizes: []types.PacketSize{250}, expectedErr: packer.ErrNegativeItems},
+		{name: "overflow", items: math.MaxInt - 10, sizes: []types.PacketSize{250}, expectedErr: packer.ErrItemsOverflow},
+		{name: "search too large", items: 1_000_000_000_000_000_000, sizes: []types.PacketSize{250}, expectedErr: packer.ErrTableTooLarge},
+		{
+			name:        "constrained search too large",
+			items:       packer.MaxSearchTotal - 1000,
+			sizes:       []types.PacketSize{250, 500},
+			constraints: []types.PacketConstraint{{Size: 500, MinQuantity: 1000}},
+			expectedErr: packer.ErrTableTooLarge,
+		},
+	}
+
+	solvers := map[string]func(*packer.CalculateOptimalPacketsForItemsParams) (packer.Result, error){
+		"V1":       packer.CalculateOptimalPacketsForItemsV1,
+		"V2":       packer.CalculateOptimalPacketsForItemsV2,
+		"Parallel": packer.CalculateOptimalPacketsForItemsParallel,
+	}
+
+	for solverName, solve := range solvers {
+		for _, tt := range tests {
+			t.Run(solverName+"/"+tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				sizes := append([]types.PacketSize(nil), tt.sizes...)
+				result, err := solve(&packer.CalculateOptimalPacketsForItemsParams{
+					Items:       tt.items,
+					PacketSizes: sizes,
+					Constraints: tt.constraints,
+				})
+				if tt.expectedErr != nil {
+					require.ErrorIs(t, err, tt.expectedErr)
+
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedResult, result)
+				require.Equal(t, tt.sizes, sizes, "the caller's sizes are not modified")
+			})
+		}
+	}
+}
+
+func Test_CalculateOptimalPacketsForItems_Constraints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expectedResult packer.Result
+		name           string
+		sizes          []types.PacketSize
+		constraints    []types.PacketConstraint
+		items          int
+	}{
+		{
+			name:           "pairs that fit",
+			items:          12001,
+			sizes:          []types.PacketSize{250, 500, 1000, 2000, 5000},
+			constraints:    []types.PacketConstraint{{Size: 5000, Step: 2}},
+			expectedResult: packer.Result{5000: 2, 2000: 1, 250: 1},
+		},
+		{
+			name:           "pairs that do not fit",
+			items:          5001,
+			sizes:          []types.PacketSize{250, 500, 1000, 2000, 5000},
+			constraints:    []types.PacketConstraint{{Size: 5000, Step: 2}},
+			expectedResult: packer.Result{2000: 2, 1000: 1, 250: 1},
+		},
+		{
+			name:           "minimum quantity",
+			items:          500,
+			sizes:          []types.PacketSize{23, 31, 53},
+			constraints:    []types.PacketConstraint{{Size: 53, MinQuantity: 10}},
+			expectedResult: packer.Result{31: 5, 23: 15},
+		},
+		{
+			name:           "minimum quantity in steps below",
+			items:          3000,
+			sizes:          []types.PacketSize{250, 1000},
+			constraints:    []types.PacketConstraint{{Size: 1000, MinQuantity: 3, Step: 2}},
+			expectedResult: packer.Result{250: 12},
+		},
+		{
+			name:           "minimum quantity in steps",
+			items:          5100,
+			sizes:          []types.PacketSize{250, 1000},
+			constraints:    []types.PacketConstraint{{Size: 1000, MinQuantity: 3, Step: 2}},
+			expectedResult: packer.Result{1000: 4, 250: 5},
+		},
+		{
+			name:           "constraint of another size",
+			items:          501,
+			sizes:          []types.PacketSize{250, 500},
+			constraints:    []types.PacketConstraint{{Size: 1000, Step: 2}},
+			expectedResult: packer.Result{500: 1, 250: 1},
+		},
+	}
+
+	solvers := map[string]func(*packer.CalculateOptimalPacketsForItemsParams) (packer.Result, error){
+		"V1":       packer.CalculateOptimalPacketsForItemsV1,
+		"V2":       packer.CalculateOptimalPacketsForItemsV2,
+		"Parallel": packer.CalculateOptimalPacketsForItemsParallel,
+	}
+
+	for solverName, solve := range solvers {
+		for _, tt := range tests {
+			t.Run(solverName+"/"+tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				result, err := solve(&packer.CalculateOptimalPacketsForItemsParams{
+					Items:       tt.items,
+					PacketSizes: tt.sizes,
+					Constraints: tt.constraints,
+				})
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedResult, result)
+			})
+		}
+	}
+}
+
+func Test_CalculateOptimalPacketsForItems_ConstraintsAreOptimal(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewPCG(48, 2026)) //nolint:gosec // Reproducible test inputs.
+	for range 200 {
+		sizes := make([]types.PacketSize, 1+random.IntN(3))
+		constraints := make([]types.PacketConstraint, 0, len(sizes))
+		for i := range sizes {
+			sizes[i] = types.PacketSize(3 + random.IntN(40))
+			if random.IntN(2) == 0 {
+				constraints = append(constraints, types.PacketConstraint{
+					Size:        sizes[i],
+					MinQuantity: types.PacketQuantity(random.IntN(5)),
+					Step:        types.PacketQuantity(random.IntN(4)),
+				})
+			}
+		}
+		params := &packer.CalculateOptimalPacketsForItemsParams{
+			Items:       random.IntN(400),
+			PacketSizes: sizes,
+			Constraints: constraints,
+		}
+
+		v1, err := packer.CalculateOptimalPacketsForItemsV1(params)
+		require.NoError(t, err)
+		parallel, err := packer.CalculateOptimalPacketsForItemsParallel(params)
+		require.NoError(t, err)
+		require.Equal(t, v1, parallel, "%+v", params)
+		v2, err := packer.CalculateOptimalPacketsForItemsV2(params)
+		require.NoError(t, err)
+		require.Equal(t, v1.TotalItems(), v2.TotalItems(), "%+v", params)
+		require.Equal(t, v1.Packs(), v2.Packs(), "%+v", params)
+
+		for _, constraint := range constraints {
+			require.True(t, constraint.Allows(v1[constraint.Size]), "%+v: %v", params, v1)
+		}
+		total, packs := constrainedOptimum(params)
+		require.Equal(t, total, v1.TotalItems(), "%+v: %v", params, v1)
+		require.Equal(t, packs, v1.Packs(), "%+v: %v", params, v1)
+	}
+}
+
+// constrainedOptimum returns the smallest total of at least the items and its fewest packets by trying every allowed
+// quantity of every size, independently of how the solvers expand the constraints.
+func constrainedOptimum(params *packer.CalculateOptimalPacketsForItemsParams) (int, int) {
+	constraintOf := make(map[types.PacketSize]types.PacketConstraint)
+	for _, constraint := range params.Constraints {
+		constraintOf[constraint.Size] = constraint
+	}
+
+	// Batches of the smallest allowed quantity reach every interval of their weight; quantities here are below 16.
+	bound := params.Items + 16*int(slices.Max(params.PacketSizes))
+	packs := make([]int, bound+1)
+	for total := range packs {
+		packs[total] = math.MaxInt
+	}
+	packs[0] = 0
+
+	for _, size := range slices.Compact(slices.Sorted(slices.Values(params.PacketSizes))) {
+		next := slices.Clone(packs)
+		for total := range packs {
+			for quantity := 1; quantity*int(size) <= total; quantity++ {
+				previous := packs[total-quantity*int(size)]
+				if previous != math.MaxInt && constraintOf[size].Allows(types.PacketQuantity(quantity)) {
+					next[total] = min(next[total], previous+quantity)
+				}
+			}
+		}
+		packs = next
+	}
+
+	for total := params.Items; ; total++ {
+		if packs[total] != math.MaxInt {
+			return total, packs[total]
+		}
+	}
+}
+
+func TestSolverWork(t *testing.T) {
+	t.Parallel()
+
+	sizes := []types.PacketSize{5000, 250, 500, 1000, 2000}
+
+	require.Equal(t, int64(12001+5000), packer.SolverWork(12001, sizes, types.PacketSizeDetails{}))
+	require.Equal(t, int64(7), packer.SolverWork(7, nil, types.PacketSizeDetails{}))
+
+	constrained := types.PacketSizeDetails{Constraints: []types.PacketConstraint{{Size: 5000, MinQuantity: 1000}}}
+	require.Equal(t, int64(12001+1000*5000)*(4+1000)/5, packer.SolverWork(12001, sizes, constrained),
+		"a constrained size searches further and expands into batches")
+
+	require.Equal(t, int64(math.MaxInt64), packer.SolverWork(math.MaxInt-10, sizes, constrained))
+}
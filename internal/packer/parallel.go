@@ -0,0 +1,143 @@
+package packer
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+const (
+	// parallelMinTotals is the smallest table worth spreading over goroutines.
+	parallelMinTotals = 1 << 16
+	// parallelMinChunk is the fewest residues a goroutine handles in a pass.
+	parallelMinChunk = 32
+)
+
+// CalculateOptimalPacketsForItemsParallel finds the same packing as CalculateOptimalPacketsForItemsV1, spreading the
+// dynamic programming over params.Workers goroutines (GOMAXPROCS when zero). Constrained sizes are solved as batches
+// of packets, see packBatches.
+//
+// V1 computes every total from all sizes at once, so each total depends on the one just computed. Instead, the
+// fewest packets are computed one batch after another: within the pass of a batch, a total only depends on the total
+// one batch weight below it, i.e. on its own residue modulo the weight. Each goroutine takes a contiguous block of
+// residues and walks the table in strides of the weight, so it reads and writes contiguous memory and never waits for
+// the others until the pass ends. A last pass, split into contiguous ranges of totals, records for every total the
+// first batch that reaches it with the fewest packets, which is the choice V1 makes, so the packings are identical.
+func CalculateOptimalPacketsForItemsParallel(params *CalculateOptimalPacketsForItemsParams) (Result, error) {
+	packetSizes, err := normalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.Items == 0 {
+		return make(Result), nil
+	}
+
+	batches, searchAbove, err := packBatches(params.Items, packetSizes, params.Constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSum, err := searchTotal(params.Items, searchAbove)
+	if err != nil {
+		return nil, err
+	}
+	if maxSum >= unreachable || len(batches) > math.MaxUint16 {
+		return CalculateOptimalPacketsForItemsV1(params)
+	}
+
+	packs := make([]uint32, maxSum+1)
+	batchIndex := make([]uint16, maxSum+1)
+	fillPacksParallel(packs, batchIndex, batches, parallelWorkers(params.Workers, maxSum))
+
+	// Multiples of a batch of the largest size always lie in [items, items + its weight), so a total is found.
+	bestSum := -1
+	for s := params.Items; s <= maxSum; s++ {
+		if packs[s] != unreachable {
+			bestSum = s
+
+			break
+		}
+	}
+	if bestSum < 0 {
+		return nil, ErrNoPacking
+	}
+
+	result := make(Result)
+	for cur := bestSum; cur > 0; cur -= batches[batchIndex[cur]].weight {
+		batch := batches[batchIndex[cur]]
+		result[batch.size] += batch.quantity
+	}
+
+	return result, nil
+}
+
+// parallelWorkers returns how many goroutines fill a table of maxSum totals.
+func parallelWorkers(requested, maxSum int) int {
+	if maxSum < parallelMinTotals {
+		return 1
+	}
+	if requested <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return requested
+}
+
+// fillPacksParallel computes, for every total, the fewest packets that ship it and the first batch that reaches it
+// with them, as CalculateOptimalPacketsForItemsV1 does.
+func fillPacksParallel(packs []uint32, batchIndex []uint16, batches []packBatch, workers int) {
+	end := len(packs)
+
+	inParallel(workers, 1, end, func(from, to int) {
+		for s := from; s < to; s++ {
+			packs[s] = unreachable
+		}
+	})
+
+	for _, batch := range batches {
+		weight, quantity := batch.weight, uint32(batch.quantity) //nolint:gosec // Quantities are bounded by validation.
+		// Residues [first, last) of the weight: the totals base + r for every multiple base of the weight.
+		inParallel(min(workers, max(1, weight/parallelMinChunk)), 0, min(weight, end), func(first, last int) {
+			for base := weight; base < end; base += weight {
+				for s := base + first; s < min(base+last, end); s++ {
+					if previous := packs[s-weight]; previous != unreachable && previous+quantity < packs[s] {
+						packs[s] = previous + quantity
+					}
+				}
+			}
+		})
+	}
+
+	inParallel(workers, 1, end, func(from, to int) {
+		for s := from; s < to; s++ {
+			if packs[s] == unreachable {
+				continue
+			}
+			for index, batch := range batches {
+				if s >= batch.weight && packs[s-batch.weight] != unreachable &&
+					packs[s-batch.weight]+uint32(batch.quantity) == packs[s] { //nolint:gosec // As above.
+					batchIndex[s] = uint16(index) //nolint:gosec // The caller checks the number of batches.
+
+					break
+				}
+			}
+		}
+	})
+}
+
+// inParallel splits [from, to) into workers contiguous ranges and runs fn on each in its own goroutine.
+func inParallel(workers, from, to int, fn func(from, to int)) {
+	if workers <= 1 {
+		fn(from, to)
+
+		return
+	}
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Go(func() {
+			fn(from+worker*(to-from)/workers, from+(worker+1)*(to-from)/workers)
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package packer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/internal/types"
+)
+
+func TestCalculateOptimalPacketsForItemsParallel_MatchesV1(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sizes []types.PacketSize
+		items []int
+	}{
+		{sizes: []types.PacketSize{250, 500, 1000, 2000, 5000}, items: []int{1, 12001, 70_001, 500_123}},
+		{sizes: []types.PacketSize{251, 503, 997, 2003, 4999}, items: []int{249, 65_537, 250_123}},
+		{sizes: []types.PacketSize{23, 31, 53}, items: []int{263, 100_000, 100_001}},
+		{sizes: []types.PacketSize{5000, 64, 6400, 64}, items: []int{70_000, 199_999}},
+		{sizes: []types.PacketSize{1}, items: []int{0, 80_000}},
+	}
+
+	for _, tt := range tests {
+		for _, items := range tt.items {
+			for _, workers := range []int{0, 1, 3, 8} {
+				t.Run(fmt.Sprintf("%v/%d/%d", tt.sizes, items, workers), func(t *testing.T) {
+					t.Parallel()
+
+					params := &packer.CalculateOptimalPacketsForItemsParams{
+						PacketSizes: tt.sizes,
+						Items:       items,
+						Workers:     workers,
+					}
+					expected, err := packer.CalculateOptimalPacketsForItemsV1(params)
+					require.NoError(t, err)
+
+					actual, err := packer.CalculateOptimalPacketsForItemsParallel(params)
+					require.NoError(t, err)
+					require.Equal(t, expected, actual)
+				})
+			}
+		}
+	}
+}
+
+func TestCalculateOptimalPacketsForItemsParallel_Inputs(t *testing.T) {
+	t.Parallel()
+
+	_, err := packer.CalculateOptimalPacketsForItemsParallel(&packer.CalculateOptimalPacketsForItemsParams{Items: 1})
+	require.ErrorIs(t, err, packer.ErrNoPacketSizes)
+
+	_, err = packer.CalculateOptimalPacketsForItemsParallel(&packer.CalculateOptimalPacketsForItemsParams{
+		Items:       -1,
+		PacketSizes: []types.PacketSize{1},
+	})
+	require.ErrorIs(t, err, packer.ErrNegativeItems)
+}
@@ -1,6 +1,7 @@
 package responder
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/goccy/go-json"
@@ -23,3 +24,31 @@ func WriteSuccess[T any](w http.ResponseWriter, status int, message string, data
 func WriteError(w http.ResponseWriter, status int, err error) {
 	WriteJSON(w, status, types.NewErrorResponse[string](err.Error()))
 }
+
+func WriteErrorWithData[T any](w http.ResponseWriter, status int, err error, data T) {
+	response := types.NewErrorResponse[T](err.Error())
+	response.Data = data
+	WriteJSON(w, status, response)
+}
+
+// ErrorWriter writes an error response. Route sets with their own error format install one with WithErrorWriter.
+type ErrorWriter func(w http.ResponseWriter, status int, err error)
+
+type errorWriterContextKey struct{}
+
+// WithErrorWriter returns r with the error writer used by WriteRequestError.
+func WithErrorWriter(r *http.Request, writer ErrorWriter) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), errorWriterContextKey{}, writer))
+}
+
+// WriteRequestError writes err with the error writer of the request, or with WriteError when it has none.
+// Middleware shared by several route sets uses it so that each set keeps its error format.
+func WriteRequestError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if writer, ok := r.Context().Value(errorWriterContextKey{}).(ErrorWriter); ok {
+		writer(w, status, err)
+
+		return
+	}
+
+	WriteError(w, status, err)
+}
@@ -0,0 +1,409 @@
+// Package shipment plans how the packs of a packing are loaded into containers such as pallets.
+package shipment
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/dsha256/packer/internal/types"
+)
+
+var (
+	ErrNoPacks           = errors.New("at least one pack is required")
+	ErrInvalidPackLine   = errors.New("pack lines need a positive size and quantity")
+	ErrInvalidPackSpec   = errors.New("pack specs need a unique size and no negative weight or dimension")
+	ErrMissingPackSpec   = errors.New("no pack spec for size")
+	ErrInvalidContainer  = errors.New("container needs a positive max_weight, max_volume or max_packs and none negative")
+	ErrPackTooLarge      = errors.New("pack does not fit in an empty container")
+	ErrTooManyPacks      = errors.New("number of packs exceeds maximum allowed value")
+	ErrTooManyContainers = errors.New("number of containers exceeds maximum allowed value")
+	ErrUnknownMode       = errors.New("unknown mode, use auto, first-fit-decreasing or exact")
+)
+
+const (
+	// MaxPacks bounds the packs of a plan.
+	MaxPacks = 1_000_000
+	// MaxContainers bounds the containers of a plan.
+	MaxContainers = 10_000
+	// ExactMaxPacks bounds the packs planned by ModeExact, whose search grows exponentially with them.
+	ExactMaxPacks = 20
+
+	// tolerance absorbs the rounding of summed weights and volumes when checking capacities.
+	tolerance = 1e-9
+)
+
+// Mode selects how packs are assigned to containers.
+type Mode string
+
+const (
+	// ModeAuto plans with ModeExact up to ExactMaxPacks packs and with ModeFirstFitDecreasing above.
+	ModeAuto Mode = "auto"
+	// ModeFirstFitDecreasing loads the packs, most demanding first, into the first container with room left.
+	ModeFirstFitDecreasing Mode = "first-fit-decreasing"
+	// ModeExact searches for the fewest containers, starting from the first-fit-decreasing plan.
+	ModeExact Mode = "exact"
+)
+
+// PackSpec is the weight and outer dimensions of the pack of a packet size. Only the volume of the dimensions is
+// compared with the container, the packs are not arranged geometrically.
+type PackSpec struct {
+	Size       types.PacketSize `json:"size"`
+	Weight     float64          `json:"weight"`
+	Dimensions types.Dimensions `json:"dimensions"`
+}
+
+// Container is the capacity of each container of a plan. Zero limits are unbounded, but at least one is set.
+type Container struct {
+	MaxWeight float64 `json:"max_weight"`
+	MaxVolume float64 `json:"max_volume"`
+	MaxPacks  int     `json:"max_packs"`
+}
+
+// Load is the content of one container. Lines are ordered by size, largest first.
+type Load struct {
+	Lines     []types.PackingLine `json:"lines"`
+	Weight    float64             `json:"weight"`
+	Volume    float64             `json:"volume"`
+	PackCount int                 `json:"pack_count"`
+}
+
+// Plan assigns every pack to a container.
+type Plan struct {
+	// Mode is the mode that made the plan, never ModeAuto.
+	Mode       Mode   `json:"mode"`
+	Containers []Load `json:"containers"`
+	// LowerBound is the fewest containers the total weight, volume and pack count could fit in.
+	LowerBound  int     `json:"lower_bound"`
+	TotalWeight float64 `json:"total_weight"`
+	TotalVolume float64 `json:"total_volume"`
+	// Optimal reports that no plan uses fewer containers, because the plan reaches LowerBound or the exact search
+	// completed.
+	Optimal bool `json:"optimal"`
+}
+
+// packGroup is the packs of one size.
+type packGroup struct {
+	size     types.PacketSize
+	weight   float64
+	volume   float64
+	quantity int
+}
+
+// bin is a container being loaded, with the quantity of each pack group in it.
+type bin struct {
+	quantities []int
+	weight     float64
+	volume     float64
+	packs      int
+}
+
+// NewPlan loads the packs of lines, described by specs, into containers. Lines of the same size are added up.
+func NewPlan(lines []types.PackingLine, specs []PackSpec, container Container, mode Mode) (Plan, error) {
+	if mode == "" {
+		mode = ModeAuto
+	}
+	if mode != ModeAuto && mode != ModeFirstFitDecreasing && mode != ModeExact {
+		return Plan{}, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+	if err := container.validate(); err != nil {
+		return Plan{}, err
+	}
+
+	groups, packs, err := newPackGroups(lines, specs)
+	if err != nil {
+		return Plan{}, err
+	}
+	for _, group := range groups {
+		if container.room(&bin{}, &group) == 0 {
+			return Plan{}, fmt.Errorf("%w: size %d", ErrPackTooLarge, group.size)
+		}
+	}
+
+	if mode == ModeAuto {
+		mode = ModeFirstFitDecreasing
+		if packs <= ExactMaxPacks {
+			mode = ModeExact
+		}
+	}
+	if mode == ModeExact && packs > ExactMaxPacks {
+		return Plan{}, fmt.Errorf("%w of %d for the exact mode", ErrTooManyPacks, ExactMaxPacks)
+	}
+
+	// Most demanding packs first, as first-fit-decreasing needs, and the exact search benefits from.
+	slices.SortStableFunc(groups, func(a, b packGroup) int {
+		return cmp.Or(cmp.Compare(container.demand(&b), container.demand(&a)), cmp.Compare(b.size, a.size))
+	})
+
+	plan := Plan{Mode: mode}
+	for _, group := range groups {
+		plan.TotalWeight += group.weight * float64(group.quantity)
+		plan.TotalVolume += group.volume * float64(group.quantity)
+	}
+	plan.LowerBound = container.lowerBound(plan.TotalWeight, plan.TotalVolume, packs)
+
+	bins, err := firstFitDecreasing(container, groups)
+	if err != nil {
+		return Plan{}, err
+	}
+	complete := false
+	if mode == ModeExact && len(bins) > plan.LowerBound {
+		var better []bin
+		if better, complete = exactSearch(container, groups, len(bins), plan.LowerBound); better != nil {
+			bins = better
+		}
+	}
+
+	plan.Containers = loads(groups, bins)
+	plan.Optimal = complete || len(bins) == plan.LowerBound
+
+	return plan, nil
+}
+
+// newPackGroups groups the packs of lines by size and attaches their specs. It returns the number of packs too.
+func newPackGroups(lines []types.PackingLine, specs []PackSpec) ([]packGroup, int, error) {
+	specOf := make(map[types.PacketSize]PackSpec, len(specs))
+	for _, spec := range specs {
+		_, duplicate := specOf[spec.Size]
+		if duplicate || spec.Size < 1 || spec.Weight < 0 || spec.Dimensions.Length < 0 || spec.Dimensions.Width < 0 ||
+			spec.Dimensions.Height < 0 {
+			return nil, 0, fmt.Errorf("%w: size %d", ErrInvalidPackSpec, spec.Size)
+		}
+		specOf[spec.Size] = spec
+	}
+
+	groups := make([]packGroup, 0, len(lines))
+	indexOf := make(map[types.PacketSize]int, len(lines))
+	packs := 0
+	for _, line := range lines {
+		if line.Size < 1 || line.Quantity < 1 {
+			return nil, 0, fmt.Errorf("%w: %d packs of size %d", ErrInvalidPackLine, line.Quantity, line.Size)
+		}
+		// Compared before adding, so that a huge quantity cannot overflow the count.
+		if line.Quantity > types.PacketQuantity(MaxPacks-packs) {
+			return nil, 0, fmt.Errorf("%w of %d", ErrTooManyPacks, MaxPacks)
+		}
+		packs += int(line.Quantity)
+
+		if index, ok := indexOf[line.Size]; ok {
+			groups[index].quantity += int(line.Quantity)
+
+			continue
+		}
+		spec, ok := specOf[line.Size]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w %d", ErrMissingPackSpec, line.Size)
+		}
+		indexOf[line.Size] = len(groups)
+		groups = append(groups, packGroup{
+			size:     line.Size,
+			weight:   spec.Weight,
+			volume:   spec.Dimensions.Volume(),
+			quantity: int(line.Quantity),
+		})
+	}
+	if packs == 0 {
+		return nil, 0, ErrNoPacks
+	}
+
+	return groups, packs, nil
+}
+
+func (container Container) validate() error {
+	if container.MaxWeight < 0 || container.MaxVolume < 0 || container.MaxPacks < 0 ||
+		container.MaxWeight == 0 && container.MaxVolume == 0 && container.MaxPacks == 0 {
+		return ErrInvalidContainer
+	}
+
+	return nil
+}
+
+// room returns how many more packs of the group fit in the bin.
+func (container Container) room(load *bin, group *packGroup) int {
+	room := math.MaxInt
+	fit := func(capacity, used, each float64) {
+		if capacity > 0 && each > 0 {
+			room = min(room, int(math.Max(math.Floor((capacity-used)/each+tolerance), 0)))
+		}
+	}
+	fit(container.MaxWeight, load.weight, group.weight)
+	fit(container.MaxVolume, load.volume, group.volume)
+	if container.MaxPacks > 0 {
+		room = min(room, container.MaxPacks-load.packs)
+	}
+
+	return max(room, 0)
+}
+
+// demand returns the largest share of a container a pack of the group takes in any bounded dimension.
+func (container Container) demand(group *packGroup) float64 {
+	demand := 0.0
+	if container.MaxWeight > 0 {
+		demand = max(demand, group.weight/container.MaxWeight)
+	}
+	if container.MaxVolume > 0 {
+		demand = max(demand, group.volume/container.MaxVolume)
+	}
+	if container.MaxPacks > 0 {
+		demand = max(demand, 1/float64(container.MaxPacks))
+	}
+
+	return demand
+}
+
+// lowerBound returns the fewest containers the totals fit in, ignoring how they split into packs.
+func (container Container) lowerBound(weight, volume float64, packs int) int {
+	bound := 1
+	if container.MaxWeight > 0 {
+		bound = max(bound, int(math.Ceil(weight/container.MaxWeight-tolerance)))
+	}
+	if container.MaxVolume > 0 {
+		bound = max(bound, int(math.Ceil(volume/container.MaxVolume-tolerance)))
+	}
+	if container.MaxPacks > 0 {
+		bound = max(bound, (packs+container.MaxPacks-1)/container.MaxPacks)
+	}
+
+	return bound
+}
+
+// add puts quantity packs of group index into the bin; a negative quantity takes them out.
+func (load *bin) add(groups []packGroup, index, quantity int) {
+	load.quantities[index] += quantity
+	load.weight += groups[index].weight * float64(quantity)
+	load.volume += groups[index].volume * float64(quantity)
+	load.packs += quantity
+}
+
+// loads describes the bins.
+func loads(groups []packGroup, bins []bin) []Load {
+	result := make([]Load, 0, len(bins))
+	for _, load := range bins {
+		packets := make(map[types.PacketSize]types.PacketQuantity, len(groups))
+		for index, quantity := range load.quantities {
+			if quantity > 0 {
+				packets[groups[index].size] = types.PacketQuantity(quantity)
+			}
+		}
+		lines := types.NewPackingResult(0, packets).Lines
+		result = append(result, Load{Lines: lines, Weight: load.weight, Volume: load.volume, PackCount: load.packs})
+	}
+
+	return result
+}
+
+// firstFitDecreasing loads the groups, in order, into the first bins with room left, opening bins as needed. As the
+// packs of a group are identical, filling each bin with as many as fit gives the plan of loading them one by one.
+func firstFitDecreasing(container Container, groups []packGroup) ([]bin, error) {
+	var bins []bin
+	for index := range groups {
+		group := &groups[index]
+		remaining := group.quantity
+		for b := 0; b < len(bins) && remaining > 0; b++ {
+			quantity := min(container.room(&bins[b], group), remaining)
+			bins[b].add(groups, index, quantity)
+			remaining -= quantity
+		}
+		for remaining > 0 {
+			if len(bins) == MaxContainers {
+				return nil, fmt.Errorf("%w of %d", ErrTooManyContainers, MaxContainers)
+			}
+			bins = append(bins, bin{quantities: make([]int, len(groups))})
+			quantity := min(container.room(&bins[len(bins)-1], group), remaining)
+			bins[len(bins)-1].add(groups, index, quantity)
+			remaining -= quantity
+		}
+	}
+
+	return bins, nil
+}
+
+// exactNodeBudget bounds the branches of the exact search, which keeps its best plan when it runs out.
+const exactNodeBudget = 2_000_000
+
+// search is the state of the exact search.
+type search struct {
+	container Container
+	groups    []packGroup
+	// packs holds the group index of every pack, identical packs next to each other.
+	packs      []int
+	assignment []int
+	bins       []bin
+	best       []bin
+	lowerBound int
+	nodes      int
+}
+
+// exactSearch looks for a plan with fewer than upperBound bins by branch and bound. It returns the best plan found,
+// nil when none has fewer bins, and whether that is proven optimal.
+func exactSearch(container Container, groups []packGroup, upperBound, lowerBound int) ([]bin, bool) {
+	state := &search{container: container, groups: groups, lowerBound: lowerBound}
+	for index, group := range groups {
+		for range group.quantity {
+			state.packs = append(state.packs, index)
+		}
+	}
+	state.assignment = make([]int, len(state.packs))
+
+	complete := !state.place(0, upperBound)
+
+	return state.best, complete || len(state.best) == lowerBound
+}
+
+// place assigns the packs from pack on, recording plans with fewer than limit bins. It returns true when the search
+// stops early, because the budget ran out or a plan reached the lower bound.
+func (state *search) place(pack, limit int) bool {
+	if state.nodes++; state.nodes > exactNodeBudget {
+		return true
+	}
+	if state.best != nil {
+		limit = len(state.best)
+	}
+	if pack == len(state.packs) {
+		state.best = make([]bin, len(state.bins))
+		for b, load := range state.bins {
+			state.best[b] = load
+			state.best[b].quantities = slices.Clone(load.quantities)
+		}
+
+		return len(state.best) == state.lowerBound
+	}
+
+	index := state.packs[pack]
+	group := &state.groups[index]
+	// An identical pack goes in the same bin as the previous one or a later one, skipping mirrored plans.
+	first := 0
+	if pack > 0 && state.packs[pack-1] == index {
+		first = state.assignment[pack-1]
+	}
+	for b := first; b < len(state.bins); b++ {
+		if state.container.room(&state.bins[b], group) == 0 {
+			continue
+		}
+		state.bins[b].add(state.groups, index, 1)
+		state.assignment[pack] = b
+		stop := state.place(pack+1, limit)
+		state.bins[b].add(state.groups, index, -1)
+		if stop {
+			return true
+		}
+		if state.best != nil {
+			limit = len(state.best)
+		}
+	}
+
+	if len(state.bins)+1 < limit {
+		state.bins = append(state.bins, bin{quantities: make([]int, len(state.groups))})
+		state.bins[len(state.bins)-1].add(state.groups, index, 1)
+		state.assignment[pack] = len(state.bins) - 1
+		stop := state.place(pack+1, limit)
+		state.bins = state.bins[:len(state.bins)-1]
+		if stop {
+			return true
+		}
+	}
+
+	return false
+}
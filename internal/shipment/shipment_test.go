@@ -0,0 +1,288 @@
+package shipment_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/shipment"
+	"github.com/dsha256/packer/internal/types"
+)
+
+var cube = types.Dimensions{Length: 1, Width: 1, Height: 1}
+
+func TestNewPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mode       shipment.Mode
+		lines      []types.PackingLine
+		specs      []shipment.PackSpec
+		expected   [][]types.PackingLine
+		container  shipment.Container
+		lowerBound int
+		optimal    bool
+	}{
+		{
+			name:       "fills containers by count",
+			mode:       shipment.ModeFirstFitDecreasing,
+			lines:      []types.PackingLine{{Size: 500, Quantity: 5}},
+			specs:      []shipment.PackSpec{{Size: 500, Weight: 10, Dimensions: cube}},
+			container:  shipment.Container{MaxPacks: 2},
+			expected:   [][]types.PackingLine{{{Size: 500, Quantity: 2}}, {{Size: 500, Quantity: 2}}, {{Size: 500, Quantity: 1}}},
+			lowerBound: 3,
+			optimal:    true,
+		},
+		{
+			name: "loads the heaviest packs first",
+			mode: shipment.ModeFirstFitDecreasing,
+			lines: []types.PackingLine{
+				{Size: 250, Quantity: 3},
+				{Size: 1000, Quantity: 2},
+			},
+			specs: []shipment.PackSpec{
+				{Size: 250, Weight: 20, Dimensions: cube},
+				{Size: 1000, Weight: 60, Dimensions: cube},
+			},
+			container: shipment.Container{MaxWeight: 100},
+			expected: [][]types.PackingLine{
+				{{Size: 1000, Quantity: 1}, {Size: 250, Quantity: 2}},
+				{{Size: 1000, Quantity: 1}, {Size: 250, Quantity: 1}},
+			},
+			lowerBound: 2,
+			optimal:    true,
+		},
+		{
+			name: "exact beats first-fit-decreasing",
+			mode: shipment.ModeExact,
+			// First-fit-decreasing loads 5+5, 4+4 and 3+3+3, leaving a 3 for a fourth container.
+			lines: []types.PackingLine{
+				{Size: 5, Quantity: 2},
+				{Size: 4, Quantity: 2},
+				{Size: 3, Quantity: 4},
+			},
+			specs: []shipment.PackSpec{
+				{Size: 5, Weight: 5},
+				{Size: 4, Weight: 4},
+				{Size: 3, Weight: 3},
+			},
+			container: shipment.Container{MaxWeight: 10},
+			expected: [][]types.PackingLine{
+				{{Size: 5, Quantity: 2}},
+				{{Size: 4, Quantity: 1}, {Size: 3, Quantity: 2}},
+				{{Size: 4, Quantity: 1}, {Size: 3, Quantity: 2}},
+			},
+			lowerBound: 3,
+			optimal:    true,
+		},
+		{
+			name: "exact proves the lower bound unreachable",
+			mode: shipment.ModeAuto,
+			lines: []types.PackingLine{
+				{Size: 6, Quantity: 3},
+			},
+			specs:     []shipment.PackSpec{{Size: 6, Dimensions: types.Dimensions{Length: 6, Width: 1, Height: 1}}},
+			container: shipment.Container{MaxVolume: 10},
+			expected: [][]types.PackingLine{
+				{{Size: 6, Quantity: 1}},
+				{{Size: 6, Quantity: 1}},
+				{{Size: 6, Quantity: 1}},
+			},
+			lowerBound: 2,
+			optimal:    true,
+		},
+		{
+			name: "first-fit-decreasing is not proven optimal above the lower bound",
+			mode: shipment.ModeFirstFitDecreasing,
+			lines: []types.PackingLine{
+				{Size: 6, Quantity: 3},
+			},
+			specs:     []shipment.PackSpec{{Size: 6, Dimensions: types.Dimensions{Length: 6, Width: 1, Height: 1}}},
+			container: shipment.Container{MaxVolume: 10},
+			expected: [][]types.PackingLine{
+				{{Size: 6, Quantity: 1}},
+				{{Size: 6, Quantity: 1}},
+				{{Size: 6, Quantity: 1}},
+			},
+			lowerBound: 2,
+		},
+		{
+			name: "adds up lines of the same size",
+			mode: shipment.ModeAuto,
+			lines: []types.PackingLine{
+				{Size: 250, Quantity: 1},
+				{Size: 250, Quantity: 2},
+			},
+			specs:      []shipment.PackSpec{{Size: 250, Weight: 0.1, Dimensions: cube}},
+			container:  shipment.Container{MaxWeight: 0.3},
+			expected:   [][]types.PackingLine{{{Size: 250, Quantity: 3}}},
+			lowerBound: 1,
+			optimal:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := shipment.NewPlan(tt.lines, tt.specs, tt.container, tt.mode)
+			require.NoError(t, err)
+
+			containers := make([][]types.PackingLine, 0, len(plan.Containers))
+			packs := 0
+			for _, load := range plan.Containers {
+				containers = append(containers, load.Lines)
+				packs += load.PackCount
+			}
+			require.ElementsMatch(t, tt.expected, containers)
+			require.Equal(t, tt.lowerBound, plan.LowerBound)
+			require.Equal(t, tt.optimal, plan.Optimal)
+			require.NotEqual(t, shipment.ModeAuto, plan.Mode)
+
+			expectedPacks := 0
+			for _, line := range tt.lines {
+				expectedPacks += int(line.Quantity)
+			}
+			require.Equal(t, expectedPacks, packs)
+		})
+	}
+}
+
+func TestNewPlan_ExactIsOptimal(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewPCG(49, 2026)) //nolint:gosec // Reproducible test inputs.
+	container := shipment.Container{MaxWeight: 100}
+	for range 200 {
+		var (
+			lines   []types.PackingLine
+			specs   []shipment.PackSpec
+			weights []float64
+		)
+		for size := 1; size <= 1+random.IntN(4); size++ {
+			weight := float64(10 + random.IntN(80))
+			quantity := 1 + random.IntN(3)
+			lines = append(lines, types.PackingLine{Size: types.PacketSize(size), Quantity: types.PacketQuantity(quantity)})
+			specs = append(specs, shipment.PackSpec{Size: types.PacketSize(size), Weight: weight})
+			for range quantity {
+				weights = append(weights, weight)
+			}
+		}
+
+		plan, err := shipment.NewPlan(lines, specs, container, shipment.ModeExact)
+		require.NoError(t, err)
+		require.True(t, plan.Optimal)
+		require.Len(t, plan.Containers, fewestBins(weights, container.MaxWeight), "weights %v", weights)
+		for _, load := range plan.Containers {
+			require.LessOrEqual(t, load.Weight, container.MaxWeight)
+		}
+	}
+}
+
+// fewestBins tries every assignment of weights to bins of capacity.
+func fewestBins(weights []float64, capacity float64) int {
+	best := len(weights)
+	var bins []float64
+	var place func(index int)
+	place = func(index int) {
+		if len(bins) >= best {
+			return
+		}
+		if index == len(weights) {
+			best = len(bins)
+
+			return
+		}
+		for b := range bins {
+			if bins[b]+weights[index] <= capacity {
+				bins[b] += weights[index]
+				place(index + 1)
+				bins[b] -= weights[index]
+			}
+		}
+		bins = append(bins, weights[index])
+		place(index + 1)
+		bins = bins[:len(bins)-1]
+	}
+	place(0)
+
+	return best
+}
+
+func TestNewPlan_Errors(t *testing.T) {
+	t.Parallel()
+
+	lines := []types.PackingLine{{Size: 500, Quantity: 2}}
+	specs := []shipment.PackSpec{{Size: 500, Weight: 10, Dimensions: cube}}
+	container := shipment.Container{MaxWeight: 100}
+
+	tests := []struct {
+		expected  error
+		name      string
+		mode      shipment.Mode
+		lines     []types.PackingLine
+		specs     []shipment.PackSpec
+		container shipment.Container
+	}{
+		{name: "no packs", lines: nil, specs: specs, container: container, expected: shipment.ErrNoPacks},
+		{
+			name:      "non-positive quantity",
+			lines:     []types.PackingLine{{Size: 500, Quantity: 0}},
+			specs:     specs,
+			container: container,
+			expected:  shipment.ErrInvalidPackLine,
+		},
+		{name: "missing spec", lines: []types.PackingLine{{Size: 250, Quantity: 1}}, specs: specs, container: container, expected: shipment.ErrMissingPackSpec},
+		{
+			name:      "duplicated spec",
+			lines:     lines,
+			specs:     append(append([]shipment.PackSpec{}, specs...), specs...),
+			container: container,
+			expected:  shipment.ErrInvalidPackSpec,
+		},
+		{
+			name:      "negative weight",
+			lines:     lines,
+			specs:     []shipment.PackSpec{{Size: 500, Weight: -1}},
+			container: container,
+			expected:  shipment.ErrInvalidPackSpec,
+		},
+		{name: "unbounded container", lines: lines, specs: specs, expected: shipment.ErrInvalidContainer},
+		{name: "negative limit", lines: lines, specs: specs, container: shipment.Container{MaxWeight: 100, MaxPacks: -1}, expected: shipment.ErrInvalidContainer},
+		{name: "pack too heavy", lines: lines, specs: specs, container: shipment.Container{MaxWeight: 5}, expected: shipment.ErrPackTooLarge},
+		{name: "unknown mode", mode: "best-fit", lines: lines, specs: specs, container: container, expected: shipment.ErrUnknownMode},
+		{
+			name:      "too many packs for exact",
+			mode:      shipment.ModeExact,
+			lines:     []types.PackingLine{{Size: 500, Quantity: shipment.ExactMaxPacks + 1}},
+			specs:     specs,
+			container: container,
+			expected:  shipment.ErrTooManyPacks,
+		},
+		{
+			name:      "pack count overflow",
+			lines:     []types.PackingLine{{Size: 1, Quantity: 3}, {Size: 2, Quantity: 9223372036854775805}},
+			specs:     []shipment.PackSpec{{Size: 1, Weight: 1}, {Size: 2, Weight: 1}},
+			container: container,
+			expected:  shipment.ErrTooManyPacks,
+		},
+		{
+			name:      "too many containers",
+			lines:     []types.PackingLine{{Size: 500, Quantity: shipment.MaxContainers + 1}},
+			specs:     specs,
+			container: shipment.Container{MaxPacks: 1},
+			expected:  shipment.ErrTooManyContainers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := shipment.NewPlan(tt.lines, tt.specs, tt.container, tt.mode)
+			require.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
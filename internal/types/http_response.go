@@ -21,3 +21,15 @@ func NewErrorResponse[T any](err string) Response[T] {
 		Err: err,
 	}
 }
+
+// APIError is a structured error of the v2 API. Code is stable and meant for programs, Message for people.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+// ErrorResponseV2 is the v2 error envelope: {"error": {...}}.
+type ErrorResponseV2 struct {
+	Error APIError `json:"error"`
+}
@@ -0,0 +1,42 @@
+package types
+
+// OrderLineResult is the packing of one line of an order.
+type OrderLineResult struct {
+	SKU     string        `json:"sku,omitempty"`
+	Catalog string        `json:"catalog"`
+	Result  PackingResult `json:"result"`
+}
+
+// OrderTotals sums the packings of an order's lines.
+type OrderTotals struct {
+	ItemsRequested int `json:"items_requested"`
+	TotalShipped   int `json:"total_shipped"`
+	Overshoot      int `json:"overshoot"`
+	PackCount      int `json:"pack_count"`
+	// TotalWeight, TotalVolume and TotalCost sum the line results that have them.
+	TotalWeight float64 `json:"total_weight,omitempty"`
+	TotalVolume float64 `json:"total_volume,omitempty"`
+	TotalCost   float64 `json:"total_cost,omitempty"`
+}
+
+// OrderResult is the packing of every line of an order, in the order of the request, and their totals.
+type OrderResult struct {
+	Lines  []OrderLineResult `json:"lines"`
+	Totals OrderTotals       `json:"totals"`
+}
+
+// NewOrderResult sums the lines into the order totals.
+func NewOrderResult(lines []OrderLineResult) OrderResult {
+	order := OrderResult{Lines: lines}
+	for _, line := range lines {
+		order.Totals.ItemsRequested += line.Result.ItemsRequested
+		order.Totals.TotalShipped += line.Result.TotalShipped
+		order.Totals.Overshoot += line.Result.Overshoot
+		order.Totals.PackCount += line.Result.PackCount
+		order.Totals.TotalWeight += line.Result.TotalWeight
+		order.Totals.TotalVolume += line.Result.TotalVolume
+		order.Totals.TotalCost += line.Result.TotalCost
+	}
+
+	return order
+}
@@ -4,3 +4,58 @@ type (
 	PacketSize     int
 	PacketQuantity int
 )
+
+// PacketConstraint restricts the quantity of a packet size in a packing: either none of it, or at least MinQuantity
+// packets in multiples of Step, e.g. a pack that ships in pairs on a pallet. Zero MinQuantity and Step leave the
+// size unconstrained.
+type PacketConstraint struct {
+	Size        PacketSize     `json:"size"`
+	MinQuantity PacketQuantity `json:"min_quantity,omitempty"`
+	Step        PacketQuantity `json:"step,omitempty"`
+}
+
+// StepOrOne returns Step, or 1 when it is unset.
+func (constraint PacketConstraint) StepOrOne() PacketQuantity {
+	return max(constraint.Step, 1)
+}
+
+// MinSteps returns the fewest steps a packing that uses the size must hold, at least 1.
+func (constraint PacketConstraint) MinSteps() PacketQuantity {
+	step := constraint.StepOrOne()
+
+	return max((constraint.MinQuantity+step-1)/step, 1)
+}
+
+// Allows reports whether a packing may hold quantity packets of the size.
+func (constraint PacketConstraint) Allows(quantity PacketQuantity) bool {
+	return quantity == 0 || quantity >= constraint.MinQuantity && quantity%constraint.StepOrOne() == 0
+}
+
+// Dimensions are the outer measurements of a pack, in the same length unit wherever they are compared.
+type Dimensions struct {
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Volume returns Length × Width × Height.
+func (dimensions Dimensions) Volume() float64 {
+	return dimensions.Length * dimensions.Width * dimensions.Height
+}
+
+// PacketDefinition describes the pack of a packet size for downstream systems, e.g. its SKU and shipping weight. Size,
+// the capacity, is the key of the definition; the other fields are optional and never change a packing.
+type PacketDefinition struct {
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	ID         string      `json:"id,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	Size       PacketSize  `json:"size"`
+	Weight     float64     `json:"weight,omitempty"`
+	Cost       float64     `json:"cost,omitempty"`
+}
+
+// PacketSizeDetails are the optional constraints and definitions of packet sizes, replaced together with them.
+type PacketSizeDetails struct {
+	Constraints []PacketConstraint
+	Definitions []PacketDefinition
+}
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// PackingLine is the number of packets of one size in a packing, with the definition of the size when it has one.
+type PackingLine struct {
+	Definition *PacketDefinition `json:"definition,omitempty"`
+	Size       PacketSize        `json:"size"`
+	Quantity   PacketQuantity    `json:"quantity"`
+}
+
+// PackingResult is a calculated packing with its totals. Lines are ordered by size, largest first.
+type PackingResult struct {
+	// Strategy is the solver that calculated the packing.
+	Strategy string        `json:"strategy"`
+	Lines    []PackingLine `json:"lines"`
+	// ItemsRequested is the number of items the packing was calculated for.
+	ItemsRequested int `json:"items_requested"`
+	// TotalShipped is the number of items the packets hold, at least ItemsRequested.
+	TotalShipped int `json:"total_shipped"`
+	// Overshoot is TotalShipped - ItemsRequested.
+	Overshoot int `json:"overshoot"`
+	PackCount int `json:"pack_count"`
+	// TotalWeight, TotalVolume and TotalCost sum the weight, volume and cost of the packs whose definitions give them.
+	TotalWeight float64 `json:"total_weight,omitempty"`
+	TotalVolume float64 `json:"total_volume,omitempty"`
+	TotalCost   float64 `json:"total_cost,omitempty"`
+	// SizeSetVersion identifies the packet sizes used. It changes whenever the sizes are replaced.
+	SizeSetVersion uint64 `json:"size_set_version"`
+	// ComputeTime is how long the solver took. A cached result keeps the time of its calculation.
+	ComputeTime time.Duration `json:"compute_time_ns"`
+	Cached      bool          `json:"cached"`
+}
+
+// NewPackingResult builds the lines and totals of a packing of items.
+func NewPackingResult(items int, packets map[PacketSize]PacketQuantity) PackingResult {
+	result := PackingResult{
+		Lines:          make([]PackingLine, 0, len(packets)),
+		ItemsRequested: items,
+	}
+	for size, quantity := range packets {
+		result.Lines = append(result.Lines, PackingLine{Size: size, Quantity: quantity})
+		result.TotalShipped += int(size) * int(quantity)
+		result.PackCount += int(quantity)
+	}
+	slices.SortFunc(result.Lines, func(a, b PackingLine) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	result.Overshoot = result.TotalShipped - items
+
+	return result
+}
+
+// Packets returns the quantities keyed by packet size, the shape of the v1 optimal_packets field.
+func (result *PackingResult) Packets() map[PacketSize]PacketQuantity {
+	packets := make(map[PacketSize]PacketQuantity, len(result.Lines))
+	for _, line := range result.Lines {
+		packets[line.Size] = line.Quantity
+	}
+
+	return packets
+}
+
+// ApplyDefinitions attaches the definition of each line's size and sums the weight, volume and cost of the packs.
+func (result *PackingResult) ApplyDefinitions(definitions []PacketDefinition) {
+	for index := range result.Lines {
+		line := &result.Lines[index]
+		for _, definition := range definitions {
+			if definition.Size != line.Size {
+				continue
+			}
+
+			line.Definition = &definition
+			result.TotalWeight += definition.Weight * float64(line.Quantity)
+			result.TotalCost += definition.Cost * float64(line.Quantity)
+			if definition.Dimensions != nil {
+				result.TotalVolume += definition.Dimensions.Volume() * float64(line.Quantity)
+			}
+
+			break
+		}
+	}
+}
@@ -2,21 +2,50 @@ package validation
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
 
 	"github.com/dsha256/packer/internal/types"
 )
 
 var (
-	ErrNonPositiveSize = errors.New("size should be a positive integer")
-	ErrDuplicatedSizes = errors.New("sizes should be unique")
+	ErrNonPositiveSize   = errors.New("size should be a positive integer")
+	ErrSizeTooLarge      = errors.New("size exceeds maximum allowed value")
+	ErrDuplicatedSizes   = errors.New("sizes should be unique")
+	ErrInvalidItems      = errors.New("items should be positive integer")
+	ErrItemsTooLarge     = errors.New("items exceed maximum allowed value")
+	ErrTooManySizes      = errors.New("number of sizes exceeds maximum allowed value")
+	ErrInvalidConstraint = errors.New("invalid packet constraint")
+	ErrInvalidDefinition = errors.New("invalid packet definition")
 )
 
-func ValidatePacketSizes(sizes []types.PacketSize) error {
+// MaxPacketSize bounds a packet size. The solvers and tables hold an entry per total up to the items plus the largest
+// size, so it bounds the memory a set of sizes asks for regardless of the items.
+const MaxPacketSize = 1_000_000_000
+
+// MaxConstraintQuantity bounds the min_quantity and step of a packet constraint. The solvers expand a constrained size
+// into up to MaxConstraintQuantity packings of it, so it bounds their work too.
+const MaxConstraintQuantity = 1000
+
+const (
+	// MaxDefinitionIDLength bounds the id of a packet definition, in characters.
+	MaxDefinitionIDLength = 64
+	// MaxDefinitionLabelLength bounds the label of a packet definition, in characters.
+	MaxDefinitionLabelLength = 256
+)
+
+// ValidatePacketSizes checks that sizes are positive, at most MaxPacketSize and unique, and that each constraint applies to one of them, once,
+// with a min_quantity and step of at most MaxConstraintQuantity.
+func ValidatePacketSizes(sizes []types.PacketSize, constraints ...types.PacketConstraint) error {
 	tempSizes := make(map[types.PacketSize]types.PacketSize, len(sizes))
 	for _, size := range sizes {
 		if size < 1 {
 			return ErrNonPositiveSize
 		}
+		if size > MaxPacketSize {
+			return fmt.Errorf("%w of %d, got %d", ErrSizeTooLarge, MaxPacketSize, size)
+		}
 		tempSizes[size]++
 	}
 
@@ -26,5 +55,86 @@ func ValidatePacketSizes(sizes []types.PacketSize) error {
 		}
 	}
 
+	constrained := make(map[types.PacketSize]struct{}, len(constraints))
+	for _, constraint := range constraints {
+		if _, ok := tempSizes[constraint.Size]; !ok {
+			return fmt.Errorf("%w: size %d is not one of the sizes", ErrInvalidConstraint, constraint.Size)
+		}
+		if _, ok := constrained[constraint.Size]; ok {
+			return fmt.Errorf("%w: size %d is constrained twice", ErrInvalidConstraint, constraint.Size)
+		}
+		if constraint.MinQuantity < 0 || constraint.MinQuantity > MaxConstraintQuantity ||
+			constraint.Step < 0 || constraint.Step > MaxConstraintQuantity {
+			return fmt.Errorf("%w: size %d needs min_quantity and step between 0 and %d",
+				ErrInvalidConstraint, constraint.Size, MaxConstraintQuantity)
+		}
+		constrained[constraint.Size] = struct{}{}
+	}
+
+	return nil
+}
+
+// ValidatePacketDefinitions checks that each definition applies to one of sizes, once, that ids are unique and labels
+// and ids short enough, and that weights, costs and dimensions are finite and not negative.
+func ValidatePacketDefinitions(sizes []types.PacketSize, definitions []types.PacketDefinition) error {
+	known := make(map[types.PacketSize]struct{}, len(sizes))
+	for _, size := range sizes {
+		known[size] = struct{}{}
+	}
+
+	defined := make(map[types.PacketSize]struct{}, len(definitions))
+	ids := make(map[string]types.PacketSize, len(definitions))
+	for _, definition := range definitions {
+		if _, ok := known[definition.Size]; !ok {
+			return fmt.Errorf("%w: size %d is not one of the sizes", ErrInvalidDefinition, definition.Size)
+		}
+		if _, ok := defined[definition.Size]; ok {
+			return fmt.Errorf("%w: size %d is defined twice", ErrInvalidDefinition, definition.Size)
+		}
+		if size, ok := ids[definition.ID]; ok && definition.ID != "" {
+			return fmt.Errorf("%w: sizes %d and %d share the id %q", ErrInvalidDefinition, size, definition.Size, definition.ID)
+		}
+		if utf8.RuneCountInString(definition.ID) > MaxDefinitionIDLength ||
+			utf8.RuneCountInString(definition.Label) > MaxDefinitionLabelLength {
+			return fmt.Errorf("%w: size %d needs an id of at most %d and a label of at most %d characters",
+				ErrInvalidDefinition, definition.Size, MaxDefinitionIDLength, MaxDefinitionLabelLength)
+		}
+
+		measures := []float64{definition.Weight, definition.Cost}
+		if definition.Dimensions != nil {
+			measures = append(measures,
+				definition.Dimensions.Length, definition.Dimensions.Width, definition.Dimensions.Height)
+		}
+		for _, measure := range measures {
+			if measure < 0 || math.IsInf(measure, 0) || math.IsNaN(measure) {
+				return fmt.Errorf("%w: size %d needs a weight, cost and dimensions that are finite and not negative",
+					ErrInvalidDefinition, definition.Size)
+			}
+		}
+
+		defined[definition.Size] = struct{}{}
+		ids[definition.ID] = definition.Size
+	}
+
+	return nil
+}
+
+func ValidatePacketSizesCount(sizes []types.PacketSize, maxSizes int) error {
+	if len(sizes) > maxSizes {
+		return fmt.Errorf("%w of %d", ErrTooManySizes, maxSizes)
+	}
+
+	return nil
+}
+
+func ValidateItems(items, maxItems int) error {
+	if items < 1 {
+		return ErrInvalidItems
+	}
+
+	if items > maxItems {
+		return fmt.Errorf("%w of %d", ErrItemsTooLarge, maxItems)
+	}
+
 	return nil
 }
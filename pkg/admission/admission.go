@@ -0,0 +1,174 @@
+package admission
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull         = errors.New("admission queue is full")
+	ErrWaitTimeout       = errors.New("timed out waiting for admission")
+	ErrExceedsCapacity   = errors.New("request exceeds the total admission capacity")
+	ErrNonPositiveWeight = errors.New("weight should be a positive integer")
+)
+
+// Config holds the configuration for the admission controller.
+type Config struct {
+	// Capacity is the total weight that may be in flight at once.
+	Capacity int64
+	// MaxQueue is the number of requests allowed to wait for capacity. Zero rejects immediately when busy.
+	MaxQueue int
+	// MaxWait bounds how long a request waits in the queue. Zero waits until the context is done.
+	MaxWait time.Duration
+}
+
+// Stats is a snapshot of the controller state.
+type Stats struct {
+	Capacity   int64 `json:"capacity"`
+	InUse      int64 `json:"in_use"`
+	QueueDepth int   `json:"queue_depth"`
+	Admitted   int64 `json:"admitted"`
+	Rejected   int64 `json:"rejected"`
+}
+
+type waiter struct {
+	ready  chan struct{}
+	weight int64
+}
+
+// Controller is a weighted semaphore with a bounded FIFO queue.
+// It bounds the total work of requests running concurrently.
+type Controller struct {
+	waiters  *list.List
+	config   Config
+	inUse    int64
+	admitted int64
+	rejected int64
+	mu       sync.Mutex
+}
+
+// New creates a new Controller.
+func New(config Config) *Controller {
+	return &Controller{
+		waiters: list.New(),
+		config:  config,
+	}
+}
+
+// Acquire blocks until weight fits into the capacity, the queue wait times out or ctx is done.
+// On success the returned release function must be called exactly once when the work is finished.
+func (controller *Controller) Acquire(ctx context.Context, weight int64) (func(), error) {
+	if weight < 1 {
+		return nil, ErrNonPositiveWeight
+	}
+
+	controller.mu.Lock()
+	if weight > controller.config.Capacity {
+		controller.rejected++
+		controller.mu.Unlock()
+
+		return nil, ErrExceedsCapacity
+	}
+
+	// Queued requests are served first so that large requests are not starved by a stream of small ones.
+	if controller.waiters.Len() == 0 && controller.inUse+weight <= controller.config.Capacity {
+		controller.inUse += weight
+		controller.admitted++
+		controller.mu.Unlock()
+
+		return controller.releaseFunc(weight), nil
+	}
+
+	if controller.waiters.Len() >= controller.config.MaxQueue {
+		controller.rejected++
+		controller.mu.Unlock()
+
+		return nil, ErrQueueFull
+	}
+
+	ready := make(chan struct{})
+	element := controller.waiters.PushBack(&waiter{ready: ready, weight: weight})
+	controller.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if controller.config.MaxWait > 0 {
+		timer := time.NewTimer(controller.config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return controller.releaseFunc(weight), nil
+	case <-ctx.Done():
+		return controller.abandon(element, ready, weight, ctx.Err())
+	case <-timeout:
+		return controller.abandon(element, ready, weight, ErrWaitTimeout)
+	}
+}
+
+// Stats returns the current state of the controller.
+func (controller *Controller) Stats() Stats {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	return Stats{
+		Capacity:   controller.config.Capacity,
+		InUse:      controller.inUse,
+		QueueDepth: controller.waiters.Len(),
+		Admitted:   controller.admitted,
+		Rejected:   controller.rejected,
+	}
+}
+
+// abandon removes a waiter that gave up. If it was admitted in the meantime the admission wins.
+func (controller *Controller) abandon(element *list.Element, ready chan struct{}, weight int64, err error) (func(), error) {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	select {
+	case <-ready:
+		return controller.releaseFunc(weight), nil
+	default:
+	}
+
+	isFront := controller.waiters.Front() == element
+	controller.waiters.Remove(element)
+	controller.rejected++
+	if isFront {
+		controller.notifyWaiters()
+	}
+
+	return nil, err
+}
+
+func (controller *Controller) releaseFunc(weight int64) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			controller.mu.Lock()
+			controller.inUse -= weight
+			controller.notifyWaiters()
+			controller.mu.Unlock()
+		})
+	}
+}
+
+// notifyWaiters admits queued requests in FIFO order while they fit. The caller must hold mu.
+func (controller *Controller) notifyWaiters() {
+	for element := controller.waiters.Front(); element != nil; element = controller.waiters.Front() {
+		next, ok := element.Value.(*waiter)
+		if !ok || controller.inUse+next.weight > controller.config.Capacity {
+			return
+		}
+
+		controller.inUse += next.weight
+		controller.admitted++
+		controller.waiters.Remove(element)
+		close(next.ready)
+	}
+}
@@ -0,0 +1,117 @@
+package admission_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/pkg/admission"
+)
+
+func TestController_AcquireWithinCapacity(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10})
+
+	releaseFirst, err := controller.Acquire(context.Background(), 4)
+	require.NoError(t, err)
+	releaseSecond, err := controller.Acquire(context.Background(), 6)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), controller.Stats().InUse)
+
+	releaseFirst()
+	releaseFirst()
+	releaseSecond()
+
+	stats := controller.Stats()
+	require.Equal(t, int64(0), stats.InUse, "release is idempotent")
+	require.Equal(t, int64(2), stats.Admitted)
+}
+
+func TestController_RejectsInvalidWeights(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10})
+
+	_, err := controller.Acquire(context.Background(), 0)
+	require.ErrorIs(t, err, admission.ErrNonPositiveWeight)
+
+	_, err = controller.Acquire(context.Background(), 11)
+	require.ErrorIs(t, err, admission.ErrExceedsCapacity)
+}
+
+func TestController_RejectsWhenQueueIsFull(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10, MaxQueue: 0})
+
+	release, err := controller.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+	defer release()
+
+	_, err = controller.Acquire(context.Background(), 1)
+	require.ErrorIs(t, err, admission.ErrQueueFull)
+	require.Equal(t, int64(1), controller.Stats().Rejected)
+}
+
+func TestController_QueuesUntilReleased(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10, MaxQueue: 1, MaxWait: time.Second})
+
+	release, err := controller.Acquire(context.Background(), 8)
+	require.NoError(t, err)
+
+	admitted := make(chan error, 1)
+	go func() {
+		queuedRelease, queuedErr := controller.Acquire(context.Background(), 5)
+		if queuedErr == nil {
+			queuedRelease()
+		}
+		admitted <- queuedErr
+	}()
+
+	require.Eventually(t, func() bool {
+		return controller.Stats().QueueDepth == 1
+	}, time.Second, time.Millisecond)
+
+	release()
+	require.NoError(t, <-admitted)
+	require.Equal(t, 0, controller.Stats().QueueDepth)
+}
+
+func TestController_WaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10, MaxQueue: 1, MaxWait: 20 * time.Millisecond})
+
+	release, err := controller.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+	defer release()
+
+	_, err = controller.Acquire(context.Background(), 1)
+	require.ErrorIs(t, err, admission.ErrWaitTimeout)
+	require.Equal(t, 0, controller.Stats().QueueDepth)
+}
+
+func TestController_ContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	controller := admission.New(admission.Config{Capacity: 10, MaxQueue: 2})
+
+	release, err := controller.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = controller.Acquire(ctx, 5)
+	require.ErrorIs(t, err, context.Canceled)
+
+	release()
+	release, err = controller.Acquire(context.Background(), 10)
+	require.NoError(t, err, "abandoned waiters do not hold capacity")
+	release()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,24 +25,46 @@ func (item *cacheItem) isExpired() bool {
 	return time.Now().After(item.expiration)
 }
 
+const defaultCleanupInterval = 10 * time.Second
+
+// Config configures an InMemoryCache.
+type Config struct {
+	// CleanupInterval is how often expired keys are removed. Zero means 10s.
+	CleanupInterval time.Duration
+	// Capacity is the maximum number of keys. Once reached, expired keys are removed first, otherwise an arbitrary
+	// key is evicted. Concurrent writers may exceed it briefly. Zero means unbounded.
+	Capacity int
+}
+
 type InMemoryCache struct {
 	data        *sync.Map
 	stopCleanup chan struct{}
 	cleanupWg   sync.WaitGroup
+	size        atomic.Int64
+	capacity    int64
 }
 
 func NewInMemoryCache() ClosableCache {
-	return NewInMemoryCacheWithCleanup(time.Second * 10)
+	return NewInMemoryCacheWithConfig(Config{})
 }
 
 func NewInMemoryCacheWithCleanup(cleanupInterval time.Duration) ClosableCache {
+	return NewInMemoryCacheWithConfig(Config{CleanupInterval: cleanupInterval})
+}
+
+func NewInMemoryCacheWithConfig(cfg Config) ClosableCache {
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = defaultCleanupInterval
+	}
+
 	cache := &InMemoryCache{
 		data:        &sync.Map{},
 		stopCleanup: make(chan struct{}),
+		capacity:    int64(max(cfg.Capacity, 0)),
 	}
 
 	cache.cleanupWg.Add(1)
-	go cache.cleanupExpiredKeys(cleanupInterval)
+	go cache.cleanupExpiredKeys(cfg.CleanupInterval)
 
 	return cache
 }
@@ -58,7 +81,7 @@ func (cache *InMemoryCache) Get(_ context.Context, key string) (any, error) {
 	}
 
 	if item.isExpired() {
-		cache.data.Delete(key)
+		cache.deleteItem(key, item)
 
 		return "", ErrNoKey
 	}
@@ -75,17 +98,67 @@ func (cache *InMemoryCache) Set(_ context.Context, key string, value any, expira
 		item.expiration = time.Now().Add(expiration)
 	}
 
-	cache.data.Store(key, item)
+	if _, loaded := cache.data.Swap(key, item); !loaded {
+		if cache.size.Add(1) > cache.capacity && cache.capacity > 0 {
+			cache.evict(key)
+		}
+	}
 
 	return nil
 }
 
 func (cache *InMemoryCache) Del(_ context.Context, key string) error {
-	cache.data.Delete(key)
+	if _, loaded := cache.data.LoadAndDelete(key); loaded {
+		cache.size.Add(-1)
+	}
 
 	return nil
 }
 
+// Len returns the number of keys, including expired keys that have not been removed yet.
+func (cache *InMemoryCache) Len() int {
+	return int(cache.size.Load())
+}
+
+// deleteItem removes key only if it still holds item, so that a concurrent Set is not lost.
+func (cache *InMemoryCache) deleteItem(key, item any) bool {
+	if !cache.data.CompareAndDelete(key, item) {
+		return false
+	}
+	cache.size.Add(-1)
+
+	return true
+}
+
+// evict makes room after keep was inserted into a full cache.
+func (cache *InMemoryCache) evict(keep string) {
+	if cache.removeExpired() > 0 {
+		return
+	}
+
+	cache.data.Range(func(key, value any) bool {
+		if key == keep {
+			return true
+		}
+
+		return !cache.deleteItem(key, value)
+	})
+}
+
+func (cache *InMemoryCache) removeExpired() int {
+	removed := 0
+	cache.data.Range(func(key, value any) bool {
+		item, ok := value.(*cacheItem)
+		if ok && item.isExpired() && cache.deleteItem(key, value) {
+			removed++
+		}
+
+		return true
+	})
+
+	return removed
+}
+
 func (cache *InMemoryCache) Close() {
 	close(cache.stopCleanup)
 	cache.cleanupWg.Wait()
@@ -100,18 +173,7 @@ func (cache *InMemoryCache) cleanupExpiredKeys(interval time.Duration) {
 	for {
 		select {
 		case <-ticker.C:
-			cache.data.Range(func(key, value any) bool {
-				item, ok := value.(*cacheItem)
-				if !ok {
-					return true
-				}
-
-				if item.isExpired() {
-					cache.data.Delete(key)
-				}
-
-				return true
-			})
+			cache.removeExpired()
 		case <-cache.stopCleanup:
 			return
 		}
@@ -401,3 +401,35 @@ func TestInMemoryCache_CloseStopsCleanup(t *testing.T) {
 		t.Fatal("Close() did not complete in time - cleanup goroutine may not have stopped")
 	}
 }
+
+func TestInMemoryCache_Capacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := cache.NewInMemoryCacheWithConfig(cache.Config{Capacity: 2})
+	defer closeCache(c)
+
+	inMemory, ok := c.(*cache.InMemoryCache)
+	require.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "expired", 1, time.Millisecond))
+	require.NoError(t, c.Set(ctx, "kept", 2, 0))
+	time.Sleep(5 * time.Millisecond)
+
+	require.NoError(t, c.Set(ctx, "new", 3, 0))
+	require.Equal(t, 2, inMemory.Len())
+	_, err := c.Get(ctx, "expired")
+	require.ErrorIs(t, err, cache.ErrNoKey, "expired keys are evicted first")
+
+	require.NoError(t, c.Set(ctx, "kept", 4, 0))
+	require.Equal(t, 2, inMemory.Len(), "overwriting a key does not grow the cache")
+
+	require.NoError(t, c.Set(ctx, "newest", 5, 0))
+	require.Equal(t, 2, inMemory.Len())
+	value, err := c.Get(ctx, "newest")
+	require.NoError(t, err)
+	require.Equal(t, 5, value, "the inserted key is never the one evicted")
+
+	require.NoError(t, c.Del(ctx, "newest"))
+	require.Equal(t, 1, inMemory.Len())
+}
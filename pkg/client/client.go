@@ -0,0 +1,332 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	defaultTimeout          = 30 * time.Second
+	defaultMaxRetries       = 3
+	defaultMinBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff       = 5 * time.Second
+	defaultBatchConcurrency = 4
+	maxErrorBodySize        = 1 << 20
+)
+
+var ErrInvalidBaseURL = errors.New("base URL should be an absolute http(s) URL")
+
+// Client is a client for the packer HTTP API. It is safe for concurrent use.
+type Client struct {
+	baseURL          *url.URL
+	httpClient       *http.Client
+	apiKey           string
+	bearerToken      string
+	maxRetries       int
+	minBackoff       time.Duration
+	maxBackoff       time.Duration
+	batchConcurrency int
+}
+
+// New creates a new Client for the API served at baseURL, e.g. "http://localhost:3000".
+func New(baseURL string) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBaseURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidBaseURL
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+
+	return &Client{
+		baseURL:          parsed,
+		httpClient:       &http.Client{Timeout: defaultTimeout},
+		maxRetries:       defaultMaxRetries,
+		minBackoff:       defaultMinBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		batchConcurrency: defaultBatchConcurrency,
+	}, nil
+}
+
+// WithHTTPClient sets a custom HTTP client, e.g. to configure transports or TLS.
+func (client *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	client.httpClient = httpClient
+
+	return client
+}
+
+// WithAPIKey authenticates requests with a static API key.
+func (client *Client) WithAPIKey(apiKey string) *Client {
+	client.apiKey = apiKey
+
+	return client
+}
+
+// WithBearerToken authenticates requests with a JWT bearer token.
+func (client *Client) WithBearerToken(token string) *Client {
+	client.bearerToken = token
+
+	return client
+}
+
+// WithRetries sets how many times a request is retried on 429 and 5xx responses or transport errors,
+// and the bounds of the exponential backoff between attempts. A Retry-After header takes precedence over the backoff,
+// up to maxBackoff. A 503 is only retried when it has a Retry-After header, otherwise the server is not expected to
+// recover soon.
+func (client *Client) WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) *Client {
+	client.maxRetries = maxRetries
+	client.minBackoff = minBackoff
+	client.maxBackoff = maxBackoff
+
+	return client
+}
+
+// WithBatchConcurrency sets how many calculations CalculateBatch runs at once.
+func (client *Client) WithBatchConcurrency(concurrency int) *Client {
+	client.batchConcurrency = max(1, concurrency)
+
+	return client
+}
+
+// Packs maps a packet size to the number of packets of that size.
+type Packs map[int]int
+
+// BatchResult is the outcome of one calculation of a batch.
+type BatchResult struct {
+	Err   error
+	Packs Packs
+	Items int
+}
+
+// AdmissionStats reports the usage of the server's admission control.
+type AdmissionStats struct {
+	Capacity   int64 `json:"capacity"`
+	InUse      int64 `json:"in_use"`
+	QueueDepth int   `json:"queue_depth"`
+	Admitted   int64 `json:"admitted"`
+	Rejected   int64 `json:"rejected"`
+}
+
+// Health is the health report of the server.
+type Health struct {
+	Admission *AdmissionStats `json:"admission,omitempty"`
+	Message   string          `json:"-"`
+}
+
+type envelope[T any] struct {
+	Data T      `json:"data"`
+	Err  string `json:"err"`
+	Msg  string `json:"msg"`
+}
+
+// ListPacketSizes returns the configured packet sizes in ascending order.
+func (client *Client) ListPacketSizes(ctx context.Context) ([]int, error) {
+	var response envelope[struct {
+		PacketSizes []int `json:"packet_sizes"`
+	}]
+	if err := client.do(ctx, http.MethodGet, "/api/v1/packet/size", nil, nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Data.PacketSizes, nil
+}
+
+// SetPacketSizes replaces the configured packet sizes. It requires the admin role when authentication is enabled.
+func (client *Client) SetPacketSizes(ctx context.Context, sizes []int) error {
+	body, err := json.Marshal(map[string][]int{"sizes": sizes})
+	if err != nil {
+		return err
+	}
+
+	var response envelope[json.RawMessage]
+
+	return client.do(ctx, http.MethodPut, "/api/v1/packet/size", nil, body, &response)
+}
+
+// Calculate returns the optimal packs for the number of items.
+func (client *Client) Calculate(ctx context.Context, items int) (Packs, error) {
+	var response envelope[struct {
+		OptimalPackets map[string]int `json:"optimal_packets"`
+	}]
+	query := url.Values{"items": {strconv.Itoa(items)}}
+	if err := client.do(ctx, http.MethodGet, "/api/v1/packet/calculate", query, nil, &response); err != nil {
+		return nil, err
+	}
+
+	packs := make(Packs, len(response.Data.OptimalPackets))
+	for size, quantity := range response.Data.OptimalPackets {
+		sizeInt, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected packet size %q in response: %w", size, err)
+		}
+		packs[sizeInt] = quantity
+	}
+
+	return packs, nil
+}
+
+// CalculateBatch calculates the optimal packs for every item count concurrently.
+// Results are returned in the order of items, each with its own error.
+func (client *Client) CalculateBatch(ctx context.Context, items []int) []BatchResult {
+	results := make([]BatchResult, len(items))
+	semaphore := make(chan struct{}, client.batchConcurrency)
+
+	var wg sync.WaitGroup
+	for index, itemCount := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			packs, err := client.Calculate(ctx, itemCount)
+			results[index] = BatchResult{Items: itemCount, Packs: packs, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Health returns the health report of the server.
+func (client *Client) Health(ctx context.Context) (*Health, error) {
+	var response envelope[*Health]
+	if err := client.do(ctx, http.MethodGet, "/api/v1/health", nil, nil, &response); err != nil {
+		return nil, err
+	}
+
+	health := response.Data
+	if health == nil {
+		health = &Health{}
+	}
+	health.Message = response.Msg
+
+	return health, nil
+}
+
+// do sends the request, retrying retryable failures, and decodes the response envelope into target.
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, target any) error {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := client.doOnce(ctx, method, path, query, body, target)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if attempt >= client.maxRetries || !isRetryable(ctx, err) {
+			return lastErr
+		}
+
+		wait := client.backoff(attempt)
+		if retryAfter > 0 {
+			wait = min(retryAfter, max(client.maxBackoff, 0))
+		}
+		// A retry that could only start after the deadline would fail anyway.
+		if deadline, ok := ctx.Deadline(); ok && wait >= time.Until(deadline) {
+			return lastErr
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return errors.Join(lastErr, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (client *Client) doOnce(ctx context.Context, method, path string, query url.Values, body []byte, target any) (time.Duration, error) {
+	endpoint := *client.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bodyReader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client.apiKey != "" {
+		req.Header.Set("X-API-Key", client.apiKey)
+	}
+	if client.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+client.bearerToken)
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return 0, &TransportError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := newAPIError(resp)
+
+		return apiErr.RetryAfter, apiErr
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return 0, nil
+}
+
+// backoff returns the exponential backoff for the attempt with full jitter.
+func (client *Client) backoff(attempt int) time.Duration {
+	backoff := client.minBackoff << min(attempt, 30)
+	if backoff <= 0 || backoff > client.maxBackoff {
+		backoff = client.maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int64N(int64(backoff))) + 1 //nolint:gosec // Jitter does not need a secure source.
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusServiceUnavailable {
+			return apiErr.RetryAfter > 0
+		}
+
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
+}
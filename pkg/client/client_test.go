@@ -0,0 +1,232 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/internal/handler"
+	"github.com/dsha256/packer/internal/middleware"
+	"github.com/dsha256/packer/internal/packer"
+	"github.com/dsha256/packer/pkg/cache"
+	"github.com/dsha256/packer/pkg/client"
+)
+
+func newTestServer(t *testing.T, configure func(h *handler.Handler), wrap func(next http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	newCache := cache.NewInMemoryCache()
+	t.Cleanup(newCache.Close)
+
+	newHandler := handler.New(slog.New(slog.DiscardHandler), packer.New(), newCache)
+	if configure != nil {
+		configure(newHandler)
+	}
+
+	mux := http.NewServeMux()
+	newHandler.RegisterRoutes(mux)
+
+	var serverHandler http.Handler = mux
+	if wrap != nil {
+		serverHandler = wrap(mux)
+	}
+
+	server := httptest.NewServer(serverHandler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestClient(t *testing.T, server *httptest.Server) *client.Client {
+	t.Helper()
+
+	newClient, err := client.New(server.URL)
+	require.NoError(t, err)
+
+	return newClient.WithHTTPClient(server.Client()).WithRetries(3, time.Millisecond, 5*time.Millisecond)
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	t.Parallel()
+
+	for _, baseURL := range []string{"", "localhost:3000", "ftp://example.com", "://bad"} {
+		_, err := client.New(baseURL)
+		require.ErrorIs(t, err, client.ErrInvalidBaseURL, baseURL)
+	}
+}
+
+func TestClient_PacketSizes(t *testing.T) {
+	t.Parallel()
+
+	newClient := newTestClient(t, newTestServer(t, nil, nil))
+	ctx := context.Background()
+
+	sizes, err := newClient.ListPacketSizes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{250, 500, 1000, 2000, 5000}, sizes)
+
+	require.NoError(t, newClient.SetPacketSizes(ctx, []int{53, 31, 23}))
+
+	sizes, err = newClient.ListPacketSizes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{23, 31, 53}, sizes)
+
+	err = newClient.SetPacketSizes(ctx, []int{23, 23})
+	require.ErrorIs(t, err, client.ErrBadRequest)
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "sizes should be unique", apiErr.Message)
+}
+
+func TestClient_Calculate(t *testing.T) {
+	t.Parallel()
+
+	newClient := newTestClient(t, newTestServer(t, nil, nil))
+
+	packs, err := newClient.Calculate(context.Background(), 12001)
+	require.NoError(t, err)
+	require.Equal(t, client.Packs{5000: 2, 2000: 1, 250: 1}, packs)
+
+	_, err = newClient.Calculate(context.Background(), 0)
+	require.ErrorIs(t, err, client.ErrBadRequest)
+}
+
+func TestClient_CalculateBatch(t *testing.T) {
+	t.Parallel()
+
+	newClient := newTestClient(t, newTestServer(t, nil, nil)).WithBatchConcurrency(2)
+
+	results := newClient.CalculateBatch(context.Background(), []int{1, 251, -5, 501})
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	require.Equal(t, client.Packs{250: 1}, results[0].Packs)
+	require.NoError(t, results[1].Err)
+	require.Equal(t, client.Packs{500: 1}, results[1].Packs)
+	require.ErrorIs(t, results[2].Err, client.ErrBadRequest)
+	require.Equal(t, -5, results[2].Items)
+	require.NoError(t, results[3].Err)
+	require.Equal(t, client.Packs{500: 1, 250: 1}, results[3].Packs)
+}
+
+func TestClient_Health(t *testing.T) {
+	t.Parallel()
+
+	newClient := newTestClient(t, newTestServer(t, nil, nil))
+
+	health, err := newClient.Health(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "All services are up and running", health.Message)
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newTestServer(t, nil, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= 2 {
+				w.WriteHeader(http.StatusBadGateway)
+
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	newClient := newTestClient(t, server)
+
+	sizes, err := newClient.ListPacketSizes(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, sizes)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_GivesUpAfterMaxRetries(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newTestServer(t, nil, func(_ http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+	newClient := newTestClient(t, server).WithRetries(2, time.Millisecond, time.Millisecond)
+
+	start := time.Now()
+	_, err := newClient.ListPacketSizes(context.Background())
+	require.ErrorIs(t, err, client.ErrUnavailable)
+	require.Equal(t, int32(3), calls.Load())
+	require.Less(t, time.Since(start), time.Second, "the Retry-After wait is bounded by the max backoff")
+}
+
+func TestClient_DoesNotRetryUnavailableWithoutRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newTestServer(t, nil, func(_ http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+	newClient := newTestClient(t, server)
+
+	_, err := newClient.ListPacketSizes(context.Background())
+	require.ErrorIs(t, err, client.ErrUnavailable)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_RateLimitedRespectsContext(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, func(h *handler.Handler) {
+		limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+			Default: middleware.ClientLimit{RequestsPerSecond: 0.01, Burst: 1},
+		})
+		t.Cleanup(limiter.Close)
+		h.WithRateLimiter(limiter, 1000)
+	}, nil)
+	newClient := newTestClient(t, server).WithRetries(3, time.Millisecond, time.Hour)
+
+	_, err := newClient.Calculate(context.Background(), 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = newClient.Calculate(ctx, 1)
+	require.ErrorIs(t, err, client.ErrRateLimited)
+	require.NoError(t, ctx.Err())
+	require.Less(t, time.Since(start), time.Second, "a Retry-After wait past the deadline is not started")
+}
+
+func TestClient_Authentication(t *testing.T) {
+	t.Parallel()
+
+	adminDigest := sha256.Sum256([]byte("admin-key"))
+	server := newTestServer(t, func(h *handler.Handler) {
+		h.WithAuthenticator(middleware.NewAuthenticator(middleware.AuthConfig{
+			APIKeys: map[string]middleware.Principal{
+				hex.EncodeToString(adminDigest[:]): {Subject: "admin", Role: middleware.RoleAdmin},
+			},
+		}))
+	}, nil)
+
+	_, err := newTestClient(t, server).ListPacketSizes(context.Background())
+	require.ErrorIs(t, err, client.ErrUnauthenticated)
+
+	_, err = newTestClient(t, server).WithAPIKey("admin-key").ListPacketSizes(context.Background())
+	require.NoError(t, err)
+}
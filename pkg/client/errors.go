@@ -0,0 +1,92 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrUnavailable     = errors.New("service unavailable")
+	ErrServer          = errors.New("server error")
+)
+
+// APIError is an error response of the API. Use errors.Is with the Err* sentinels to classify it.
+type APIError struct {
+	// Message is the "err" field of the response envelope, or the raw body when it is not an envelope.
+	Message    string
+	StatusCode int
+	// RetryAfter is the wait requested by the server's Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (apiErr *APIError) Error() string {
+	return fmt.Sprintf("packer API responded %d: %s", apiErr.StatusCode, apiErr.Message)
+}
+
+// Is maps the status code to the matching sentinel error.
+func (apiErr *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return apiErr.StatusCode == http.StatusBadRequest
+	case ErrUnauthenticated:
+		return apiErr.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return apiErr.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return apiErr.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return apiErr.StatusCode == http.StatusServiceUnavailable
+	case ErrServer:
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	default:
+		return false
+	}
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var response envelope[json.RawMessage]
+	if err = json.Unmarshal(body, &response); err == nil && response.Err != "" {
+		apiErr.Message = response.Err
+	} else {
+		apiErr.Message = string(body)
+	}
+
+	return apiErr
+}
+
+// TransportError is a failure to get any response from the server.
+type TransportError struct {
+	Err error
+}
+
+func (transportErr *TransportError) Error() string {
+	return "packer API request failed: " + transportErr.Err.Error()
+}
+
+func (transportErr *TransportError) Unwrap() error {
+	return transportErr.Err
+}
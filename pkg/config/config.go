@@ -5,24 +5,212 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/dsha256/packer/pkg/tlsconfig"
+)
+
+const (
+	// DefaultPath is used when neither the -config flag nor PathEnv is set.
+	DefaultPath = "./config.yaml"
+	// PathEnv names the environment variable holding the config file path.
+	PathEnv = "PACKER_CONFIG"
 )
 
 type Config struct {
-	Server   Server   `json:"server"   yaml:"server"`
-	Profiler Profiler `json:"profiler" yaml:"profiler"`
+	Server    Server    `json:"server"     yaml:"server"`
+	Log       Log       `json:"log"        yaml:"log"`
+	Profiler  Profiler  `json:"profiler"   yaml:"profiler"`
+	RateLimit RateLimit `json:"rate_limit" yaml:"rate_limit"`
+	Auth      Auth      `json:"auth"       yaml:"auth"`
+	Packer    Packer    `json:"packer"     yaml:"packer"`
+	Cache     Cache     `json:"cache"      yaml:"cache"`
+	Admission Admission `json:"admission"  yaml:"admission"`
+	GRPC      GRPC      `json:"grpc"       yaml:"grpc"`
+}
+
+// Log configures the application logger. Level is one of debug, info, warn or error.
+type Log struct {
+	Level string `json:"level" yaml:"level"`
+}
+
+// Packer configures the solver. Zero values keep the built-in defaults.
+type Packer struct {
+	// DefaultStrategy is the solver used for calculations: v1 (dynamic programming), v2 (min-heap) or parallel
+	// (dynamic programming over Workers goroutines).
+	DefaultStrategy string `json:"default_strategy" yaml:"default_strategy"`
+	// DefaultSizes are used until the sizes are changed through the API.
+	DefaultSizes []int `json:"default_sizes"    yaml:"default_sizes"`
+	// LookupTable precomputes the answers of small calculations for the current sizes.
+	LookupTable LookupTable `json:"lookup_table"     yaml:"lookup_table"`
+	// DPTable keeps the v1 dynamic programming table of the current sizes between calculations.
+	DPTable DPTable `json:"dp_table"         yaml:"dp_table"`
+	// Catalogs are further named packet size sets that order lines can be packed with.
+	Catalogs []Catalog `json:"catalogs"         yaml:"catalogs"`
+	// MaxItems is the largest item count accepted by a calculation.
+	MaxItems int `json:"max_items"        yaml:"max_items"`
+	// MaxSizes is the largest number of packet sizes that can be set.
+	MaxSizes int `json:"max_sizes"        yaml:"max_sizes"`
+	// Workers is the number of goroutines of the parallel strategy, 0 means one per CPU.
+	Workers int `json:"workers"          yaml:"workers"`
+}
+
+// LookupTable configures the precomputed answer table built whenever the packet sizes change. Calculations up to
+// MaxItems are answered from it, larger ones by the solver; zero disables it.
+type LookupTable struct {
+	MaxItems   int  `json:"max_items"  yaml:"max_items"`
+	Background bool `json:"background" yaml:"background"`
+}
+
+// DPTable configures the dynamic programming table that the v1 strategy keeps and grows between calculations of up
+// to MaxItems items; zero disables it.
+type DPTable struct {
+	MaxItems int `json:"max_items" yaml:"max_items"`
+}
+
+// Catalog is a named set of packet sizes for the order lines of its SKUs. The sizes of the "default" catalog are the
+// ones managed through the API.
+type Catalog struct {
+	Name  string `json:"name"  yaml:"name"`
+	Sizes []int  `json:"sizes" yaml:"sizes"`
+}
+
+// Cache configures the calculation result cache. Zero values keep the built-in defaults.
+type Cache struct {
+	TTL             time.Duration `json:"ttl"              yaml:"ttl"`
+	CleanupInterval time.Duration `json:"cleanup_interval" yaml:"cleanup_interval"`
+	// Capacity is the maximum number of cached calculations, 0 means unbounded.
+	Capacity int `json:"capacity"         yaml:"capacity"`
 }
 
 type Server struct {
-	Port              int           `json:"port"                yaml:"port"`
-	ReadTimeout       time.Duration `json:"read_timeout"        yaml:"read_timeout"`
-	ReadHeaderTimeout time.Duration `json:"read_header_timeout" yaml:"read_header_timeout"`
-	WriteTimeout      time.Duration `json:"write_timeout"       yaml:"write_timeout"`
+	Port              int           `json:"port"                 yaml:"port"`
+	ReadTimeout       time.Duration `json:"read_timeout"         yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout"  yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `json:"write_timeout"        yaml:"write_timeout"`
+	// ShutdownDrainDelay is how long /readyz reports not ready before the server stops accepting connections.
+	ShutdownDrainDelay time.Duration `json:"shutdown_drain_delay" yaml:"shutdown_drain_delay"`
+	// TLS secures the HTTP and gRPC APIs.
+	TLS TLS `json:"tls" yaml:"tls"`
 }
 
+// TLS configures a TLS listener. Certificate, key and CA files are reloaded when their content changes.
+type TLS struct {
+	CertFile string `json:"cert_file"           yaml:"cert_file"`
+	KeyFile  string `json:"key_file"            yaml:"key_file"`
+	// ClientCAFile enables mTLS: client certificates are verified against this PEM bundle.
+	ClientCAFile string `json:"client_ca_file"      yaml:"client_ca_file"`
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string `json:"min_version"         yaml:"min_version"`
+	// CipherSuites restricts the TLS 1.2 cipher suites by their Go names.
+	CipherSuites      []string `json:"cipher_suites"       yaml:"cipher_suites"`
+	RequireClientCert bool     `json:"require_client_cert" yaml:"require_client_cert"`
+	Enabled           bool     `json:"enabled"             yaml:"enabled"`
+}
+
+type GRPC struct {
+	Port    int  `json:"port"    yaml:"port"`
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// Profiler configures the pprof server. It binds to 127.0.0.1 unless Host says otherwise, and a token is required
+// when it is reachable from other hosts.
 type Profiler struct {
-	Port              int           `json:"port"                yaml:"port"`
-	ReadHeaderTimeout time.Duration `json:"read_header_timeout" yaml:"read_header_timeout"`
-	Enabled           bool          `json:"enabled"             yaml:"enabled"`
+	TLS                  TLS           `json:"tls"                    yaml:"tls"`
+	Snapshots            Snapshots     `json:"snapshots"              yaml:"snapshots"`
+	Host                 string        `json:"host"                   yaml:"host"`
+	Token                string        `json:"token"                  yaml:"token"`
+	Port                 int           `json:"port"                   yaml:"port"`
+	ReadHeaderTimeout    time.Duration `json:"read_header_timeout"    yaml:"read_header_timeout"`
+	BlockProfileRate     int           `json:"block_profile_rate"     yaml:"block_profile_rate"`
+	MutexProfileFraction int           `json:"mutex_profile_fraction" yaml:"mutex_profile_fraction"`
+	Enabled              bool          `json:"enabled"                yaml:"enabled"`
+}
+
+// Snapshots configures continuous profiling: CPU and heap profiles captured into Dir periodically and when the heap
+// or a request's latency crosses a threshold. It runs with the profiler server.
+type Snapshots struct {
+	Dir                string        `json:"dir"                  yaml:"dir"`
+	Interval           time.Duration `json:"interval"             yaml:"interval"`
+	CPUDuration        time.Duration `json:"cpu_duration"         yaml:"cpu_duration"`
+	MaxAge             time.Duration `json:"max_age"              yaml:"max_age"`
+	LatencyThreshold   time.Duration `json:"latency_threshold"    yaml:"latency_threshold"`
+	Cooldown           time.Duration `json:"cooldown"             yaml:"cooldown"`
+	HeapThresholdBytes uint64        `json:"heap_threshold_bytes" yaml:"heap_threshold_bytes"`
+	MaxFiles           int           `json:"max_files"            yaml:"max_files"`
+	Enabled            bool          `json:"enabled"              yaml:"enabled"`
+}
+
+type RateLimit struct {
+	Clients           []RateLimitClient `json:"clients"             yaml:"clients"`
+	RequestsPerSecond float64           `json:"requests_per_second" yaml:"requests_per_second"`
+	Burst             int               `json:"burst"               yaml:"burst"`
+	ItemsPerCostUnit  int               `json:"items_per_cost_unit" yaml:"items_per_cost_unit"`
+	IdleTimeout       time.Duration     `json:"idle_timeout"        yaml:"idle_timeout"`
+	Enabled           bool              `json:"enabled"             yaml:"enabled"`
+	TrustForwardedFor bool              `json:"trust_forwarded_for" yaml:"trust_forwarded_for"`
+}
+
+// RateLimitClient overrides the default budget for a client identified by its API key.
+// Only the SHA-256 hex digest of the key is stored.
+type RateLimitClient struct {
+	Name              string  `json:"name"                yaml:"name"`
+	APIKeySHA256      string  `json:"api_key_sha256"      yaml:"api_key_sha256"`
+	RequestsPerSecond float64 `json:"requests_per_second" yaml:"requests_per_second"`
+	Burst             int     `json:"burst"               yaml:"burst"`
+}
+
+type Auth struct {
+	APIKeys     []APIKey      `json:"api_keys"     yaml:"api_keys"`
+	JWTSecret   string        `json:"jwt_secret"   yaml:"jwt_secret"`
+	JWTIssuer   string        `json:"jwt_issuer"   yaml:"jwt_issuer"`
+	JWTAudience string        `json:"jwt_audience" yaml:"jwt_audience"`
+	JWTLeeway   time.Duration `json:"jwt_leeway"   yaml:"jwt_leeway"`
+	Enabled     bool          `json:"enabled"      yaml:"enabled"`
+}
+
+// APIKey is a static API key. Only the SHA-256 hex digest of the key is stored.
+type APIKey struct {
+	Name   string `json:"name"   yaml:"name"`
+	SHA256 string `json:"sha256" yaml:"sha256"`
+	Role   string `json:"role"   yaml:"role"`
+}
+
+// Admission bounds the total calculation work in flight, measured as the sum of items + largest pack size.
+type Admission struct {
+	MaxInFlightWork int64         `json:"max_in_flight_work" yaml:"max_in_flight_work"`
+	MaxQueue        int           `json:"max_queue"          yaml:"max_queue"`
+	MaxWait         time.Duration `json:"max_wait"           yaml:"max_wait"`
+	Enabled         bool          `json:"enabled"            yaml:"enabled"`
+}
+
+// Config converts the settings to a tlsconfig.Config.
+func (cfg *TLS) Config() tlsconfig.Config {
+	return tlsconfig.Config{
+		CertFile:          cfg.CertFile,
+		KeyFile:           cfg.KeyFile,
+		ClientCAFile:      cfg.ClientCAFile,
+		MinVersion:        cfg.MinVersion,
+		CipherSuites:      cfg.CipherSuites,
+		RequireClientCert: cfg.RequireClientCert,
+	}
+}
+
+// Load reads the config file, applies the PACKER_* environment overrides and validates the result.
+func Load(path string) (*Config, error) {
+	cfg, err := GetConfigFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ApplyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
 
 func GetConfigFromFile(path string) (*Config, error) {
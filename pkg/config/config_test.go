@@ -0,0 +1,385 @@
+package config_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/pkg/config"
+)
+
+const validConfig = `
+server:
+  port: 3000
+log:
+  level: info
+rate_limit:
+  enabled: true
+  requests_per_second: 5
+  burst: 20
+packer:
+  default_sizes: [250, 500]
+cache:
+  ttl: 1h
+`
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func lookupFrom(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+
+		return value, ok
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.GetConfigFromFile(writeConfig(t, t.TempDir(), validConfig))
+	require.NoError(t, err)
+
+	err = config.ApplyEnv(cfg, lookupFrom(map[string]string{
+		"PACKER_SERVER_PORT":                    "8080",
+		"PACKER_SERVER_READ_TIMEOUT":            "3s",
+		"PACKER_LOG_LEVEL":                      "warn",
+		"PACKER_RATE_LIMIT_ENABLED":             "false",
+		"PACKER_RATE_LIMIT_REQUESTS_PER_SECOND": "1.5",
+		"PACKER_PACKER_DEFAULT_SIZES":           "23, 31,53",
+		"PACKER_AUTH_API_KEYS":                  `[{name: ops, sha256: abc, role: admin}]`,
+	}))
+	require.NoError(t, err)
+
+	require.Equal(t, 8080, cfg.Server.Port)
+	require.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	require.Equal(t, "warn", cfg.Log.Level)
+	require.False(t, cfg.RateLimit.Enabled)
+	require.InDelta(t, 1.5, cfg.RateLimit.RequestsPerSecond, 0)
+	require.Equal(t, []int{23, 31, 53}, cfg.Packer.DefaultSizes)
+	require.Equal(t, []config.APIKey{{Name: "ops", SHA256: "abc", Role: "admin"}}, cfg.Auth.APIKeys)
+	require.Equal(t, time.Hour, cfg.Cache.TTL, "fields without an override are kept")
+}
+
+func TestApplyEnv_InvalidValue(t *testing.T) {
+	t.Parallel()
+
+	var cfg config.Config
+	err := config.ApplyEnv(&cfg, lookupFrom(map[string]string{"PACKER_SERVER_PORT": "http"}))
+	require.ErrorIs(t, err, config.ErrInvalidEnv)
+	require.ErrorContains(t, err, "PACKER_SERVER_PORT")
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		problems []string
+	}{
+		{
+			name:    "valid",
+			content: validConfig,
+		},
+		{
+			name: "invalid settings",
+			content: `
+server:
+  port: 70000
+  write_timeout: -1s
+  tls:
+    enabled: true
+    min_version: "1.0"
+log:
+  level: verbose
+grpc:
+  enabled: true
+  port: 70000
+profiler:
+  enabled: true
+  host: 0.0.0.0
+  port: 4667
+  mutex_profile_fraction: -1
+  snapshots:
+    enabled: true
+    latency_threshold: -1s
+rate_limit:
+  enabled: true
+  requests_per_second: 0
+  burst: 0
+auth:
+  enabled: true
+  api_keys:
+    - name: ops
+      sha256: nothex
+      role: root
+packer:
+  default_strategy: v3
+  max_sizes: 2
+  workers: -1
+  lookup_table:
+    max_items: -1
+  dp_table:
+    max_items: -1
+  default_sizes: [250, 0, 250]
+  catalogs:
+    - name: default
+      sizes: [1]
+    - name: bolts
+      sizes: [10, -5, 2000000000]
+    - name: bolts
+cache:
+  capacity: -1
+`,
+			problems: []string{
+				"server.port: must be between 1 and 65535, got 70000",
+				"server.write_timeout: must not be negative",
+				"server.tls: cert_file and key_file are required",
+				"server.tls: unknown TLS version",
+				"log.level",
+				"grpc.port: 70000 is already used by server.port",
+				`profiler.token: required when profiler.host "0.0.0.0" is not a loopback address`,
+				"profiler.mutex_profile_fraction: must not be negative",
+				"profiler.snapshots.dir: required when snapshots are enabled",
+				"profiler.snapshots.latency_threshold: must not be negative",
+				"rate_limit.requests_per_second: must be positive",
+				"rate_limit.burst: must be at least 1",
+				"auth.api_keys[0].sha256: must be a 64 character hex SHA-256 digest",
+				"auth.api_keys[0].role: must be reader or admin",
+				"packer.default_sizes[1]: must be positive",
+				"packer.default_sizes[2]: duplicate size 250",
+				"packer.default_strategy: must be v1, v2 or parallel",
+				"packer.workers: must not be negative",
+				"packer.lookup_table.max_items: must not be negative",
+				"packer.dp_table.max_items: must not be negative",
+				"packer.default_sizes: 3 sizes exceed packer.max_sizes of 2",
+				`packer.catalogs[0].name: "default" is reserved`,
+				"packer.catalogs[1].sizes[1]: must be positive, got -5",
+				"packer.catalogs[1].sizes[2]: must be at most 1000000000, got 2000000000",
+				`packer.catalogs[2].name: duplicate catalog "bolts"`,
+				"packer.catalogs[2].sizes: required",
+				"cache.capacity: must not be negative",
+			},
+		},
+		{
+			name: "admission fits max items",
+			content: `
+server:
+  port: 3000
+admission:
+  enabled: true
+  max_in_flight_work: 1500
+packer:
+  default_sizes: [250, 500]
+  max_items: 1000
+`,
+		},
+		{
+			name: "admission too small for max items",
+			content: `
+admission:
+  enabled: true
+  max_in_flight_work: 1000
+packer:
+  default_sizes: [250, 500]
+  max_items: 1000
+  catalogs:
+    - name: bolts
+      sizes: [100, 750]
+`,
+			problems: []string{"admission.max_in_flight_work: 1000 is less than packer.max_items 1000 + the largest size 750"},
+		},
+		{
+			name: "bursts fit max items",
+			content: `
+server:
+  port: 3000
+rate_limit:
+  enabled: true
+  requests_per_second: 5
+  burst: 11
+  items_per_cost_unit: 1000
+packer:
+  default_sizes: [250, 500]
+  max_items: 10000
+`,
+		},
+		{
+			name: "bursts too small for max items",
+			content: `
+server:
+  port: 3000
+rate_limit:
+  enabled: true
+  requests_per_second: 5
+  burst: 10
+  items_per_cost_unit: 1000
+  clients:
+    - name: batch
+      api_key_sha256: ` + strings.Repeat("a", 64) + `
+      requests_per_second: 5
+      burst: 5
+packer:
+  default_sizes: [250, 500]
+  max_items: 10000
+`,
+			problems: []string{
+				"rate_limit.burst: 10 is less than the cost 11 of packer.max_items 10000 at rate_limit.items_per_cost_unit 1000",
+				"rate_limit.clients[0].burst: 5 is less than the cost 11",
+			},
+		},
+		{
+			name: "bursts too small for the default max items",
+			content: `
+server:
+  port: 3000
+rate_limit:
+  enabled: true
+  requests_per_second: 5
+  burst: 20
+  items_per_cost_unit: 1000000
+packer:
+  default_sizes: [250, 500]
+`,
+			problems: []string{"rate_limit.burst: 20 is less than the cost 1001 of packer.max_items 1000000000"},
+		},
+		{
+			name: "admission without max items",
+			content: validConfig + `
+admission:
+  enabled: true
+  max_in_flight_work: 1000
+`,
+			problems: []string{"packer.max_items: required when admission is enabled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := config.GetConfigFromFile(writeConfig(t, t.TempDir(), tt.content))
+			require.NoError(t, err)
+
+			err = cfg.Validate()
+			if len(tt.problems) == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, config.ErrInvalidConfig)
+			for _, problem := range tt.problems {
+				require.ErrorContains(t, err, problem)
+			}
+		})
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := writeConfig(t, dir, validConfig)
+	initial, err := config.Load(path)
+	require.NoError(t, err)
+
+	var applied []*config.Config
+	reloader := config.NewReloader(slog.New(slog.DiscardHandler), path, initial).OnReload(func(previous, next *config.Config) {
+		require.Same(t, initial, previous)
+		applied = append(applied, next)
+	})
+
+	writeConfig(t, dir, validConfig+"  # touched\n")
+	require.NoError(t, reloader.Reload())
+	require.Len(t, applied, 1)
+	require.Same(t, applied[0], reloader.Current())
+
+	writeConfig(t, dir, "server:\n  port: 0\n")
+	require.ErrorIs(t, reloader.Reload(), config.ErrInvalidConfig)
+	require.Len(t, applied, 1, "an invalid file is not applied")
+	require.Same(t, applied[0], reloader.Current())
+}
+
+func TestReloader_KeepsRestartOnlySettings(t *testing.T) {
+	t.Parallel()
+
+	const admissionConfig = validConfig + `admission:
+  enabled: true
+  max_in_flight_work: 1500
+`
+	withMaxItems := strings.Replace(admissionConfig, "packer:\n", "packer:\n  max_items: 1000\n", 1)
+
+	dir := t.TempDir()
+	path := writeConfig(t, dir, withMaxItems)
+	initial, err := config.Load(path)
+	require.NoError(t, err)
+	reloader := config.NewReloader(slog.New(slog.DiscardHandler), path, initial)
+
+	writeConfig(t, dir, strings.Replace(strings.Replace(withMaxItems, "port: 3000", "port: 3001", 1), "ttl: 1h", "ttl: 5m", 1))
+	require.NoError(t, reloader.Reload())
+	require.Equal(t, 3000, reloader.Current().Server.Port, "the running port is kept until a restart")
+	require.Equal(t, 5*time.Minute, reloader.Current().Cache.TTL)
+
+	// Sizes that fit the new but not the running admission capacity are rejected.
+	tooLarge := strings.Replace(withMaxItems, "max_in_flight_work: 1500", "max_in_flight_work: 2500", 1)
+	writeConfig(t, dir, strings.Replace(tooLarge, "[250, 500]", "[250, 1000]", 1))
+	require.ErrorIs(t, reloader.Reload(), config.ErrInvalidConfig)
+	require.Equal(t, []int{250, 500}, reloader.Current().Packer.DefaultSizes)
+}
+
+func TestReloader_Watch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := writeConfig(t, dir, validConfig)
+	initial, err := config.Load(path)
+	require.NoError(t, err)
+
+	reloaded := make(chan *config.Config, 2)
+	reloader := config.NewReloader(slog.New(slog.DiscardHandler), path, initial).
+		OnReload(func(_, next *config.Config) {
+			reloaded <- next
+		})
+
+	signals := make(chan os.Signal, 1)
+	go reloader.Watch(t.Context(), 10*time.Millisecond, signals)
+
+	writeConfig(t, dir, strings.Replace(validConfig, "ttl: 1h", "ttl: 5m", 1))
+	select {
+	case next := <-reloaded:
+		require.Equal(t, 5*time.Minute, next.Cache.TTL)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file change was not picked up")
+	}
+
+	signals <- syscall.SIGHUP
+	select {
+	case next := <-reloaded:
+		require.Equal(t, 5*time.Minute, next.Cache.TTL)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not trigger a reload")
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	t.Parallel()
+
+	previous := &config.Config{Server: config.Server{Port: 3000}, Log: config.Log{Level: "info"}}
+	next := &config.Config{Server: config.Server{Port: 3001}, Log: config.Log{Level: "debug"}}
+
+	require.Equal(t, []string{"server"}, config.RestartRequired(previous, next))
+}
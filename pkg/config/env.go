@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes every environment override, e.g. PACKER_SERVER_PORT overrides server.port.
+const EnvPrefix = "PACKER"
+
+var ErrInvalidEnv = errors.New("invalid environment override")
+
+// ApplyEnv overrides every config field for which lookup finds a value. Variable names are EnvPrefix followed by
+// the upper-cased YAML path joined by underscores. Values are parsed as YAML, so durations ("5s"), lists
+// ("[250, 500]" or "250,500") and lists of objects are supported.
+func ApplyEnv(cfg *Config, lookup func(key string) (string, bool)) error {
+	return applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup)
+}
+
+func applyEnv(value reflect.Value, prefix string, lookup func(key string) (string, bool)) error {
+	var errs []error
+	for index := range value.NumField() {
+		field := value.Type().Field(index)
+		tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(tag)
+		fieldValue := value.Field(index)
+		if fieldValue.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(fieldValue, name, lookup))
+
+			continue
+		}
+
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setFromEnv(fieldValue, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%w %s=%q: %w", ErrInvalidEnv, name, raw, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func setFromEnv(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+
+		return nil
+	case reflect.Slice:
+		// Lists of scalars may be given without brackets.
+		trimmed := strings.TrimSpace(raw)
+		if field.Type().Elem().Kind() != reflect.Struct && trimmed != "" && !strings.HasPrefix(trimmed, "[") {
+			raw = "[" + trimmed + "]"
+		}
+	default:
+	}
+
+	parsed := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(raw), parsed.Interface()); err != nil {
+		return err
+	}
+	field.Set(parsed.Elem())
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadFunc applies the reloadable settings of next. previous is the configuration it replaces.
+type ReloadFunc func(previous, next *Config)
+
+// Reloader keeps the current configuration and reloads it on SIGHUP or when the file content changes.
+// An invalid file is logged and ignored, so the running configuration is never replaced by a broken one.
+type Reloader struct {
+	logger   *slog.Logger
+	current  atomic.Pointer[Config]
+	path     string
+	onReload []ReloadFunc
+	digest   [sha256.Size]byte
+	mu       sync.Mutex
+}
+
+// NewReloader creates a Reloader for the file at path, starting from the already loaded initial configuration.
+func NewReloader(logger *slog.Logger, path string, initial *Config) *Reloader {
+	reloader := &Reloader{
+		logger: logger,
+		path:   path,
+	}
+	reloader.current.Store(initial)
+	reloader.digest, _ = fileDigest(path)
+
+	return reloader
+}
+
+// OnReload registers a function that applies the reloadable settings after every successful reload.
+func (reloader *Reloader) OnReload(apply ReloadFunc) *Reloader {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	reloader.onReload = append(reloader.onReload, apply)
+
+	return reloader
+}
+
+// Current returns the configuration in effect.
+func (reloader *Reloader) Current() *Config {
+	return reloader.current.Load()
+}
+
+// Reload loads and validates the file, then applies it through the registered functions. Settings that require a
+// restart keep their running values, in Current too, until the process restarts.
+func (reloader *Reloader) Reload() error {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	next, err := Load(reloader.path)
+	if err != nil {
+		return err
+	}
+	reloader.digest, _ = fileDigest(reloader.path)
+
+	previous := reloader.current.Load()
+	if changed := RestartRequired(previous, next); len(changed) > 0 {
+		reloader.logger.Warn("Config changes require a restart to take effect, keeping the running values", "sections", changed)
+		keepRestartOnly(previous, next)
+		// The reloaded settings may not fit the running ones, e.g. new sizes with the running admission capacity.
+		if err = next.Validate(); err != nil {
+			return err
+		}
+	}
+	reloader.current.Store(next)
+
+	for _, apply := range reloader.onReload {
+		apply(previous, next)
+	}
+
+	return nil
+}
+
+// Watch reloads on every value received from signals and whenever the file content changes, checking it every
+// pollInterval. Polling the content rather than watching inodes also catches atomic symlink swaps of mounted files.
+// It blocks until ctx is done.
+func (reloader *Reloader) Watch(ctx context.Context, pollInterval time.Duration, signals <-chan os.Signal) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			reloader.logger.Info("Reloading config", "path", reloader.path, "signal", sig.String())
+			reloader.reloadAndLog()
+		case <-ticker.C:
+			digest, err := fileDigest(reloader.path)
+			if err != nil || reloader.unchanged(digest) {
+				continue
+			}
+			reloader.logger.Info("Config file changed, reloading", "path", reloader.path)
+			reloader.reloadAndLog()
+		}
+	}
+}
+
+func (reloader *Reloader) reloadAndLog() {
+	if err := reloader.Reload(); err != nil {
+		reloader.logger.Error("Failed to reload config, keeping the current one", "error", err)
+
+		return
+	}
+
+	reloader.logger.Info("Config reloaded", "path", reloader.path)
+}
+
+func (reloader *Reloader) unchanged(digest [sha256.Size]byte) bool {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	if digest == reloader.digest {
+		return true
+	}
+	// Remember the new content even if it turns out invalid, so a broken file is reported once rather than on every poll.
+	reloader.digest = digest
+
+	return false
+}
+
+// restartOnly returns the settings of cfg that are only read at startup, by name.
+// Log level, rate limit budgets, default packet sizes and cache TTL are applied on reload.
+func restartOnly(cfg *Config) []struct {
+	setting any
+	name    string
+} {
+	return []struct {
+		setting any
+		name    string
+	}{
+		{name: "server", setting: &cfg.Server},
+		{name: "grpc", setting: &cfg.GRPC},
+		{name: "profiler", setting: &cfg.Profiler},
+		{name: "auth", setting: &cfg.Auth},
+		{name: "admission", setting: &cfg.Admission},
+		{name: "rate_limit.enabled", setting: &cfg.RateLimit.Enabled},
+		{name: "rate_limit.idle_timeout", setting: &cfg.RateLimit.IdleTimeout},
+		{name: "packer.default_strategy", setting: &cfg.Packer.DefaultStrategy},
+		{name: "packer.max_items", setting: &cfg.Packer.MaxItems},
+		{name: "packer.max_sizes", setting: &cfg.Packer.MaxSizes},
+		{name: "packer.workers", setting: &cfg.Packer.Workers},
+		{name: "packer.lookup_table", setting: &cfg.Packer.LookupTable},
+		{name: "packer.dp_table", setting: &cfg.Packer.DPTable},
+		{name: "packer.catalogs", setting: &cfg.Packer.Catalogs},
+		{name: "cache.cleanup_interval", setting: &cfg.Cache.CleanupInterval},
+		{name: "cache.capacity", setting: &cfg.Cache.Capacity},
+	}
+}
+
+// RestartRequired lists the changed settings that are only read at startup.
+func RestartRequired(previous, next *Config) []string {
+	var changed []string
+	nextSettings := restartOnly(next)
+	for index, previousSetting := range restartOnly(previous) {
+		if !reflect.DeepEqual(previousSetting.setting, nextSettings[index].setting) {
+			changed = append(changed, previousSetting.name)
+		}
+	}
+
+	return changed
+}
+
+// keepRestartOnly copies the settings that are only read at startup from previous to next, so that next describes the
+// configuration actually in effect.
+func keepRestartOnly(previous, next *Config) {
+	nextSettings := restartOnly(next)
+	for index, previousSetting := range restartOnly(previous) {
+		reflect.ValueOf(nextSettings[index].setting).Elem().Set(reflect.ValueOf(previousSetting.setting).Elem())
+	}
+}
+
+func fileDigest(path string) ([sha256.Size]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(content), nil
+}
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+const (
+	maxPort      = 65535
+	sha256HexLen = 64
+	// maxSize is the largest packet size the API accepts, see validation.MaxPacketSize.
+	maxSize = 1_000_000_000
+	// defaultMaxItems is the item limit when packer.max_items is 0, see handler.MaxAllowedItems.
+	defaultMaxItems = 1_000_000_000
+)
+
+// Validate reports every invalid setting at once, each naming the offending YAML path.
+func (cfg *Config) Validate() error {
+	var problems []string
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	cfg.validateListeners(report)
+
+	if _, err := ParseLogLevel(cfg.Log.Level); err != nil {
+		report("log.level: %v", err)
+	}
+
+	if cfg.RateLimit.Enabled {
+		cfg.RateLimit.validate(report)
+		cfg.validateRateLimitBursts(report)
+	}
+
+	if cfg.Auth.Enabled {
+		cfg.Auth.validate(report)
+	}
+
+	if cfg.Admission.Enabled {
+		if cfg.Admission.MaxInFlightWork < 1 {
+			report("admission.max_in_flight_work: must be positive, got %d", cfg.Admission.MaxInFlightWork)
+		}
+		if cfg.Admission.MaxQueue < 0 {
+			report("admission.max_queue: must not be negative, got %d", cfg.Admission.MaxQueue)
+		}
+		validateNonNegative(report, "admission.max_wait", cfg.Admission.MaxWait)
+		cfg.validateAdmissionCapacity(report)
+	}
+
+	cfg.Packer.validate(report)
+
+	validateNonNegative(report, "cache.ttl", cfg.Cache.TTL)
+	validateNonNegative(report, "cache.cleanup_interval", cfg.Cache.CleanupInterval)
+	if cfg.Cache.Capacity < 0 {
+		report("cache.capacity: must not be negative, got %d", cfg.Cache.Capacity)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w:\n  %s", ErrInvalidConfig, strings.Join(problems, "\n  "))
+}
+
+// validateAdmissionCapacity checks that the largest calculation the packer accepts, max_items + the largest configured
+// size, can be admitted at all. Constrained sizes set through the API may still weigh more and are rejected per request.
+func (cfg *Config) validateAdmissionCapacity(report func(format string, args ...any)) {
+	if cfg.Admission.MaxInFlightWork < 1 {
+		return
+	}
+	if cfg.Packer.MaxItems == 0 {
+		report("packer.max_items: required when admission is enabled, to fit admission.max_in_flight_work of %d", cfg.Admission.MaxInFlightWork)
+
+		return
+	}
+
+	largest := 0
+	if len(cfg.Packer.DefaultSizes) > 0 {
+		largest = slices.Max(cfg.Packer.DefaultSizes)
+	}
+	for _, catalog := range cfg.Packer.Catalogs {
+		if len(catalog.Sizes) > 0 {
+			largest = max(largest, slices.Max(catalog.Sizes))
+		}
+	}
+
+	if work := int64(cfg.Packer.MaxItems) + int64(largest); work > cfg.Admission.MaxInFlightWork {
+		report("admission.max_in_flight_work: %d is less than packer.max_items %d + the largest size %d",
+			cfg.Admission.MaxInFlightWork, cfg.Packer.MaxItems, largest)
+	}
+}
+
+// validateRateLimitBursts checks, like validateAdmissionCapacity, that the largest calculation the packer accepts fits
+// the burst of every client: it costs 1 + max_items / items_per_cost_unit tokens, and a request costing more than the
+// burst is always rejected.
+func (cfg *Config) validateRateLimitBursts(report func(format string, args ...any)) {
+	if cfg.RateLimit.ItemsPerCostUnit < 1 {
+		return
+	}
+
+	maxItems := cfg.Packer.MaxItems
+	if maxItems == 0 {
+		maxItems = defaultMaxItems
+	}
+	maxCost := 1 + maxItems/cfg.RateLimit.ItemsPerCostUnit
+
+	check := func(path string, burst int) {
+		if burst >= 1 && burst < maxCost {
+			report("%s: %d is less than the cost %d of packer.max_items %d at rate_limit.items_per_cost_unit %d",
+				path, burst, maxCost, maxItems, cfg.RateLimit.ItemsPerCostUnit)
+		}
+	}
+	check("rate_limit.burst", cfg.RateLimit.Burst)
+	for index, client := range cfg.RateLimit.Clients {
+		check(fmt.Sprintf("rate_limit.clients[%d].burst", index), client.Burst)
+	}
+}
+
+func (cfg *Config) validateListeners(report func(format string, args ...any)) {
+	validatePort(report, "server.port", cfg.Server.Port)
+	validateNonNegative(report, "server.read_timeout", cfg.Server.ReadTimeout)
+	validateNonNegative(report, "server.read_header_timeout", cfg.Server.ReadHeaderTimeout)
+	validateNonNegative(report, "server.write_timeout", cfg.Server.WriteTimeout)
+	validateNonNegative(report, "server.shutdown_drain_delay", cfg.Server.ShutdownDrainDelay)
+	validateTLS(report, "server.tls", &cfg.Server.TLS)
+
+	if cfg.GRPC.Enabled {
+		validatePort(report, "grpc.port", cfg.GRPC.Port)
+		if cfg.GRPC.Port == cfg.Server.Port {
+			report("grpc.port: %d is already used by server.port", cfg.GRPC.Port)
+		}
+	}
+
+	if cfg.Profiler.Enabled {
+		validatePort(report, "profiler.port", cfg.Profiler.Port)
+		if cfg.Profiler.Port == cfg.Server.Port || (cfg.GRPC.Enabled && cfg.Profiler.Port == cfg.GRPC.Port) {
+			report("profiler.port: %d is already used by another listener", cfg.Profiler.Port)
+		}
+		validateNonNegative(report, "profiler.read_header_timeout", cfg.Profiler.ReadHeaderTimeout)
+		validateTLS(report, "profiler.tls", &cfg.Profiler.TLS)
+		cfg.Profiler.validate(report)
+	}
+}
+
+func (profiler *Profiler) validate(report func(format string, args ...any)) {
+	if profiler.Token == "" && !isLoopback(profiler.Host) {
+		report("profiler.token: required when profiler.host %q is not a loopback address", profiler.Host)
+	}
+	if profiler.BlockProfileRate < 0 {
+		report("profiler.block_profile_rate: must not be negative, got %d", profiler.BlockProfileRate)
+	}
+	if profiler.MutexProfileFraction < 0 {
+		report("profiler.mutex_profile_fraction: must not be negative, got %d", profiler.MutexProfileFraction)
+	}
+
+	snapshots := &profiler.Snapshots
+	if !snapshots.Enabled {
+		return
+	}
+	if snapshots.Dir == "" {
+		report("profiler.snapshots.dir: required when snapshots are enabled")
+	}
+	if snapshots.MaxFiles < 0 {
+		report("profiler.snapshots.max_files: must not be negative, got %d", snapshots.MaxFiles)
+	}
+	validateNonNegative(report, "profiler.snapshots.interval", snapshots.Interval)
+	validateNonNegative(report, "profiler.snapshots.cpu_duration", snapshots.CPUDuration)
+	validateNonNegative(report, "profiler.snapshots.max_age", snapshots.MaxAge)
+	validateNonNegative(report, "profiler.snapshots.latency_threshold", snapshots.LatencyThreshold)
+	validateNonNegative(report, "profiler.snapshots.cooldown", snapshots.Cooldown)
+}
+
+// isLoopback reports whether host only accepts local connections. Empty means the profiler default of 127.0.0.1.
+func isLoopback(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func (packer *Packer) validate(report func(format string, args ...any)) {
+	switch packer.DefaultStrategy {
+	case "", "v1", "v2", "parallel":
+	default:
+		report("packer.default_strategy: must be v1, v2 or parallel, got %q", packer.DefaultStrategy)
+	}
+
+	if packer.MaxItems < 0 {
+		report("packer.max_items: must not be negative, got %d", packer.MaxItems)
+	}
+	if packer.MaxSizes < 0 {
+		report("packer.max_sizes: must not be negative, got %d", packer.MaxSizes)
+	}
+	if packer.Workers < 0 {
+		report("packer.workers: must not be negative, got %d", packer.Workers)
+	}
+	if packer.LookupTable.MaxItems < 0 {
+		report("packer.lookup_table.max_items: must not be negative, got %d", packer.LookupTable.MaxItems)
+	}
+	if packer.DPTable.MaxItems < 0 {
+		report("packer.dp_table.max_items: must not be negative, got %d", packer.DPTable.MaxItems)
+	}
+	validateSizes("packer.default_sizes", packer.DefaultSizes, packer.MaxSizes, report)
+
+	seenCatalogs := make(map[string]struct{}, len(packer.Catalogs))
+	for index, catalog := range packer.Catalogs {
+		switch _, seen := seenCatalogs[catalog.Name]; {
+		case catalog.Name == "":
+			report("packer.catalogs[%d].name: required", index)
+		case catalog.Name == "default":
+			report("packer.catalogs[%d].name: \"default\" is reserved for packer.default_sizes", index)
+		case seen:
+			report("packer.catalogs[%d].name: duplicate catalog %q", index, catalog.Name)
+		}
+		seenCatalogs[catalog.Name] = struct{}{}
+
+		if len(catalog.Sizes) == 0 {
+			report("packer.catalogs[%d].sizes: required", index)
+		}
+		validateSizes(fmt.Sprintf("packer.catalogs[%d].sizes", index), catalog.Sizes, packer.MaxSizes, report)
+	}
+}
+
+// validateSizes checks that sizes are positive, unique and at most maxSizes when it is set.
+func validateSizes(field string, sizes []int, maxSizes int, report func(format string, args ...any)) {
+	if maxSizes > 0 && len(sizes) > maxSizes {
+		report("%s: %d sizes exceed packer.max_sizes of %d", field, len(sizes), maxSizes)
+	}
+
+	seenSizes := make(map[int]struct{}, len(sizes))
+	for index, size := range sizes {
+		if size < 1 {
+			report("%s[%d]: must be positive, got %d", field, index, size)
+		}
+		if size > maxSize {
+			report("%s[%d]: must be at most %d, got %d", field, index, maxSize, size)
+		}
+		if _, ok := seenSizes[size]; ok {
+			report("%s[%d]: duplicate size %d", field, index, size)
+		}
+		seenSizes[size] = struct{}{}
+	}
+}
+
+func (rateLimit *RateLimit) validate(report func(format string, args ...any)) {
+	if rateLimit.RequestsPerSecond <= 0 {
+		report("rate_limit.requests_per_second: must be positive, got %v", rateLimit.RequestsPerSecond)
+	}
+	if rateLimit.Burst < 1 {
+		report("rate_limit.burst: must be at least 1, got %d", rateLimit.Burst)
+	}
+	if rateLimit.ItemsPerCostUnit < 0 {
+		report("rate_limit.items_per_cost_unit: must not be negative, got %d", rateLimit.ItemsPerCostUnit)
+	}
+	validateNonNegative(report, "rate_limit.idle_timeout", rateLimit.IdleTimeout)
+
+	seenDigests := make(map[string]struct{}, len(rateLimit.Clients))
+	for index, client := range rateLimit.Clients {
+		path := fmt.Sprintf("rate_limit.clients[%d]", index)
+		validateSHA256(report, path+".api_key_sha256", client.APIKeySHA256, seenDigests)
+		if client.RequestsPerSecond <= 0 {
+			report("%s.requests_per_second: must be positive, got %v", path, client.RequestsPerSecond)
+		}
+		if client.Burst < 1 {
+			report("%s.burst: must be at least 1, got %d", path, client.Burst)
+		}
+	}
+}
+
+func (auth *Auth) validate(report func(format string, args ...any)) {
+	if len(auth.APIKeys) == 0 && auth.JWTSecret == "" {
+		report("auth: at least one of api_keys or jwt_secret is required when enabled")
+	}
+	validateNonNegative(report, "auth.jwt_leeway", auth.JWTLeeway)
+
+	seenDigests := make(map[string]struct{}, len(auth.APIKeys))
+	for index, apiKey := range auth.APIKeys {
+		path := fmt.Sprintf("auth.api_keys[%d]", index)
+		validateSHA256(report, path+".sha256", apiKey.SHA256, seenDigests)
+		if apiKey.Role != "reader" && apiKey.Role != "admin" {
+			report("%s.role: must be reader or admin, got %q", path, apiKey.Role)
+		}
+	}
+}
+
+// ParseLogLevel parses debug, info, warn or error. An empty level means info.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo, err
+	}
+
+	return parsed, nil
+}
+
+func validateTLS(report func(format string, args ...any), path string, cfg *TLS) {
+	if !cfg.Enabled {
+		return
+	}
+
+	tlsConfig := cfg.Config()
+	if err := tlsConfig.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			report("%s: %s", path, line)
+		}
+	}
+}
+
+func validatePort(report func(format string, args ...any), path string, port int) {
+	if port < 1 || port > maxPort {
+		report("%s: must be between 1 and %d, got %d", path, maxPort, port)
+	}
+}
+
+func validateNonNegative(report func(format string, args ...any), path string, duration time.Duration) {
+	if duration < 0 {
+		report("%s: must not be negative, got %s", path, duration)
+	}
+}
+
+func validateSHA256(report func(format string, args ...any), path, digest string, seen map[string]struct{}) {
+	decoded, err := hex.DecodeString(digest)
+	if err != nil || len(digest) != sha256HexLen {
+		report("%s: must be a %d character hex SHA-256 digest", path, sha256HexLen)
+
+		return
+	}
+
+	key := hex.EncodeToString(decoded)
+	if _, ok := seen[key]; ok {
+		report("%s: duplicate digest", path)
+	}
+	seen[key] = struct{}{}
+}
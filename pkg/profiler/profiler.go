@@ -2,33 +2,62 @@ package profiler
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/goccy/go-json"
 )
 
-var ErrServerAlreadyStarted = errors.New("profiler server already started")
+var (
+	ErrServerAlreadyStarted = errors.New("profiler server already started")
+	ErrServerNotStarted     = errors.New("profiler server not started")
+	ErrUnauthorized         = errors.New("missing or invalid profiler token")
+	ErrInvalidSettings      = errors.New("invalid runtime settings")
+)
 
 const (
+	defaultHTTPHost                 = "127.0.0.1"
 	defaultHTTPPort                 = 4667
 	defaultHTTPReadHeaderTimeout    = 3 * time.Second
 	defaultURLPathFirstSubdirectory = "pprof"
+	defaultShutdownTimeout          = 5 * time.Second
+
+	// TokenQueryParam carries the token for tools that cannot set headers, such as go tool pprof.
+	TokenQueryParam = "token"
 )
 
 // Config holds the configuration for the profiler.
 type Config struct {
-	HTTPServer               *http.Server
+	HTTPServer *http.Server
+	// TLSConfig serves the profiler over TLS when set. It must provide the server certificate.
+	TLSConfig *tls.Config
+	// HTTPHost is the interface to bind. Empty means 127.0.0.1, so the profiler is not reachable from other hosts.
+	HTTPHost string
+	// Token is required as "Authorization: Bearer <token>" or the token query parameter when set.
+	Token                    string
 	URLPathFirstSubdirectory string
 	HTTPPort                 int
 	HTTPReadHeaderTimeout    time.Duration
+	// BlockProfileRate is passed to runtime.SetBlockProfileRate on start, 0 disables the block profile.
+	BlockProfileRate int
+	// MutexProfileFraction is passed to runtime.SetMutexProfileFraction on start, 0 disables the mutex profile.
+	MutexProfileFraction int
 }
 
 // DefaultConfig returns a Config with default values.
 func DefaultConfig() *Config {
 	return &Config{
+		HTTPHost:                 defaultHTTPHost,
 		HTTPPort:                 defaultHTTPPort,
 		HTTPReadHeaderTimeout:    defaultHTTPReadHeaderTimeout,
 		URLPathFirstSubdirectory: defaultURLPathFirstSubdirectory,
@@ -37,8 +66,11 @@ func DefaultConfig() *Config {
 
 // Profiler provides HTTP endpoints for pprof profiling.
 type Profiler struct {
-	config  *Config
-	started bool
+	config      *Config
+	snapshotter *Snapshotter
+	// blockProfileRate mirrors the runtime setting, which has no getter.
+	blockProfileRate atomic.Int64
+	started          bool
 }
 
 // New creates a new Profiler instance with the default configuration.
@@ -58,6 +90,13 @@ func (profiler *Profiler) WithConfig(config *Config) *Profiler {
 	return profiler
 }
 
+// WithHTTPHost sets the interface the profiler binds to.
+func (profiler *Profiler) WithHTTPHost(host string) *Profiler {
+	profiler.config.HTTPHost = host
+
+	return profiler
+}
+
 // WithHTTPPort sets a custom HTTP port for the profiler.
 func (profiler *Profiler) WithHTTPPort(port int) *Profiler {
 	profiler.config.HTTPPort = port
@@ -79,6 +118,36 @@ func (profiler *Profiler) WithHTTPServer(server *http.Server) *Profiler {
 	return profiler
 }
 
+// WithTLSConfig serves the profiler over TLS.
+func (profiler *Profiler) WithTLSConfig(tlsConfig *tls.Config) *Profiler {
+	profiler.config.TLSConfig = tlsConfig
+
+	return profiler
+}
+
+// WithToken requires the token on every profiler request.
+func (profiler *Profiler) WithToken(token string) *Profiler {
+	profiler.config.Token = token
+
+	return profiler
+}
+
+// WithRuntimeRates sets the block profile rate and mutex profile fraction applied on start.
+func (profiler *Profiler) WithRuntimeRates(blockProfileRate, mutexProfileFraction int) *Profiler {
+	profiler.config.BlockProfileRate = blockProfileRate
+	profiler.config.MutexProfileFraction = mutexProfileFraction
+
+	return profiler
+}
+
+// WithSnapshotter serves the stored snapshots under the snapshots path and runs the snapshotter while the
+// profiler is started.
+func (profiler *Profiler) WithSnapshotter(snapshotter *Snapshotter) *Profiler {
+	profiler.snapshotter = snapshotter
+
+	return profiler
+}
+
 // WithDefaultURLPathFirstSubdirectory sets a custom URL path prefix.
 func (profiler *Profiler) WithDefaultURLPathFirstSubdirectory(firstSubdirectory string) *Profiler {
 	profiler.config.URLPathFirstSubdirectory = firstSubdirectory
@@ -86,19 +155,8 @@ func (profiler *Profiler) WithDefaultURLPathFirstSubdirectory(firstSubdirectory
 	return profiler
 }
 
-// Start initializes and starts the profiler server.
-func (profiler *Profiler) Start(ctx context.Context) error {
-	if profiler.started {
-		return ErrServerAlreadyStarted
-	}
-
-	if profiler.config.HTTPServer == nil {
-		profiler.config.HTTPServer = &http.Server{
-			Addr:              fmt.Sprintf(":%d", profiler.config.HTTPPort),
-			ReadHeaderTimeout: profiler.config.HTTPReadHeaderTimeout,
-		}
-	}
-
+// Handler returns the pprof endpoints, protected by the token when one is configured.
+func (profiler *Profiler) Handler() http.Handler {
 	mux := http.NewServeMux()
 	prefix := "/" + profiler.config.URLPathFirstSubdirectory
 
@@ -107,34 +165,177 @@ func (profiler *Profiler) Start(ctx context.Context) error {
 	mux.HandleFunc(prefix+"/index", pprof.Index)
 	mux.HandleFunc(prefix+"/cmdline", pprof.Cmdline)
 	mux.HandleFunc(prefix+"/symbol", pprof.Symbol)
+	mux.HandleFunc(prefix+"/trace", pprof.Trace)
 	mux.Handle(prefix+"/goroutine", pprof.Handler("goroutine"))
 	mux.Handle(prefix+"/heap", pprof.Handler("heap"))
 	mux.Handle(prefix+"/threadcreate", pprof.Handler("threadcreate"))
 	mux.Handle(prefix+"/block", pprof.Handler("block"))
-	mux.Handle(prefix+"/trace", pprof.Handler("trace"))
+	mux.Handle(prefix+"/mutex", pprof.Handler("mutex"))
 	mux.Handle(prefix+"/allocs", pprof.Handler("allocs"))
+	mux.HandleFunc(prefix+"/settings", profiler.handleSettings)
+	if profiler.snapshotter != nil {
+		mux.HandleFunc("GET "+prefix+"/snapshots", profiler.handleListSnapshots)
+		mux.HandleFunc("GET "+prefix+"/snapshots/{name}", profiler.handleDownloadSnapshot)
+	}
 
-	profiler.config.HTTPServer.Handler = mux
+	return profiler.requireToken(mux)
+}
+
+// Start applies the runtime rates and starts the profiler server and the snapshotter. Both stop when ctx is done.
+func (profiler *Profiler) Start(ctx context.Context) error {
+	if profiler.started {
+		return ErrServerAlreadyStarted
+	}
+
+	host := profiler.config.HTTPHost
+	if host == "" {
+		host = defaultHTTPHost
+	}
+
+	if profiler.config.HTTPServer == nil {
+		profiler.config.HTTPServer = &http.Server{
+			Addr:              net.JoinHostPort(host, strconv.Itoa(profiler.config.HTTPPort)),
+			ReadHeaderTimeout: profiler.config.HTTPReadHeaderTimeout,
+		}
+	}
+
+	profiler.setRuntimeRates(profiler.config.BlockProfileRate, profiler.config.MutexProfileFraction)
+
+	profiler.config.HTTPServer.Handler = profiler.Handler()
+	if profiler.config.TLSConfig != nil {
+		profiler.config.HTTPServer.TLSConfig = profiler.config.TLSConfig
+	}
 	profiler.started = true
 
+	if profiler.snapshotter != nil {
+		go profiler.snapshotter.Run(ctx)
+	}
+
 	go func() {
-		slog.Info("Starting profiler server", "port", profiler.config.HTTPPort)
-		if err := profiler.config.HTTPServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Info("Starting profiler server", "addr", profiler.config.HTTPServer.Addr, "tls", profiler.config.TLSConfig != nil)
+		if err := profiler.listenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Profiler server error", "error", err)
 		}
 	}()
 
 	go func() {
 		<-ctx.Done()
-		slog.Info("Shutting down profiler server")
-		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		// ctx is already done, so the shutdown deadline must not derive from it.
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultShutdownTimeout)
 		defer cancel()
 
-		if err := profiler.config.HTTPServer.Shutdown(shutdownCtx); err != nil {
+		if err := profiler.Shutdown(shutdownCtx); err != nil {
 			slog.Error("Error shutting down profiler server", "error", err)
 		}
-		slog.Info("Profiler server shut down")
 	}()
 
 	return nil
 }
+
+// Shutdown gracefully stops the profiler server. It is safe to call more than once.
+func (profiler *Profiler) Shutdown(ctx context.Context) error {
+	if !profiler.started {
+		return ErrServerNotStarted
+	}
+
+	slog.Info("Shutting down profiler server")
+	if err := profiler.config.HTTPServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	slog.Info("Profiler server shut down")
+
+	return nil
+}
+
+func (profiler *Profiler) listenAndServe() error {
+	if profiler.config.HTTPServer.TLSConfig != nil {
+		// The certificate comes from the TLS config.
+		return profiler.config.HTTPServer.ListenAndServeTLS("", "")
+	}
+
+	return profiler.config.HTTPServer.ListenAndServe()
+}
+
+func (profiler *Profiler) requireToken(next http.Handler) http.Handler {
+	if profiler.config.Token == "" {
+		return next
+	}
+
+	expected := []byte(profiler.config.Token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(TokenQueryParam)
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="profiler"`)
+			http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RuntimeSettings are the runtime profiling rates that can be changed while the process runs.
+type RuntimeSettings struct {
+	BlockProfileRate     *int `json:"block_profile_rate,omitempty"`
+	MutexProfileFraction *int `json:"mutex_profile_fraction,omitempty"`
+}
+
+// handleSettings reports the runtime rates on GET and changes the given ones on PUT.
+func (profiler *Profiler) handleSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var settings RuntimeSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, ErrInvalidSettings.Error()+": "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+		if (settings.BlockProfileRate != nil && *settings.BlockProfileRate < 0) ||
+			(settings.MutexProfileFraction != nil && *settings.MutexProfileFraction < 0) {
+			http.Error(w, ErrInvalidSettings.Error()+": rates must not be negative", http.StatusBadRequest)
+
+			return
+		}
+
+		blockProfileRate := int(profiler.blockProfileRate.Load())
+		if settings.BlockProfileRate != nil {
+			blockProfileRate = *settings.BlockProfileRate
+		}
+		mutexProfileFraction := runtime.SetMutexProfileFraction(-1)
+		if settings.MutexProfileFraction != nil {
+			mutexProfileFraction = *settings.MutexProfileFraction
+		}
+		profiler.setRuntimeRates(blockProfileRate, mutexProfileFraction)
+		slog.Info("Profiler runtime settings changed",
+			"block_profile_rate", blockProfileRate,
+			"mutex_profile_fraction", mutexProfileFraction,
+		)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	blockProfileRate := int(profiler.blockProfileRate.Load())
+	// A negative fraction only reads the current value.
+	mutexProfileFraction := runtime.SetMutexProfileFraction(-1)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RuntimeSettings{
+		BlockProfileRate:     &blockProfileRate,
+		MutexProfileFraction: &mutexProfileFraction,
+	})
+}
+
+func (profiler *Profiler) setRuntimeRates(blockProfileRate, mutexProfileFraction int) {
+	runtime.SetBlockProfileRate(blockProfileRate)
+	profiler.blockProfileRate.Store(int64(blockProfileRate))
+	runtime.SetMutexProfileFraction(mutexProfileFraction)
+}
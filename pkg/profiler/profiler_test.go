@@ -0,0 +1,154 @@
+package profiler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/pkg/profiler"
+)
+
+const testToken = "secret"
+
+func serve(t *testing.T, handler http.Handler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestProfiler_Token(t *testing.T) {
+	t.Parallel()
+
+	handler := profiler.New().WithToken(testToken).Handler()
+
+	tests := []struct {
+		header         http.Header
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{name: "missing token", target: "/pprof/goroutine", expectedStatus: http.StatusUnauthorized},
+		{
+			name:           "wrong token",
+			target:         "/pprof/goroutine",
+			header:         http.Header{"Authorization": {"Bearer wrong"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "bearer token",
+			target:         "/pprof/goroutine",
+			header:         http.Header{"Authorization": {"Bearer " + testToken}},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "query token", target: "/pprof/goroutine?token=" + testToken, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := serve(t, handler, http.MethodGet, tt.target, "", tt.header)
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestProfiler_Trace(t *testing.T) {
+	t.Parallel()
+
+	rec := serve(t, profiler.New().Handler(), http.MethodGet, "/pprof/trace?seconds=0.05", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+	require.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("go 1.")), "the body is an execution trace")
+}
+
+func TestProfiler_Mutex(t *testing.T) {
+	t.Parallel()
+
+	rec := serve(t, profiler.New().Handler(), http.MethodGet, "/pprof/mutex", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+//nolint:paralleltest // Changes process-wide runtime settings.
+func TestProfiler_Settings(t *testing.T) {
+	previousMutexFraction := runtime.SetMutexProfileFraction(-1)
+	t.Cleanup(func() {
+		runtime.SetBlockProfileRate(0)
+		runtime.SetMutexProfileFraction(previousMutexFraction)
+	})
+
+	handler := profiler.New().Handler()
+
+	rec := serve(t, handler, http.MethodPut, "/pprof/settings", `{"block_profile_rate": 10, "mutex_profile_fraction": 5}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var settings profiler.RuntimeSettings
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	require.Equal(t, 10, *settings.BlockProfileRate)
+	require.Equal(t, 5, *settings.MutexProfileFraction)
+	require.Equal(t, 5, runtime.SetMutexProfileFraction(-1))
+
+	rec = serve(t, handler, http.MethodPut, "/pprof/settings", `{"mutex_profile_fraction": 0}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	require.Equal(t, 10, *settings.BlockProfileRate, "omitted settings are kept")
+	require.Equal(t, 0, *settings.MutexProfileFraction)
+
+	rec = serve(t, handler, http.MethodPut, "/pprof/settings", `{"block_profile_rate": -1}`, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(t, handler, http.MethodDelete, "/pprof/settings", "", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestProfiler_StartAndShutdown(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert // A TCP listener always has a TCP address.
+	require.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	newProfiler := profiler.New().WithHTTPPort(port)
+	require.NoError(t, newProfiler.Start(ctx))
+	require.ErrorIs(t, newProfiler.Start(ctx), profiler.ErrServerAlreadyStarted)
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/pprof/cmdline", port)
+	require.Eventually(t, func() bool {
+		resp, getErr := http.Get(url) //nolint:noctx // Test request.
+		if getErr != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		resp, getErr := http.Get(url) //nolint:noctx // Test request.
+		if getErr == nil {
+			_ = resp.Body.Close()
+		}
+
+		return getErr != nil
+	}, 5*time.Second, 10*time.Millisecond, "the profiler shuts down with its context")
+}
@@ -0,0 +1,351 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/metrics"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrSnapshotDirRequired   = errors.New("snapshot directory is required")
+	ErrCaptureInProgress     = errors.New("a snapshot is already being captured")
+	ErrInvalidSnapshotName   = errors.New("invalid snapshot name")
+	ErrSnapshotCaptureFailed = errors.New("failed to capture snapshot")
+)
+
+const (
+	defaultSnapshotCPUDuration   = 10 * time.Second
+	defaultSnapshotMaxFiles      = 20
+	defaultSnapshotCheckInterval = time.Second
+	defaultSnapshotCooldown      = time.Minute
+
+	snapshotTimeFormat = "20060102T150405.000Z"
+	heapObjectsMetric  = "/memory/classes/heap/objects:bytes"
+)
+
+// Snapshot reasons record what triggered a capture.
+const (
+	ReasonPeriodic = "periodic"
+	ReasonHeap     = "heap"
+	ReasonLatency  = "latency"
+	ReasonManual   = "manual"
+)
+
+// snapshotName matches the files written by the Snapshotter: <time>_<reason>_<kind>.pprof.
+var snapshotName = regexp.MustCompile(`^(\d{8}T\d{6}\.\d{3}Z)_([a-z]+)_(cpu|heap)\.pprof$`)
+
+// SnapshotConfig configures continuous profiling. Zero durations and limits fall back to the defaults noted below.
+type SnapshotConfig struct {
+	// Dir receives the profiles. It is created if missing.
+	Dir string
+	// Interval between periodic captures. Zero disables periodic captures.
+	Interval time.Duration
+	// CPUDuration is how long each CPU profile records. Defaults to 10s.
+	CPUDuration time.Duration
+	// MaxAge removes profiles older than this. Zero keeps them until MaxFiles is reached.
+	MaxAge time.Duration
+	// LatencyThreshold triggers a capture when ObserveLatency reports a longer request. Zero disables it.
+	LatencyThreshold time.Duration
+	// CheckInterval is how often the heap is compared with HeapThresholdBytes. Defaults to 1s.
+	CheckInterval time.Duration
+	// Cooldown is the minimum time between two triggered captures. Defaults to 1m.
+	Cooldown time.Duration
+	// HeapThresholdBytes triggers a capture when live heap objects exceed it. Zero disables it.
+	HeapThresholdBytes uint64
+	// MaxFiles is the number of profiles kept, the oldest are removed first. Defaults to 20.
+	MaxFiles int
+}
+
+// Snapshot describes a stored profile.
+type Snapshot struct {
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Reason    string    `json:"reason"`
+	SizeBytes int64     `json:"size_bytes"`
+}
+
+// Snapshotter captures CPU and heap profiles into a directory with retention limits, periodically and when the heap
+// or request latency crosses a threshold.
+type Snapshotter struct {
+	triggers    chan string
+	config      SnapshotConfig
+	lastTrigger atomic.Int64
+	capturing   atomic.Bool
+}
+
+// NewSnapshotter creates the snapshot directory and applies the defaults.
+func NewSnapshotter(cfg SnapshotConfig) (*Snapshotter, error) {
+	if cfg.Dir == "" {
+		return nil, ErrSnapshotDirRequired
+	}
+	if cfg.CPUDuration <= 0 {
+		cfg.CPUDuration = defaultSnapshotCPUDuration
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultSnapshotMaxFiles
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = defaultSnapshotCheckInterval
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultSnapshotCooldown
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &Snapshotter{
+		config:   cfg,
+		triggers: make(chan string, 1),
+	}, nil
+}
+
+// ObserveLatency reports the duration of a request. Durations above the latency threshold trigger a capture.
+// It never blocks.
+func (snapshotter *Snapshotter) ObserveLatency(duration time.Duration) {
+	if snapshotter.config.LatencyThreshold <= 0 || duration <= snapshotter.config.LatencyThreshold {
+		return
+	}
+
+	snapshotter.trigger(ReasonLatency)
+}
+
+// Run captures periodically and on triggers until ctx is done.
+func (snapshotter *Snapshotter) Run(ctx context.Context) {
+	var periodic <-chan time.Time
+	if snapshotter.config.Interval > 0 {
+		ticker := time.NewTicker(snapshotter.config.Interval)
+		defer ticker.Stop()
+		periodic = ticker.C
+	}
+
+	var heapCheck <-chan time.Time
+	if snapshotter.config.HeapThresholdBytes > 0 {
+		ticker := time.NewTicker(snapshotter.config.CheckInterval)
+		defer ticker.Stop()
+		heapCheck = ticker.C
+	}
+
+	for {
+		var reason string
+		select {
+		case <-ctx.Done():
+			return
+		case <-periodic:
+			reason = ReasonPeriodic
+		case <-heapCheck:
+			if heapObjectsBytes() > snapshotter.config.HeapThresholdBytes {
+				snapshotter.trigger(ReasonHeap)
+			}
+
+			continue
+		case reason = <-snapshotter.triggers:
+		}
+
+		if err := snapshotter.Capture(ctx, reason); err != nil {
+			slog.Warn("Failed to capture profiling snapshot", "reason", reason, "error", err)
+		}
+	}
+}
+
+// Capture writes a heap profile and then records a CPU profile for the configured duration.
+// CPU profiling is process-wide, so the CPU profile is skipped while another one, e.g. /pprof/profile, is running.
+func (snapshotter *Snapshotter) Capture(ctx context.Context, reason string) error {
+	if !snapshotter.capturing.CompareAndSwap(false, true) {
+		return ErrCaptureInProgress
+	}
+	defer snapshotter.capturing.Store(false)
+	defer snapshotter.prune()
+
+	prefix := time.Now().UTC().Format(snapshotTimeFormat) + "_" + reason + "_"
+
+	if err := snapshotter.writeFile(prefix+"heap.pprof", func(file *os.File) error {
+		return pprof.Lookup("heap").WriteTo(file, 0)
+	}); err != nil {
+		return err
+	}
+
+	return snapshotter.writeFile(prefix+"cpu.pprof", func(file *os.File) error {
+		if err := pprof.StartCPUProfile(file); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(snapshotter.config.CPUDuration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		pprof.StopCPUProfile()
+
+		return nil
+	})
+}
+
+// List returns the stored profiles, newest first.
+func (snapshotter *Snapshotter) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(snapshotter.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		match := snapshotName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			continue
+		}
+		createdAt, _ := time.Parse(snapshotTimeFormat, match[1])
+
+		snapshots = append(snapshots, Snapshot{
+			CreatedAt: createdAt,
+			Name:      entry.Name(),
+			Kind:      match[3],
+			Reason:    match[2],
+			SizeBytes: info.Size(),
+		})
+	}
+
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return strings.Compare(b.Name, a.Name)
+	})
+
+	return snapshots, nil
+}
+
+// Open opens a stored profile by its name as returned by List.
+func (snapshotter *Snapshotter) Open(name string) (*os.File, error) {
+	if !snapshotName.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSnapshotName, name)
+	}
+
+	return os.Open(filepath.Join(snapshotter.config.Dir, name))
+}
+
+func (snapshotter *Snapshotter) trigger(reason string) {
+	now := time.Now().UnixNano()
+	last := snapshotter.lastTrigger.Load()
+	if now-last < int64(snapshotter.config.Cooldown) || !snapshotter.lastTrigger.CompareAndSwap(last, now) {
+		return
+	}
+
+	select {
+	case snapshotter.triggers <- reason:
+	default:
+	}
+}
+
+// writeFile writes a profile through a temporary file so that List never returns a partial profile.
+func (snapshotter *Snapshotter) writeFile(name string, write func(file *os.File) error) error {
+	path := filepath.Join(snapshotter.config.Dir, name)
+	file, err := os.CreateTemp(snapshotter.config.Dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err = write(file); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("%w %s: %w", ErrSnapshotCaptureFailed, name, err)
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// prune removes profiles beyond MaxFiles and older than MaxAge.
+func (snapshotter *Snapshotter) prune() {
+	snapshots, err := snapshotter.List()
+	if err != nil {
+		slog.Warn("Failed to list profiling snapshots", "error", err)
+
+		return
+	}
+
+	for index, snapshot := range snapshots {
+		expired := snapshotter.config.MaxAge > 0 && time.Since(snapshot.CreatedAt) > snapshotter.config.MaxAge
+		if index < snapshotter.config.MaxFiles && !expired {
+			continue
+		}
+
+		if err = os.Remove(filepath.Join(snapshotter.config.Dir, snapshot.Name)); err != nil {
+			slog.Warn("Failed to remove profiling snapshot", "name", snapshot.Name, "error", err)
+		}
+	}
+}
+
+func (profiler *Profiler) handleListSnapshots(w http.ResponseWriter, _ *http.Request) {
+	snapshots, err := profiler.snapshotter.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string][]Snapshot{"snapshots": snapshots})
+}
+
+func (profiler *Profiler) handleDownloadSnapshot(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	file, err := profiler.snapshotter.Open(name)
+	switch {
+	case errors.Is(err, ErrInvalidSnapshotName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	case errors.Is(err, os.ErrNotExist):
+		http.NotFound(w, r)
+
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+func heapObjectsBytes() uint64 {
+	samples := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+
+	return samples[0].Value.Uint64()
+}
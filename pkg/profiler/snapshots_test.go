@@ -0,0 +1,157 @@
+package profiler_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/pkg/profiler"
+)
+
+func newTestSnapshotter(t *testing.T, cfg profiler.SnapshotConfig) *profiler.Snapshotter {
+	t.Helper()
+
+	cfg.Dir = filepath.Join(t.TempDir(), "profiles")
+	if cfg.CPUDuration == 0 {
+		cfg.CPUDuration = 20 * time.Millisecond
+	}
+	snapshotter, err := profiler.NewSnapshotter(cfg)
+	require.NoError(t, err)
+
+	return snapshotter
+}
+
+// The snapshot tests are not parallel because CPU profiling is process-wide.
+
+//nolint:paralleltest // Uses the process-wide CPU profiler.
+func TestSnapshotter_CaptureAndRetention(t *testing.T) {
+	snapshotter := newTestSnapshotter(t, profiler.SnapshotConfig{MaxFiles: 3})
+
+	require.NoError(t, snapshotter.Capture(context.Background(), profiler.ReasonManual))
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, snapshotter.Capture(context.Background(), profiler.ReasonPeriodic))
+
+	snapshots, err := snapshotter.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3, "the oldest profile is removed")
+
+	require.Equal(t, profiler.ReasonPeriodic, snapshots[0].Reason)
+	require.Equal(t, "cpu", snapshots[1].Kind)
+	require.Equal(t, profiler.ReasonManual, snapshots[2].Reason)
+	for _, snapshot := range snapshots {
+		require.Positive(t, snapshot.SizeBytes)
+		require.WithinDuration(t, time.Now(), snapshot.CreatedAt, time.Minute)
+	}
+}
+
+//nolint:paralleltest // Uses the process-wide CPU profiler.
+func TestSnapshotter_CPUProfilerBusy(t *testing.T) {
+	snapshotter := newTestSnapshotter(t, profiler.SnapshotConfig{})
+
+	require.NoError(t, pprof.StartCPUProfile(io.Discard))
+	err := snapshotter.Capture(context.Background(), profiler.ReasonManual)
+	pprof.StopCPUProfile()
+	require.ErrorIs(t, err, profiler.ErrSnapshotCaptureFailed)
+
+	snapshots, err := snapshotter.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1, "the heap profile is still written")
+	require.Equal(t, "heap", snapshots[0].Kind)
+}
+
+//nolint:paralleltest // Uses the process-wide CPU profiler.
+func TestSnapshotter_LatencyTrigger(t *testing.T) {
+	snapshotter := newTestSnapshotter(t, profiler.SnapshotConfig{LatencyThreshold: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		snapshotter.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	snapshotter.ObserveLatency(50 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	snapshots, err := snapshotter.List()
+	require.NoError(t, err)
+	require.Empty(t, snapshots, "latency below the threshold does not trigger a capture")
+
+	snapshotter.ObserveLatency(time.Second)
+	snapshotter.ObserveLatency(time.Second)
+	require.Eventually(t, func() bool {
+		snapshots, err = snapshotter.List()
+
+		return err == nil && len(snapshots) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	snapshots, err = snapshotter.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2, "the cooldown suppresses the second trigger")
+	for _, snapshot := range snapshots {
+		require.Equal(t, profiler.ReasonLatency, snapshot.Reason)
+	}
+}
+
+//nolint:paralleltest // Uses the process-wide CPU profiler.
+func TestProfiler_Snapshots(t *testing.T) {
+	snapshotter := newTestSnapshotter(t, profiler.SnapshotConfig{})
+	require.NoError(t, snapshotter.Capture(context.Background(), profiler.ReasonManual))
+
+	handler := profiler.New().WithToken(testToken).WithSnapshotter(snapshotter).Handler()
+	auth := http.Header{"Authorization": {"Bearer " + testToken}}
+
+	rec := serve(t, handler, http.MethodGet, "/pprof/snapshots", "", nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serve(t, handler, http.MethodGet, "/pprof/snapshots", "", auth)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var listed struct {
+		Snapshots []profiler.Snapshot `json:"snapshots"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Snapshots, 2)
+
+	name := listed.Snapshots[0].Name
+	rec = serve(t, handler, http.MethodGet, "/pprof/snapshots/"+name, "", auth)
+	require.Equal(t, http.StatusOK, rec.Code)
+	file, err := snapshotter.Open(name)
+	require.NoError(t, err)
+	defer file.Close()
+	stored, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, stored, rec.Body.Bytes())
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{name: "invalid name", target: "/pprof/snapshots/config.yaml", expectedStatus: http.StatusBadRequest},
+		{name: "escaping name", target: "/pprof/snapshots/..%2Fconfig.yaml", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "missing snapshot",
+			target:         "/pprof/snapshots/20000101T000000.000Z_manual_cpu.pprof",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, handler, http.MethodGet, tt.target, "", auth)
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
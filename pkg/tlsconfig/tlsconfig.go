@@ -0,0 +1,250 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrMissingKeyPair     = errors.New("cert_file and key_file are required")
+	ErrUnknownMinVersion  = errors.New("unknown TLS version, use 1.2 or 1.3")
+	ErrUnknownCipherSuite = errors.New("unknown or insecure cipher suite")
+	ErrNoCertificatesInCA = errors.New("no certificates found in CA bundle")
+	ErrClientCARequired   = errors.New("client_ca_file is required to verify client certificates")
+)
+
+// Config describes the server certificate, the accepted protocol versions and cipher suites, and optional
+// client certificate verification (mTLS).
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of CAs that client certificates are verified against.
+	// When set, client certificates are verified if presented.
+	ClientCAFile string
+	// MinVersion is "1.2" or "1.3". Empty means 1.2.
+	MinVersion string
+	// CipherSuites restricts the TLS 1.2 cipher suites by their Go names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	// TLS 1.3 suites are not configurable. Empty means the Go defaults.
+	CipherSuites []string
+	// RequireClientCert rejects clients without a certificate signed by ClientCAFile.
+	RequireClientCert bool
+}
+
+// Validate checks the settings without reading the files.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		errs = append(errs, ErrMissingKeyPair)
+	}
+	if _, err := ParseMinVersion(cfg.MinVersion); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := ParseCipherSuites(cfg.CipherSuites); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.RequireClientCert && cfg.ClientCAFile == "" {
+		errs = append(errs, ErrClientCARequired)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseMinVersion maps "1.2" and "1.3" to their tls constants. An empty version means TLS 1.2.
+func ParseMinVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownMinVersion, version)
+	}
+}
+
+// ParseCipherSuites maps Go cipher suite names to their IDs. Only the suites in tls.CipherSuites are accepted.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := make(map[string]uint16, len(tls.CipherSuites()))
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCipherSuite, name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Manager serves the certificates of a Config and reloads them when their files change, without restarting
+// the listener. Connections already established keep the certificate they were opened with.
+type Manager struct {
+	logger  *slog.Logger
+	current atomic.Pointer[tls.Config]
+	config  Config
+	digest  [sha256.Size]byte
+	mu      sync.Mutex
+}
+
+// New loads the certificates and CA bundle of cfg.
+func New(logger *slog.Logger, cfg Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	manager := &Manager{
+		logger: logger,
+		config: cfg,
+	}
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// TLSConfig returns the server configuration. Every handshake uses the most recently loaded certificates.
+func (manager *Manager) TLSConfig() *tls.Config {
+	current := manager.current.Load()
+
+	return &tls.Config{
+		MinVersion: current.MinVersion,
+		NextProtos: current.NextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &manager.current.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return manager.current.Load(), nil
+		},
+	}
+}
+
+// Reload reads the certificate, key and CA bundle again. On error the previous certificates stay in use.
+func (manager *Manager) Reload() error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	digest, err := manager.filesDigest()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(manager.config.CertFile, manager.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	// Both were validated by New.
+	minVersion, _ := ParseMinVersion(manager.config.MinVersion)
+	cipherSuites, _ := ParseCipherSuites(manager.config.CipherSuites)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if manager.config.ClientCAFile != "" {
+		pool, poolErr := loadCAPool(manager.config.ClientCAFile)
+		if poolErr != nil {
+			return poolErr
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if manager.config.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	manager.current.Store(tlsConfig)
+	manager.digest = digest
+
+	return nil
+}
+
+// Watch reloads the certificates whenever one of the files changes, checking them every pollInterval.
+// It blocks until ctx is done.
+func (manager *Manager) Watch(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !manager.changed() {
+				continue
+			}
+
+			if err := manager.Reload(); err != nil {
+				// Certificates are often replaced one file at a time; the next poll retries.
+				manager.logger.Warn("Failed to reload TLS certificates, keeping the current ones", "error", err)
+
+				continue
+			}
+			manager.logger.Info("TLS certificates reloaded", "cert_file", manager.config.CertFile)
+		}
+	}
+}
+
+func (manager *Manager) changed() bool {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	digest, err := manager.filesDigest()
+
+	return err == nil && digest != manager.digest
+}
+
+func (manager *Manager) filesDigest() ([sha256.Size]byte, error) {
+	hash := sha256.New()
+	for _, path := range []string{manager.config.CertFile, manager.config.KeyFile, manager.config.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		hash.Write(content)
+	}
+
+	var digest [sha256.Size]byte
+	copy(digest[:], hash.Sum(nil))
+
+	return digest, nil
+}
+
+func loadCAPool(path string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCertificatesInCA, path)
+	}
+
+	return pool, nil
+}
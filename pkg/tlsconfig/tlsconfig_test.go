@@ -0,0 +1,285 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsha256/packer/pkg/tlsconfig"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue signs a leaf certificate and returns its PEM encoded certificate and key.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+}
+
+// newTestConfig writes a server key pair signed by ca and returns a Config pointing at it.
+func newTestConfig(t *testing.T, ca *testCA) tlsconfig.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := tlsconfig.Config{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	}
+	certPEM, keyPEM := ca.issue(t, 100, x509.ExtKeyUsageServerAuth)
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+
+	return cfg
+}
+
+func newTLSServer(t *testing.T, manager *tlsconfig.Manager) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = manager.TLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(ca *testCA, clientConfig *tls.Config) *http.Client {
+	clientConfig.RootCAs = ca.pool()
+
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: clientConfig},
+		Timeout:   5 * time.Second,
+	}
+}
+
+func servedSerial(t *testing.T, client *http.Client, url string) int64 {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestManager_ServesCertificate(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	manager, err := tlsconfig.New(slog.New(slog.DiscardHandler), newTestConfig(t, ca))
+	require.NoError(t, err)
+
+	server := newTLSServer(t, manager)
+
+	client := newClient(ca, &tls.Config{MinVersion: tls.VersionTLS12})
+	require.Equal(t, int64(100), servedSerial(t, client, server.URL))
+}
+
+func TestManager_MinVersion(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	cfg := newTestConfig(t, ca)
+	cfg.MinVersion = "1.3"
+	manager, err := tlsconfig.New(slog.New(slog.DiscardHandler), cfg)
+	require.NoError(t, err)
+
+	server := newTLSServer(t, manager)
+
+	client := newClient(ca, &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12})
+	_, err = client.Get(server.URL) //nolint:bodyclose // The request fails.
+	require.Error(t, err)
+
+	client = newClient(ca, &tls.Config{MinVersion: tls.VersionTLS13})
+	require.Equal(t, int64(100), servedSerial(t, client, server.URL))
+}
+
+func TestManager_MutualTLS(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+
+	cfg := newTestConfig(t, ca)
+	cfg.ClientCAFile = filepath.Join(filepath.Dir(cfg.CertFile), "client-ca.crt")
+	cfg.RequireClientCert = true
+	writeFile(t, cfg.ClientCAFile, ca.pem)
+
+	manager, err := tlsconfig.New(slog.New(slog.DiscardHandler), cfg)
+	require.NoError(t, err)
+
+	server := newTLSServer(t, manager)
+
+	clientCert := func(issuer *testCA) []tls.Certificate {
+		certPEM, keyPEM := issuer.issue(t, 200, x509.ExtKeyUsageClientAuth)
+		certificate, keyErr := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, keyErr)
+
+		return []tls.Certificate{certificate}
+	}
+
+	tests := []struct {
+		certificates []tls.Certificate
+		name         string
+		allowed      bool
+	}{
+		{name: "no client certificate"},
+		{name: "certificate from another CA", certificates: clientCert(otherCA)},
+		{name: "certificate from the client CA", certificates: clientCert(ca), allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newClient(ca, &tls.Config{MinVersion: tls.VersionTLS12, Certificates: tt.certificates})
+			resp, err := client.Get(server.URL)
+			if !tt.allowed {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestManager_ReloadsChangedCertificates(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	cfg := newTestConfig(t, ca)
+	manager, err := tlsconfig.New(slog.New(slog.DiscardHandler), cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go manager.Watch(ctx, 10*time.Millisecond)
+
+	server := newTLSServer(t, manager)
+
+	newConnection := func() int64 {
+		// A fresh transport forces a new handshake.
+		return servedSerial(t, newClient(ca, &tls.Config{MinVersion: tls.VersionTLS12}), server.URL)
+	}
+	require.Equal(t, int64(100), newConnection())
+
+	writeFile(t, cfg.KeyFile, []byte("not a key"))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int64(100), newConnection(), "a broken key pair is not loaded")
+
+	certPEM, keyPEM := ca.issue(t, 101, x509.ExtKeyUsageServerAuth)
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+
+	require.Eventually(t, func() bool {
+		return newConnection() == 101
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cfg := tlsconfig.Config{
+		MinVersion:        "1.1",
+		CipherSuites:      []string{"TLS_RSA_WITH_RC4_128_SHA"},
+		RequireClientCert: true,
+	}
+
+	err := cfg.Validate()
+	require.ErrorIs(t, err, tlsconfig.ErrMissingKeyPair)
+	require.ErrorIs(t, err, tlsconfig.ErrUnknownMinVersion)
+	require.ErrorIs(t, err, tlsconfig.ErrUnknownCipherSuite)
+	require.ErrorIs(t, err, tlsconfig.ErrClientCARequired)
+
+	ids, err := tlsconfig.ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, ids)
+}
@@ -9,7 +9,7 @@ BACKEND_PID=$!
 
 echo "Waiting for backend to be ready..."
 for i in {1..30}; do
-  if curl -f http://localhost:3000/api/v1/packet/size > /dev/null 2>&1; then
+  if curl -f http://localhost:3000/readyz > /dev/null 2>&1; then
     echo "Backend is ready!"
     break
   fi